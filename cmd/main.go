@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/kelseyhightower/envconfig"
@@ -13,13 +14,28 @@ import (
 	"os/signal"
 	_ "reverseProxy/docs"
 	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/cacheManager"
+	"reverseProxy/pkg/certificateManager"
 	"reverseProxy/pkg/config"
 	"reverseProxy/pkg/db"
 	"reverseProxy/pkg/handler"
 	"reverseProxy/pkg/handlers/backends"
+	"reverseProxy/pkg/handlers/cache"
+	"reverseProxy/pkg/handlers/certificates"
 	"reverseProxy/pkg/handlers/credentials"
+	"reverseProxy/pkg/handlers/errorPages"
+	"reverseProxy/pkg/handlers/headerRules"
+	"reverseProxy/pkg/handlers/ipRules"
+	"reverseProxy/pkg/handlers/mirror"
+	"reverseProxy/pkg/handlers/rateLimits"
+	"reverseProxy/pkg/handlers/redirectRules"
+	"reverseProxy/pkg/handlers/routes"
+	"reverseProxy/pkg/handlers/siteHosts"
 	"reverseProxy/pkg/handlers/sites"
 	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/mirrorManager"
+	"reverseProxy/pkg/rateManager"
+	"reverseProxy/pkg/siteManager"
 	"time"
 )
 
@@ -39,6 +55,26 @@ var (
 type serverConfig interface {
 	GetRevPort() string
 	GetRouterPort() string
+	GetRevTLSPort() string
+	GetRevReadHeaderTimeout() time.Duration
+	GetRevReadTimeout() time.Duration
+	GetRevWriteTimeout() time.Duration
+	GetRevIdleTimeout() time.Duration
+	GetRouterReadTimeout() time.Duration
+	GetRouterWriteTimeout() time.Duration
+}
+
+type tlsConfig interface {
+	GetTLSCert() string
+	GetTLSKey() string
+}
+
+type cacheConfig interface {
+	GetRevCacheSize() int64
+}
+
+type mirrorConfig interface {
+	GetRevMirrorRequests() int64
 }
 
 type loggerConfig interface {
@@ -52,6 +88,7 @@ func main() {
 	reverseProxyInit := make(chan struct{})
 	crudServerInit := make(chan struct{})
 	backendManagerInit := make(chan struct{})
+	siteManagerInit := make(chan struct{})
 
 	cfg := &config.EnvCache{}
 	if err := envconfig.Process("", cfg); err != nil {
@@ -73,6 +110,18 @@ func main() {
 		}
 	}()
 
+	tlsCfg := tlsConfig(cfg)
+	var fallbackCert *tls.Certificate
+	if tlsCfg.GetTLSCert() != "" && tlsCfg.GetTLSKey() != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCfg.GetTLSCert(), tlsCfg.GetTLSKey())
+		if err != nil {
+			loggers.GetError().Str("when", "load fallback certificate").Err(err).
+				Msg("unable to load certificate")
+			panic(err)
+		}
+		fallbackCert = &cert
+	}
+
 	router := mux.NewRouter()
 	router.HandleFunc("/credentials", credentials.Create).Methods("POST")
 	router.HandleFunc("/credentials/{id:[0-9]+}", credentials.Read).Methods("GET")
@@ -84,33 +133,106 @@ func main() {
 	router.HandleFunc("/sites/{id:[0-9]+}", sites.Update).Methods("PUT")
 	router.HandleFunc("/sites/{id:[0-9]+}", sites.Delete).Methods("DELETE")
 
+	router.HandleFunc("/sites/{id:[0-9]+}/cache", cache.InvalidateSite).Methods("DELETE")
+	router.HandleFunc("/sites/{id:[0-9]+}/cache/url", cache.InvalidateURL).Methods("DELETE")
+	router.HandleFunc("/sites/{id:[0-9]+}/cache/prefix", cache.InvalidatePrefix).Methods("DELETE")
+	router.HandleFunc("/sites/{id:[0-9]+}/cache/tags", cache.ListTags).Methods("GET")
+	router.HandleFunc("/sites/{id:[0-9]+}/cache/tags/{tag}", cache.InvalidateTag).Methods("DELETE")
+
+	router.HandleFunc("/sites/{id:[0-9]+}/mirror", mirror.GetCounters).Methods("GET")
+	router.HandleFunc("/sites/{id:[0-9]+}/mirror/diffs", mirror.GetDiffs).Methods("GET")
+
+	router.HandleFunc("/siteHosts", siteHosts.Create).Methods("POST")
+	router.HandleFunc("/siteHosts/{id:[0-9]+}", siteHosts.Read).Methods("GET")
+	router.HandleFunc("/siteHosts/{id:[0-9]+}", siteHosts.Update).Methods("PUT")
+	router.HandleFunc("/siteHosts/{id:[0-9]+}", siteHosts.Delete).Methods("DELETE")
+
+	router.HandleFunc("/headerRules", headerRules.Create).Methods("POST")
+	router.HandleFunc("/headerRules/{id:[0-9]+}", headerRules.Read).Methods("GET")
+	router.HandleFunc("/headerRules/{id:[0-9]+}", headerRules.Update).Methods("PUT")
+	router.HandleFunc("/headerRules/{id:[0-9]+}", headerRules.Delete).Methods("DELETE")
+
+	router.HandleFunc("/ipRules", ipRules.Create).Methods("POST")
+	router.HandleFunc("/ipRules/{id:[0-9]+}", ipRules.Read).Methods("GET")
+	router.HandleFunc("/ipRules/{id:[0-9]+}", ipRules.Update).Methods("PUT")
+	router.HandleFunc("/ipRules/{id:[0-9]+}", ipRules.Delete).Methods("DELETE")
+
+	router.HandleFunc("/redirectRules", redirectRules.Create).Methods("POST")
+	router.HandleFunc("/redirectRules/{id:[0-9]+}", redirectRules.Read).Methods("GET")
+	router.HandleFunc("/redirectRules/{id:[0-9]+}", redirectRules.Update).Methods("PUT")
+	router.HandleFunc("/redirectRules/{id:[0-9]+}", redirectRules.Delete).Methods("DELETE")
+
+	router.HandleFunc("/errorPages", errorPages.Create).Methods("POST")
+	router.HandleFunc("/errorPages/{id:[0-9]+}", errorPages.Read).Methods("GET")
+	router.HandleFunc("/errorPages/{id:[0-9]+}", errorPages.Update).Methods("PUT")
+	router.HandleFunc("/errorPages/{id:[0-9]+}", errorPages.Delete).Methods("DELETE")
+
+	router.HandleFunc("/rateLimits", rateLimits.Create).Methods("POST")
+	router.HandleFunc("/rateLimits/{id:[0-9]+}", rateLimits.Read).Methods("GET")
+	router.HandleFunc("/rateLimits/{id:[0-9]+}", rateLimits.Update).Methods("PUT")
+	router.HandleFunc("/rateLimits/{id:[0-9]+}", rateLimits.Delete).Methods("DELETE")
+
 	router.HandleFunc("/backends", backends.Create).Methods("POST")
 	router.HandleFunc("/backends/{id:[0-9]+}", backends.Read).Methods("GET")
 	router.HandleFunc("/backends/{id:[0-9]+}", backends.Update).Methods("PUT")
 	router.HandleFunc("/backends/{id:[0-9]+}", backends.Delete).Methods("DELETE")
 
+	router.HandleFunc("/certificates", certificates.Create).Methods("POST")
+	router.HandleFunc("/certificates/{id:[0-9]+}", certificates.Read).Methods("GET")
+	router.HandleFunc("/certificates/{id:[0-9]+}", certificates.Update).Methods("PUT")
+	router.HandleFunc("/certificates/{id:[0-9]+}", certificates.Delete).Methods("DELETE")
+
+	router.HandleFunc("/routes", routes.Create).Methods("POST")
+	router.HandleFunc("/routes/{id:[0-9]+}", routes.Read).Methods("GET")
+	router.HandleFunc("/routes/{id:[0-9]+}", routes.Update).Methods("PUT")
+	router.HandleFunc("/routes/{id:[0-9]+}", routes.Delete).Methods("DELETE")
+
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
 	srvCfg := serverConfig(cfg)
 	srvCRUD := http.Server{
 		Addr:         srvCfg.GetRouterPort(),
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  srvCfg.GetRouterReadTimeout(),
+		WriteTimeout: srvCfg.GetRouterWriteTimeout(),
 	}
 
+	// the requests to the backends are limited by the timeouts
+	// of the sites, the write timeout is disabled by default,
+	// so that long polling and streaming are not cut off
 	reverseProxy := http.Server{
-		Addr:         srvCfg.GetRevPort(),
-		Handler:      handler.RevHandler{},
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		Addr:              srvCfg.GetRevPort(),
+		Handler:           handler.RevHandler{},
+		ReadHeaderTimeout: srvCfg.GetRevReadHeaderTimeout(),
+		ReadTimeout:       srvCfg.GetRevReadTimeout(),
+		WriteTimeout:      srvCfg.GetRevWriteTimeout(),
+		IdleTimeout:       srvCfg.GetRevIdleTimeout(),
 	}
+	reverseProxy.RegisterOnShutdown(handler.CloseTunnels)
 
 	ctx := context.TODO()
 
 	errGroup, errGroupCtx := errgroup.WithContext(ctx)
 
 	backendManager.BackendMgr = backendManager.NewBackendManager(errGroupCtx)
+	siteManager.SiteMgr = siteManager.NewSiteManager(errGroupCtx)
+	certificateManager.CertificateMgr = certificateManager.NewCertificateManager(errGroupCtx, fallbackCert)
+	cacheManager.CacheMgr = cacheManager.NewCacheManager(cacheConfig(cfg).GetRevCacheSize())
+	rateManager.RateMgr = rateManager.NewRateManager(errGroupCtx)
+	mirrorManager.MirrorMgr = mirrorManager.NewMirrorManager(mirrorConfig(cfg).GetRevMirrorRequests())
+
+	reverseProxyTLS := http.Server{
+		Addr:    srvCfg.GetRevTLSPort(),
+		Handler: handler.RevHandler{},
+		TLSConfig: &tls.Config{
+			GetCertificate: certificateManager.CertificateMgr.GetCertificate,
+		},
+		ReadHeaderTimeout: srvCfg.GetRevReadHeaderTimeout(),
+		ReadTimeout:       srvCfg.GetRevReadTimeout(),
+		WriteTimeout:      srvCfg.GetRevWriteTimeout(),
+		IdleTimeout:       srvCfg.GetRevIdleTimeout(),
+	}
+	reverseProxyTLS.RegisterOnShutdown(handler.CloseTunnels)
 
 	errGroup.Go(func() error {
 		interruptChan := make(chan os.Signal, 1)
@@ -132,6 +254,12 @@ func main() {
 					Err(err).Msg("failed shutdown reverseProxy")
 				panic(err)
 			}
+			if err := reverseProxyTLS.Shutdown(shutdownCtx); err != nil {
+				loggers.GetError().Str("server", "reverseProxyTLS").
+					Str("when", "received os signal").
+					Err(err).Msg("failed shutdown reverseProxyTLS")
+				panic(err)
+			}
 			return correctExit
 
 		case <-errGroupCtx.Done():
@@ -151,6 +279,12 @@ func main() {
 					Msg("failed shutdown reverseProxy")
 				panic(err)
 			}
+			if err := reverseProxyTLS.Shutdown(shutdownCtx); err != nil {
+				loggers.GetError().Str("server", "reverseProxyTLS").
+					Str("when", "received context closure signal").Err(err).
+					Msg("failed shutdown reverseProxyTLS")
+				panic(err)
+			}
 			return errGroupCtx.Err()
 		}
 	})
@@ -168,6 +302,26 @@ func main() {
 		return nil
 	})
 
+	if srvCfg.GetRevTLSPort() != "" {
+		errGroup.Go(func() error {
+			loggers.GetInfo().Msg("start reverseProxyTLS")
+
+			err := reverseProxyTLS.ListenAndServeTLS("", "")
+			if err != http.ErrServerClosed {
+				loggers.GetError().Str("server", "reverseProxyTLS").
+					Str("when", "start reverseProxyTLS").Msg("server closed")
+				return err
+			}
+			return nil
+		})
+
+		errGroup.Go(func() error {
+			loggers.GetInfo().Msg("start CertificateManager")
+
+			return certificateManager.CertificateMgr.Serve()
+		})
+	}
+
 	errGroup.Go(func() error {
 		loggers.GetInfo().Msg("start srvCRUD")
 		close(crudServerInit)
@@ -188,11 +342,25 @@ func main() {
 		return backendManager.BackendMgr.Serve()
 	})
 
+	errGroup.Go(func() error {
+		loggers.GetInfo().Msg("start SiteManager")
+		close(siteManagerInit)
+
+		return siteManager.SiteMgr.Serve()
+	})
+
+	errGroup.Go(func() error {
+		loggers.GetInfo().Msg("start RateManager")
+
+		return rateManager.RateMgr.Serve()
+	})
+
 	logCfg := loggerConfig(cfg)
 
 	<-crudServerInit
 	<-reverseProxyInit
 	<-backendManagerInit
+	<-siteManagerInit
 
 	zerolog.SetGlobalLevel(logCfg.GetLogLevel())
 
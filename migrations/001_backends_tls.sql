@@ -0,0 +1,27 @@
+-- The scheme, TLS settings and pool of the backends.
+-- The columns are filled on the existing rows, so that
+-- the backends are listed without NULL values.
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS scheme TEXT NOT NULL DEFAULT 'http';
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS ca_cert TEXT NOT NULL DEFAULT '';
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS server_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS client_cert TEXT NOT NULL DEFAULT '';
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS client_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS insecure_skip_verify BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS pool TEXT NOT NULL DEFAULT '';
+
+-- the columns created before without the constraints
+UPDATE backends SET scheme = 'http' WHERE scheme IS NULL;
+UPDATE backends SET ca_cert = '' WHERE ca_cert IS NULL;
+UPDATE backends SET server_name = '' WHERE server_name IS NULL;
+UPDATE backends SET client_cert = '' WHERE client_cert IS NULL;
+UPDATE backends SET client_key = '' WHERE client_key IS NULL;
+UPDATE backends SET insecure_skip_verify = false WHERE insecure_skip_verify IS NULL;
+UPDATE backends SET pool = '' WHERE pool IS NULL;
+ALTER TABLE backends
+    ALTER COLUMN scheme SET DEFAULT 'http', ALTER COLUMN scheme SET NOT NULL,
+    ALTER COLUMN ca_cert SET DEFAULT '', ALTER COLUMN ca_cert SET NOT NULL,
+    ALTER COLUMN server_name SET DEFAULT '', ALTER COLUMN server_name SET NOT NULL,
+    ALTER COLUMN client_cert SET DEFAULT '', ALTER COLUMN client_cert SET NOT NULL,
+    ALTER COLUMN client_key SET DEFAULT '', ALTER COLUMN client_key SET NOT NULL,
+    ALTER COLUMN insecure_skip_verify SET DEFAULT false, ALTER COLUMN insecure_skip_verify SET NOT NULL,
+    ALTER COLUMN pool SET DEFAULT '', ALTER COLUMN pool SET NOT NULL;
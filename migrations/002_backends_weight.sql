@@ -0,0 +1,9 @@
+-- The weight of the backends in the pool.
+-- The existing rows get the weight 1, so that they
+-- still get the requests, the weight 0 drains the backend.
+ALTER TABLE backends ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;
+
+-- the column created before without the constraints
+UPDATE backends SET weight = 1 WHERE weight IS NULL;
+ALTER TABLE backends
+    ALTER COLUMN weight SET DEFAULT 1, ALTER COLUMN weight SET NOT NULL;
@@ -0,0 +1,7 @@
+-- The certificates of the sites served over TLS.
+CREATE TABLE IF NOT EXISTS certificates (
+    id      SERIAL PRIMARY KEY,
+    cert    TEXT NOT NULL,
+    key     TEXT NOT NULL,
+    site_id INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
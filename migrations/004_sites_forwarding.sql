@@ -0,0 +1,3 @@
+-- The trusted proxies of the sites and the Forwarded header.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS trusted_proxies TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS forwarded BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+-- The routes choosing the pools of the backends by the path.
+CREATE TABLE IF NOT EXISTS routes (
+    id       SERIAL PRIMARY KEY,
+    prefix   TEXT NOT NULL DEFAULT '',
+    regex    TEXT NOT NULL DEFAULT '',
+    priority INTEGER NOT NULL DEFAULT 0,
+    pool     TEXT NOT NULL DEFAULT '',
+    site_id  INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- The rules of rewriting the paths of the routes.
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS strip_prefix TEXT NOT NULL DEFAULT '';
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS add_prefix TEXT NOT NULL DEFAULT '';
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS rewrite_regex TEXT NOT NULL DEFAULT '';
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS rewrite_replacement TEXT NOT NULL DEFAULT '';
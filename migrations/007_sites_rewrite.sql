@@ -0,0 +1,5 @@
+-- The rules of rewriting the paths of the sites.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS rewrite_strip_prefix TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS rewrite_regex TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS rewrite_replacement TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS rewrite_add_prefix TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- The rules adding, setting or removing the headers of the requests and the responses.
+CREATE TABLE IF NOT EXISTS header_rules (
+    id        SERIAL PRIMARY KEY,
+    direction TEXT NOT NULL DEFAULT '',
+    action    TEXT NOT NULL DEFAULT '',
+    name      TEXT NOT NULL DEFAULT '',
+    value     TEXT NOT NULL DEFAULT '',
+    site_id   INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- The retries of the failed requests of the sites, the backoff is in milliseconds.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS retry_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS retry_backoff INTEGER NOT NULL DEFAULT 0;
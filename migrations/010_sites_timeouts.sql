@@ -0,0 +1,5 @@
+-- The timeouts of the sites in milliseconds, 0 means the default timeout.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS connect_timeout INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS response_header_timeout INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS idle_timeout INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS request_timeout INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- The caching of the responses of the sites.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS cache_enabled BOOLEAN NOT NULL DEFAULT false;
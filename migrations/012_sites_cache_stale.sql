@@ -0,0 +1,2 @@
+-- The stale responses of the sites sent when the backends fail.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS cache_serve_stale BOOLEAN NOT NULL DEFAULT false;
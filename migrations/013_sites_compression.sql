@@ -0,0 +1,4 @@
+-- The compression of the responses of the sites, 0 and empty mean the defaults.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS compression_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS compression_types TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS compression_min_size INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- The rate limits of the requests to the sites, the period is in milliseconds.
+CREATE TABLE IF NOT EXISTS rate_limits (
+    id       SERIAL PRIMARY KEY,
+    key      TEXT NOT NULL DEFAULT '',
+    header   TEXT NOT NULL DEFAULT '',
+    requests INTEGER NOT NULL DEFAULT 0,
+    period   INTEGER NOT NULL DEFAULT 0,
+    burst    INTEGER NOT NULL DEFAULT 0,
+    site_id  INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
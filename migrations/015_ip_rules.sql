@@ -0,0 +1,7 @@
+-- The rules allowing or denying the requests to the sites by the CIDRs of the clients.
+CREATE TABLE IF NOT EXISTS ip_rules (
+    id      SERIAL PRIMARY KEY,
+    action  TEXT NOT NULL DEFAULT '',
+    cidr    TEXT NOT NULL DEFAULT '',
+    site_id INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+-- The templates of the error responses of the sites.
+CREATE TABLE IF NOT EXISTS error_pages (
+    id           SERIAL PRIMARY KEY,
+    status       INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    body         TEXT NOT NULL DEFAULT '',
+    site_id      INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
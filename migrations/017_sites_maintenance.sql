@@ -0,0 +1,5 @@
+-- The maintenance mode of the sites, retry_after is in seconds.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS maintenance_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS maintenance_retry_after INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS maintenance_bypass_ips TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS maintenance_bypass_logins TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- The rules redirecting the requests to the https, the other hosts and the paths of the sites.
+CREATE TABLE IF NOT EXISTS redirect_rules (
+    id      SERIAL PRIMARY KEY,
+    kind    TEXT NOT NULL DEFAULT '',
+    source  TEXT NOT NULL DEFAULT '',
+    target  TEXT NOT NULL DEFAULT '',
+    status  INTEGER NOT NULL DEFAULT 0,
+    site_id INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- The aliases of the hosts of the sites: the hosts, the wildcards and the regexes.
+CREATE TABLE IF NOT EXISTS site_hosts (
+    id      SERIAL PRIMARY KEY,
+    pattern TEXT NOT NULL DEFAULT '',
+    site_id INTEGER NOT NULL REFERENCES sites (id) ON DELETE CASCADE
+);
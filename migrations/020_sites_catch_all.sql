@@ -0,0 +1,7 @@
+-- The default site answering the requests of the unknown hosts.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS catch_all_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS catch_all_action TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS catch_all_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS catch_all_body TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS catch_all_target TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS catch_all_pool TEXT NOT NULL DEFAULT '';
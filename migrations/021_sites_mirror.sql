@@ -0,0 +1,4 @@
+-- The mirroring of the requests of the sites to the shadow backends.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS mirror_pool TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS mirror_percent INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS mirror_max_body_size BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- The comparison of the responses of the shadow backends of the sites.
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS mirror_compare BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS mirror_compare_headers TEXT NOT NULL DEFAULT '';
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS mirror_ignore_paths TEXT NOT NULL DEFAULT '';
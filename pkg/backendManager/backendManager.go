@@ -2,13 +2,15 @@ package backendManager
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"math/big"
 	"net"
 	"net/http"
 	"reverseProxy/pkg/logging"
 	"reverseProxy/pkg/repositories/backends"
+	"reverseProxy/pkg/repositories/routes"
+	"reverseProxy/pkg/repositories/sites"
 	"sync"
 	"time"
 )
@@ -17,23 +19,36 @@ var (
 	BackendMgr        *BackendManager
 	ErrNoHost         = fmt.Errorf("host not found")
 	ErrClientNotFound = fmt.Errorf("client not found")
+	ErrInvalidCACert  = fmt.Errorf("no valid certificates in CA bundle")
 )
 
 type BackendManager struct {
 	endPoints   map[string][]*Client
+	routes      map[string][]*Route
 	tickBackend *time.Ticker
 	tickDB      *time.Ticker
 	ctx         context.Context
 	mux         sync.RWMutex
 	e           chan error
 	log         *logging.Logger
+
+	// weights guards the current weights of the
+	// clients of the weighted round-robin
+	weights sync.Mutex
 }
 
 type Client struct {
 	Alive     bool
 	Address   string
+	Scheme    string
+	Pool      string
+	Weight    int64
+	current   int64
 	processed bool
 	Cl        http.Client
+	tls       backends.TLSSettings
+	tlsConfig *tls.Config
+	timeouts  sites.Timeouts
 	mux       sync.RWMutex
 }
 
@@ -41,6 +56,7 @@ type Client struct {
 func NewBackendManager(ctx context.Context) *BackendManager {
 	return &BackendManager{
 		endPoints:   make(map[string][]*Client),
+		routes:      make(map[string][]*Route),
 		tickBackend: time.NewTicker(20 * time.Second),
 		tickDB:      time.NewTicker(5 * time.Second),
 		ctx:         ctx,
@@ -80,19 +96,28 @@ func (b *BackendManager) syncHosts(endpoints []*backends.Backend) error {
 		if !ok {
 			b.endPoints[endpoint.Site.Host] = []*Client{}
 		}
+		if endpoint.Weight < 0 {
+			b.log.GetWarn().Str("when", "check weight").Int64("backend", endpoint.Id).
+				Int64("weight", endpoint.Weight).Msg("negative weight, skipped")
+			continue
+		}
 		for _, client := range b.endPoints[endpoint.Site.Host] {
-			if client.Address == endpoint.Address {
+			if client.Address == endpoint.Address && client.Scheme == endpoint.Scheme && client.tls == endpoint.TLS &&
+				client.Pool == endpoint.Pool && client.timeouts == endpoint.Site.Timeouts {
+				client.Weight = endpoint.Weight
 				client.processed = true
 				match = true
 				break
 			}
 		}
 		if !match {
-			client := Client{
-				Address:   endpoint.Address,
-				processed: true,
+			client, err := newClient(endpoint)
+			if err != nil {
+				b.log.GetWarn().Str("when", "create client").Int64("backend", endpoint.Id).
+					Err(err).Msg("invalid backend settings, skipped")
+				continue
 			}
-			b.endPoints[endpoint.Site.Host] = append(b.endPoints[endpoint.Site.Host], &client)
+			b.endPoints[endpoint.Site.Host] = append(b.endPoints[endpoint.Site.Host], client)
 		}
 	}
 
@@ -111,6 +136,105 @@ func (b *BackendManager) syncHosts(endpoints []*backends.Backend) error {
 	return nil
 }
 
+// newClient creates the client with the scheme and TLS
+// settings of the backend and the timeouts of its site
+func newClient(endpoint *backends.Backend) (*Client, error) {
+	client := &Client{
+		Address:   endpoint.Address,
+		Scheme:    endpoint.Scheme,
+		Pool:      endpoint.Pool,
+		Weight:    endpoint.Weight,
+		processed: true,
+		tls:       endpoint.TLS,
+		timeouts:  endpoint.Site.Timeouts,
+	}
+	transport := newTransport(client.timeouts)
+	client.Cl.Transport = transport
+	client.Cl.Timeout = milliseconds(client.timeouts.Request)
+	switch endpoint.Scheme {
+	case "", backends.SchemeHTTP:
+		return client, nil
+	case backends.SchemeHTTPS:
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", endpoint.Scheme)
+	}
+
+	tlsConfig, err := newTLSConfig(endpoint.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	client.tlsConfig = tlsConfig
+	return client, nil
+}
+
+// ValidateBackend checks the weight, the scheme and
+// the TLS settings of the backend, which are checked
+// again when the client of the backend is created
+func ValidateBackend(backend *backends.Backend) error {
+	if backend.Weight < 0 {
+		return fmt.Errorf("invalid weight %d", backend.Weight)
+	}
+	switch backend.Scheme {
+	case "", backends.SchemeHTTP:
+		return nil
+	case backends.SchemeHTTPS:
+		_, err := newTLSConfig(backend.TLS)
+		return err
+	}
+	return fmt.Errorf("unsupported scheme %q", backend.Scheme)
+}
+
+// newTransport creates the transport with the given
+// timeouts, the zero timeouts keep the defaults
+func newTransport(timeouts sites.Timeouts) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if timeouts.Connect > 0 {
+		dialer := &net.Dialer{
+			Timeout:   milliseconds(timeouts.Connect),
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if timeouts.ResponseHeader > 0 {
+		transport.ResponseHeaderTimeout = milliseconds(timeouts.ResponseHeader)
+	}
+	if timeouts.Idle > 0 {
+		transport.IdleConnTimeout = milliseconds(timeouts.Idle)
+	}
+	return transport
+}
+
+// milliseconds converts the number of
+// milliseconds to the duration
+func milliseconds(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// newTLSConfig creates the TLS configuration
+// of connections to the https backend
+func newTLSConfig(settings backends.TLSSettings) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         settings.ServerName,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	}
+	if settings.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(settings.CACert)) {
+			return nil, ErrInvalidCACert
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if settings.ClientCert != "" || settings.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(settings.ClientCert), []byte(settings.ClientKey))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
 // SyncEndpoints updates the current endpoints of database
 func (b *BackendManager) SyncEndpoints() {
 	b.mux.Lock()
@@ -122,7 +246,14 @@ func (b *BackendManager) SyncEndpoints() {
 	}
 	if err := b.syncHosts(endpoint); err != nil {
 		b.e <- err
+		return
+	}
+	rules, err := routes.List()
+	if err != nil {
+		b.e <- err
+		return
 	}
+	b.syncRoutes(rules)
 }
 
 // CheckEndpoints check Alive clients
@@ -155,6 +286,37 @@ func (c *Client) ping() {
 	c.Alive = true
 }
 
+// GetScheme returns the scheme of
+// requests to the client
+func (c *Client) GetScheme() string {
+	if c.Scheme == "" {
+		return backends.SchemeHTTP
+	}
+	return c.Scheme
+}
+
+// Dial establishes a connection with the client, the
+// connection to the https client is secured, the connect
+// timeout of the site replaces the given one
+func (c *Client) Dial(timeout time.Duration) (net.Conn, error) {
+	if c.timeouts.Connect > 0 {
+		timeout = milliseconds(c.timeouts.Connect)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	if c.GetScheme() != backends.SchemeHTTPS {
+		return dialer.Dial("tcp", c.Address)
+	}
+	tlsConfig := c.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	// the tunneled protocol is not negotiated,
+	// the client must not choose h2
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{"http/1.1"}
+	return tls.DialWithDialer(dialer, "tcp", c.Address, tlsConfig)
+}
+
 // getAlive block the safe reading of the
 // client's status Alive
 func (c *Client) getAlive() bool {
@@ -163,46 +325,68 @@ func (c *Client) getAlive() bool {
 	return c.Alive
 }
 
-// GetClient randomly selects a client for
-// a given host
-func (b *BackendManager) GetClient(host string) (*Client, error) {
+// GetClient selects a client of the pool for a given host by the
+// smooth weighted round-robin: each alive client gains its weight,
+// the client with the largest current weight is selected and loses
+// the total weight. The clients with the weight 0 and the tried
+// clients are not selected
+func (b *BackendManager) GetClient(host, pool string, tried ...*Client) (*Client, error) {
 	b.log = logging.NewLogs("backendManager", "getClient")
 
 	b.mux.RLock()
 	defer b.mux.RUnlock()
 
-	clients, ok := b.endPoints[host]
+	hostClients, ok := b.endPoints[host]
 	if !ok {
 		b.log.GetWarn().Msg("host not found")
 		return nil, ErrNoHost
 	}
-
-	nBig, err := rand.Int(rand.Reader, big.NewInt(int64(len(clients))))
-	if err != nil {
-		b.log.GetError().Str("when", "get random cryptographically integer").
-			Err(err).Msg("failed get integer")
-		return nil, err
+	clients := make([]*Client, 0, len(hostClients))
+	for _, client := range hostClients {
+		if client.Pool == pool && !containsClient(tried, client) {
+			clients = append(clients, client)
+		}
 	}
-	n := nBig.Int64()
-	client := clients[n]
-	if !client.getAlive() {
-		b.log.GetWarn().Msg("client alive false")
-		i := n + 1
-		for i != n {
-			if i == int64(len(clients)) {
-				i = 0
-			}
-			if clients[i].getAlive() {
-				b.log.GetWarn().Msg("client alive true")
-				return clients[i], nil
-			}
+	if len(clients) == 0 {
+		b.log.GetWarn().Str("pool", pool).Msg("pool has no clients")
+		return nil, ErrClientNotFound
+	}
+
+	b.weights.Lock()
+	defer b.weights.Unlock()
+	var selected *Client
+	total := int64(0)
+	for _, client := range clients {
+		if client.Weight <= 0 {
+			continue
 		}
-	} else {
-		return client, nil
+		if !client.getAlive() {
+			b.log.GetWarn().Str("address", client.Address).Msg("client alive false")
+			continue
+		}
+		client.current += client.Weight
+		total += client.Weight
+		if selected == nil || client.current > selected.current {
+			selected = client
+		}
+	}
+	if selected == nil {
+		b.log.GetWarn().Msg("client not found")
+		return nil, ErrClientNotFound
 	}
+	selected.current -= total
+	return selected, nil
+}
 
-	b.log.GetWarn().Msg("client not found")
-	return nil, ErrClientNotFound
+// containsClient determines whether the
+// client is in the list
+func containsClient(list []*Client, client *Client) bool {
+	for _, c := range list {
+		if c == client {
+			return true
+		}
+	}
+	return false
 }
 
 // Serve with the ticks running SyncEndpoints
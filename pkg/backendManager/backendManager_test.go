@@ -2,6 +2,9 @@ package backendManager
 
 import (
 	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"reverseProxy/pkg/logging"
 	"reverseProxy/pkg/repositories/backends"
@@ -108,19 +111,45 @@ func TestBackendManager_GetClient(t *testing.T) {
 		log         *logging.Logger
 	}
 	type args struct {
-		host string
+		host  string
+		pool  string
+		tried []*Client
 	}
 	clientExample1 := &Client{
 		Alive:   true,
 		Address: "1.2.3.4",
+		Weight:  1,
 	}
 	clientExample2 := &Client{
 		Alive:   false,
 		Address: "4.3.2.1",
+		Weight:  1,
 	}
 	clientVk1 := &Client{
 		Address: "5.4.3.2",
 		Alive:   true,
+		Weight:  1,
+	}
+	clientExample3 := &Client{
+		Alive:   true,
+		Address: "4.3.2.2",
+		Weight:  1,
+	}
+	clientVkAPI := &Client{
+		Address: "5.4.3.3",
+		Alive:   true,
+		Pool:    "api",
+		Weight:  1,
+	}
+	clientVkDead := &Client{
+		Address: "5.4.3.4",
+		Alive:   false,
+		Pool:    "static",
+		Weight:  1,
+	}
+	clientExampleDrained := &Client{
+		Alive:   true,
+		Address: "4.3.2.3",
 	}
 	tests := []struct {
 		name    string
@@ -163,6 +192,76 @@ func TestBackendManager_GetClient(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "get client of the pool",
+			fields: fields{
+				endPoints: map[string][]*Client{
+					"vk.com": {
+						clientVk1,
+						clientVkAPI,
+					},
+				},
+			},
+			args:    args{host: "vk.com", pool: "api"},
+			want:    clientVkAPI,
+			wantErr: false,
+		},
+		{
+			name: "error when all clients of the pool are dead",
+			fields: fields{
+				endPoints: map[string][]*Client{
+					"vk.com": {
+						clientVk1,
+						clientVkDead,
+						clientVkDead,
+					},
+				},
+			},
+			args:    args{host: "vk.com", pool: "static"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "tried clients are not selected",
+			fields: fields{
+				endPoints: map[string][]*Client{
+					"example.com": {
+						clientExample1,
+						clientExample3,
+					},
+				},
+			},
+			args:    args{host: "example.com", tried: []*Client{clientExample1}},
+			want:    clientExample3,
+			wantErr: false,
+		},
+		{
+			name: "clients with weight 0 are not selected",
+			fields: fields{
+				endPoints: map[string][]*Client{
+					"example.com": {
+						clientExampleDrained,
+						clientExample3,
+					},
+				},
+			},
+			args:    args{host: "example.com"},
+			want:    clientExample3,
+			wantErr: false,
+		},
+		{
+			name: "error when pool has no clients",
+			fields: fields{
+				endPoints: map[string][]*Client{
+					"vk.com": {
+						clientVk1,
+					},
+				},
+			},
+			args:    args{host: "vk.com", pool: "api"},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -175,7 +274,7 @@ func TestBackendManager_GetClient(t *testing.T) {
 				e:           tt.fields.e,
 				log:         tt.fields.log,
 			}
-			got, err := b.GetClient(tt.args.host)
+			got, err := b.GetClient(tt.args.host, tt.args.pool, tt.args.tried...)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetClient() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -187,6 +286,38 @@ func TestBackendManager_GetClient(t *testing.T) {
 	}
 }
 
+func TestBackendManager_GetClient_weights(t *testing.T) {
+	canary := &Client{Address: "1.2.3.4", Alive: true, Weight: 1}
+	stable := &Client{Address: "4.3.2.1", Alive: true, Weight: 9}
+	drained := &Client{Address: "4.3.2.2", Alive: true}
+	b := &BackendManager{endPoints: map[string][]*Client{"example.com": {canary, stable, drained}}}
+
+	counts := map[*Client]int{}
+	previous := (*Client)(nil)
+	for i := 0; i < 100; i++ {
+		client, err := b.GetClient("example.com", "")
+		if err != nil {
+			t.Fatalf("GetClient() error = %v", err)
+		}
+		if client == canary && previous == canary {
+			t.Errorf("GetClient() selected the canary twice in a row")
+		}
+		counts[client]++
+		previous = client
+	}
+	if counts[canary] != 10 || counts[stable] != 90 || counts[drained] != 0 {
+		t.Errorf("GetClient() counts canary = %d, stable = %d, drained = %d, want 10, 90, 0",
+			counts[canary], counts[stable], counts[drained])
+	}
+
+	stable.Alive = false
+	for i := 0; i < 3; i++ {
+		if client, err := b.GetClient("example.com", ""); err != nil || client != canary {
+			t.Errorf("GetClient() = %v, %v, want the canary when the stable client is dead", client, err)
+		}
+	}
+}
+
 func TestBackendManager_syncHosts(t *testing.T) {
 	type fields struct {
 		endPoints   map[string][]*Client
@@ -316,3 +447,202 @@ func TestBackendManager_syncHosts(t *testing.T) {
 		})
 	}
 }
+
+func TestBackendManager_syncHosts_weight(t *testing.T) {
+	site := &sites.Site{Id: 1, Name: "example", Host: "example.com"}
+	client := &Client{Address: "1.2.3.4", Alive: true, Weight: 1, processed: true}
+	b := &BackendManager{endPoints: map[string][]*Client{"example.com": {client}}}
+
+	endpoints := []*backends.Backend{
+		{Id: 1, Address: "1.2.3.4", Weight: 50, Site: site},
+		{Id: 2, Address: "4.3.2.1", Weight: -1, Site: site},
+	}
+	if err := b.syncHosts(endpoints); err != nil {
+		t.Fatalf("syncHosts() error = %v", err)
+	}
+	clients := b.endPoints["example.com"]
+	if len(clients) != 1 || clients[0] != client {
+		t.Fatalf("syncHosts() clients = %v, want the kept client only", clients)
+	}
+	if client.Weight != 50 {
+		t.Errorf("syncHosts() weight = %d, want 50", client.Weight)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	address := server.Listener.Addr().String()
+
+	tests := []struct {
+		name        string
+		backend     *backends.Backend
+		wantErr     bool
+		wantRequest bool
+	}{
+		{
+			name:    "http backend",
+			backend: &backends.Backend{Address: address, Scheme: backends.SchemeHTTP, Site: &sites.Site{}},
+		},
+		{
+			name:    "unsupported scheme",
+			backend: &backends.Backend{Address: address, Scheme: "ftp", Site: &sites.Site{}},
+			wantErr: true,
+		},
+		{
+			name: "https backend with invalid CA bundle",
+			backend: &backends.Backend{
+				Address: address,
+				Scheme:  backends.SchemeHTTPS,
+				Site:    &sites.Site{},
+				TLS:     backends.TLSSettings{CACert: "invalid"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "https backend with invalid client certificate",
+			backend: &backends.Backend{
+				Address: address,
+				Scheme:  backends.SchemeHTTPS,
+				Site:    &sites.Site{},
+				TLS:     backends.TLSSettings{ClientCert: "invalid", ClientKey: "invalid"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "https backend with CA bundle and server name",
+			backend: &backends.Backend{
+				Address: address,
+				Scheme:  backends.SchemeHTTPS,
+				Site:    &sites.Site{},
+				TLS:     backends.TLSSettings{CACert: caCert, ServerName: "example.com"},
+			},
+			wantRequest: true,
+		},
+		{
+			name: "https backend without verification",
+			backend: &backends.Backend{
+				Address: address,
+				Scheme:  backends.SchemeHTTPS,
+				Site:    &sites.Site{},
+				TLS:     backends.TLSSettings{InsecureSkipVerify: true},
+			},
+			wantRequest: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newClient(tt.backend)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantRequest {
+				return
+			}
+			resp, err := got.Cl.Get(got.GetScheme() + "://" + got.Address + "/")
+			if err != nil {
+				t.Fatalf("request to client failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("request to client got status %d, want %d", resp.StatusCode, http.StatusNoContent)
+			}
+			conn, err := got.Dial(time.Second)
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			conn.Close()
+		})
+	}
+}
+
+func TestValidateBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *backends.Backend
+		wantErr bool
+	}{
+		{name: "http backend", backend: &backends.Backend{Scheme: backends.SchemeHTTP}},
+		{name: "backend without scheme", backend: &backends.Backend{}},
+		{name: "https backend", backend: &backends.Backend{Scheme: backends.SchemeHTTPS,
+			TLS: backends.TLSSettings{ServerName: "internal.example.com"}}},
+		{name: "unsupported scheme", backend: &backends.Backend{Scheme: "ftp"}, wantErr: true},
+		{name: "drained backend", backend: &backends.Backend{Weight: 0}},
+		{name: "negative weight", backend: &backends.Backend{Weight: -1}, wantErr: true},
+		{name: "invalid CA bundle", backend: &backends.Backend{Scheme: backends.SchemeHTTPS,
+			TLS: backends.TLSSettings{CACert: "invalid"}}, wantErr: true},
+		{name: "client certificate without key", backend: &backends.Backend{Scheme: backends.SchemeHTTPS,
+			TLS: backends.TLSSettings{ClientCert: "invalid"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateBackend(tt.backend); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBackend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClient_timeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, err := time.ParseDuration(r.URL.Query().Get("delay"))
+		if err == nil {
+			time.Sleep(delay)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	tests := []struct {
+		name     string
+		timeouts sites.Timeouts
+		delay    string
+		wantErr  bool
+	}{
+		{
+			name:  "default timeouts",
+			delay: "50ms",
+		},
+		{
+			name:     "response in time",
+			timeouts: sites.Timeouts{ResponseHeader: 200, Request: 200},
+			delay:    "10ms",
+		},
+		{
+			name:     "response header timeout",
+			timeouts: sites.Timeouts{ResponseHeader: 20},
+			delay:    "200ms",
+			wantErr:  true,
+		},
+		{
+			name:     "request timeout",
+			timeouts: sites.Timeouts{Request: 20},
+			delay:    "200ms",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newClient(&backends.Backend{
+				Address: address,
+				Scheme:  backends.SchemeHTTP,
+				Site:    &sites.Site{Timeouts: tt.timeouts},
+			})
+			if err != nil {
+				t.Fatalf("newClient() error = %v", err)
+			}
+			defer got.Cl.CloseIdleConnections()
+			resp, err := got.Cl.Get("http://" + address + "/?delay=" + tt.delay)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("request to client error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package backendManager
+
+import (
+	"fmt"
+	"regexp"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/routes"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidRoute = fmt.Errorf("route must have either prefix or regex")
+
+// Route is the routing rule of the site, it
+// chooses the pool of clients by the path
+type Route struct {
+	Id       int64
+	Prefix   string
+	Regex    *regexp.Regexp
+	Priority int64
+	Pool     string
+
+	rewriter *Rewriter
+}
+
+// Rewriter rewrites the path of the request
+// by the rules of the route or of the site
+type Rewriter struct {
+	stripPrefix string
+	regex       *regexp.Regexp
+	replacement string
+	addPrefix   string
+}
+
+// NewRewriter compiles the rules of rewriting
+// the path, it returns nil if there are no rules
+func NewRewriter(rule routes.Rewrite) (*Rewriter, error) {
+	if rule == (routes.Rewrite{}) {
+		return nil, nil
+	}
+	rewriter := &Rewriter{
+		stripPrefix: rule.StripPrefix,
+		replacement: rule.Replacement,
+		addPrefix:   strings.TrimSuffix(rule.AddPrefix, "/"),
+	}
+	if rule.Regex != "" {
+		regex, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, err
+		}
+		rewriter.regex = regex
+	}
+	return rewriter, nil
+}
+
+// NewRoute compiles the routing rule
+func NewRoute(rule *routes.Route) (*Route, error) {
+	if (rule.Prefix == "") == (rule.Regex == "") {
+		return nil, ErrInvalidRoute
+	}
+	route := &Route{
+		Id:       rule.Id,
+		Prefix:   rule.Prefix,
+		Priority: rule.Priority,
+		Pool:     rule.Pool,
+	}
+	if rule.Regex != "" {
+		regex, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, err
+		}
+		route.Regex = regex
+	}
+	rewriter, err := NewRewriter(rule.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+	route.rewriter = rewriter
+	return route, nil
+}
+
+// match determines whether the path matches the route,
+// the prefix matches only whole path segments:
+// "/api" matches "/api" and "/api/v1", but not "/apis"
+func (r *Route) match(path string) bool {
+	if r.Regex != nil {
+		return r.Regex.MatchString(path)
+	}
+	return hasPathPrefix(path, r.Prefix)
+}
+
+// hasPathPrefix determines whether the path starts
+// with the prefix on the boundary of a segment
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// GetPool returns the pool of the route,
+// without route it is the default pool
+func (r *Route) GetPool() string {
+	if r == nil {
+		return ""
+	}
+	return r.Pool
+}
+
+// GetId returns the id of the route,
+// 0 if no route matched
+func (r *Route) GetId() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.Id
+}
+
+// GetPattern returns the prefix of the route
+// or its regex after "~", empty without route
+func (r *Route) GetPattern() string {
+	switch {
+	case r == nil:
+		return ""
+	case r.Regex != nil:
+		return "~" + r.Regex.String()
+	}
+	return r.Prefix
+}
+
+// GetRewriter returns the rules of rewriting the
+// path of the route, nil if the route has no rules
+func (r *Route) GetRewriter() *Rewriter {
+	if r == nil {
+		return nil
+	}
+	return r.rewriter
+}
+
+// Rewrite returns the path of the request to the client
+// by the rules of the route
+func (r *Route) Rewrite(path string) string {
+	return r.GetRewriter().Rewrite(path)
+}
+
+// Rewrite returns the path of the request to the client:
+// it strips the prefix (whole segments only), replaces the regex matches, where
+// $1 is the first capture group, and adds the prefix
+func (w *Rewriter) Rewrite(path string) string {
+	if w == nil {
+		return path
+	}
+	if w.stripPrefix != "" && hasPathPrefix(path, w.stripPrefix) {
+		stripped := strings.TrimSuffix(w.stripPrefix, "/")
+		path = path[len(stripped):]
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if w.regex != nil {
+		path = w.regex.ReplaceAllString(path, w.replacement)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if w.addPrefix != "" {
+		path = w.addPrefix + path
+	}
+	return path
+}
+
+// sortRoutes orders the routes by the priority, routes
+// with the same priority are ordered longest prefix
+// first, the regex routes are checked after them
+func sortRoutes(list []*Route) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority > list[j].Priority
+		}
+		if (list[i].Regex == nil) != (list[j].Regex == nil) {
+			return list[i].Regex == nil
+		}
+		if len(list[i].Prefix) != len(list[j].Prefix) {
+			return len(list[i].Prefix) > len(list[j].Prefix)
+		}
+		return list[i].Id < list[j].Id
+	})
+}
+
+// syncRoutes updates the routes of the hosts
+func (b *BackendManager) syncRoutes(rules []*routes.Route) {
+	b.log = logging.NewLogs("backendManager", "syncRoutes")
+
+	b.log.GetInfo().Msg("updating the routes")
+	hostRoutes := make(map[string][]*Route)
+	for _, rule := range rules {
+		route, err := NewRoute(rule)
+		if err != nil {
+			b.log.GetWarn().Str("when", "compile route").Int64("route", rule.Id).
+				Err(err).Msg("invalid route, skipped")
+			continue
+		}
+		hostRoutes[rule.Site.Host] = append(hostRoutes[rule.Site.Host], route)
+	}
+	for _, list := range hostRoutes {
+		sortRoutes(list)
+	}
+	b.routes = hostRoutes
+}
+
+// GetRoute returns the first route of the host that
+// matches the path, or nil if no route matches
+func (b *BackendManager) GetRoute(host, path string) *Route {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for _, route := range b.routes[host] {
+		if route.match(path) {
+			return route
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package backendManager
+
+import (
+	"reverseProxy/pkg/repositories/routes"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+)
+
+func TestBackendManager_GetRoute(t *testing.T) {
+	site := &sites.Site{Id: 1, Name: "vk", Host: "vk.com"}
+	rules := []*routes.Route{
+		{Id: 1, Prefix: "/api", Pool: "api", Site: site},
+		{Id: 2, Prefix: "/api/v2", Pool: "api-v2", Site: site},
+		{Id: 3, Prefix: "/static/", Pool: "static", Site: site},
+		{Id: 4, Regex: "^/api/v[0-9]+/admin", Priority: 10, Pool: "admin", Site: site},
+		{Id: 5, Regex: "\\.(png|jpg)$", Pool: "images", Site: site},
+		{Id: 6, Regex: "(", Pool: "invalid", Site: site},
+		{Id: 7, Prefix: "/both", Regex: "^/both", Pool: "invalid", Site: site},
+	}
+	b := &BackendManager{}
+	b.syncRoutes(rules)
+
+	tests := []struct {
+		name     string
+		host     string
+		path     string
+		wantPool string
+		wantNil  bool
+	}{
+		{
+			name:     "exact prefix",
+			host:     "vk.com",
+			path:     "/api",
+			wantPool: "api",
+		},
+		{
+			name:     "longest prefix first",
+			host:     "vk.com",
+			path:     "/api/v2/users",
+			wantPool: "api-v2",
+		},
+		{
+			name:     "prefix matches whole segments",
+			host:     "vk.com",
+			path:     "/apis",
+			wantPool: "",
+			wantNil:  true,
+		},
+		{
+			name:     "prefix with trailing slash",
+			host:     "vk.com",
+			path:     "/static/app.js",
+			wantPool: "static",
+		},
+		{
+			name:     "higher priority first",
+			host:     "vk.com",
+			path:     "/api/v2/admin/users",
+			wantPool: "admin",
+		},
+		{
+			name:     "regex after prefixes of the same priority",
+			host:     "vk.com",
+			path:     "/static/logo.png",
+			wantPool: "static",
+		},
+		{
+			name:     "regex",
+			host:     "vk.com",
+			path:     "/images/logo.png",
+			wantPool: "images",
+		},
+		{
+			name:     "invalid routes are skipped",
+			host:     "vk.com",
+			path:     "/both",
+			wantPool: "",
+			wantNil:  true,
+		},
+		{
+			name:     "host without routes",
+			host:     "example.com",
+			path:     "/api",
+			wantPool: "",
+			wantNil:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := b.GetRoute(tt.host, tt.path)
+			if (got == nil) != tt.wantNil {
+				t.Errorf("GetRoute() got = %v, wantNil %v", got, tt.wantNil)
+			}
+			if got.GetPool() != tt.wantPool {
+				t.Errorf("GetRoute() pool = %q, want %q", got.GetPool(), tt.wantPool)
+			}
+		})
+	}
+}
+
+func TestRoute_Rewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		rewrite  routes.Rewrite
+		path     string
+		wantPath string
+	}{
+		{
+			name:     "strip prefix",
+			rewrite:  routes.Rewrite{StripPrefix: "/billing"},
+			path:     "/billing/invoices",
+			wantPath: "/invoices",
+		},
+		{
+			name:     "strip whole path",
+			rewrite:  routes.Rewrite{StripPrefix: "/billing/"},
+			path:     "/billing",
+			wantPath: "/billing",
+		},
+		{
+			name:     "strip prefix to root",
+			rewrite:  routes.Rewrite{StripPrefix: "/billing"},
+			path:     "/billing",
+			wantPath: "/",
+		},
+		{
+			name:     "strip prefix matches whole segments",
+			rewrite:  routes.Rewrite{StripPrefix: "/billing"},
+			path:     "/billings/1",
+			wantPath: "/billings/1",
+		},
+		{
+			name:     "add prefix",
+			rewrite:  routes.Rewrite{AddPrefix: "/internal/"},
+			path:     "/users",
+			wantPath: "/internal/users",
+		},
+		{
+			name:     "regex with capture groups",
+			rewrite:  routes.Rewrite{Regex: "^/v([0-9]+)/(.*)$", Replacement: "/api/$2/version/$1"},
+			path:     "/v2/users",
+			wantPath: "/api/users/version/2",
+		},
+		{
+			name: "all rules in order",
+			rewrite: routes.Rewrite{
+				StripPrefix: "/billing",
+				Regex:       "^/old/",
+				Replacement: "/new/",
+				AddPrefix:   "/v1",
+			},
+			path:     "/billing/old/invoices",
+			wantPath: "/v1/new/invoices",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := NewRoute(&routes.Route{Prefix: "/", Rewrite: tt.rewrite})
+			if err != nil {
+				t.Fatalf("NewRoute() error = %v", err)
+			}
+			if got := route.Rewrite(tt.path); got != tt.wantPath {
+				t.Errorf("Rewrite() got = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
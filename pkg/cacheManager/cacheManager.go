@@ -0,0 +1,358 @@
+package cacheManager
+
+import (
+	"container/list"
+	"net/http"
+	"reverseProxy/pkg/logging"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxEntryShare limits the size of one response
+// to the share of the size of the cache
+const maxEntryShare = 8
+
+var CacheMgr *CacheManager
+
+type CacheManager struct {
+	maxSize      int64
+	maxEntrySize int64
+	size         int64
+	entries      map[string][]*list.Element
+	lru          *list.List
+	generations  map[string]uint64
+	mux          sync.Mutex
+	log          *logging.Logger
+}
+
+// Entry is the stored response, it is not
+// changed after it has been stored
+type Entry struct {
+	key          string
+	host         string
+	path         string
+	uri          string
+	vary         map[string]string
+	size         int64
+	Status       int
+	Header       http.Header
+	Body         []byte
+	Tags         []string
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+// NewCacheManager returns new struct CacheManager,
+// maxSize limits the memory of the stored responses
+// in bytes
+func NewCacheManager(maxSize int64) *CacheManager {
+	return &CacheManager{
+		maxSize:      maxSize,
+		maxEntrySize: maxSize / maxEntryShare,
+		entries:      make(map[string][]*list.Element),
+		lru:          list.New(),
+		generations:  make(map[string]uint64),
+	}
+}
+
+// MaxEntrySize returns the maximum size
+// of the body of the stored response
+func (c *CacheManager) MaxEntrySize() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.maxEntrySize
+}
+
+// Key returns the key of the responses to the
+// request, the responses of the aliases of the
+// host are grouped by the host of the site
+func Key(r *http.Request, host string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + strings.ToLower(host) + r.URL.RequestURI()
+}
+
+// Get returns the stored response to the request
+// of the host, whose Vary headers match the request
+func (c *CacheManager) Get(r *http.Request, host string) *Entry {
+	if c == nil {
+		return nil
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, element := range c.entries[Key(r, host)] {
+		entry := element.Value.(*Entry)
+		if entry.matches(r) {
+			c.lru.MoveToFront(element)
+			return entry
+		}
+	}
+	return nil
+}
+
+// NewEntry creates the response to the request
+// of the host, which may be stored
+func NewEntry(r *http.Request, host string, status int, header http.Header, body []byte,
+	requestTime, responseTime time.Time) *Entry {
+	return &Entry{
+		key:          Key(r, host),
+		host:         strings.ToLower(host),
+		path:         r.URL.Path,
+		uri:          r.URL.RequestURI(),
+		vary:         varyValues(r, header),
+		Status:       status,
+		Header:       header.Clone(),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+}
+
+// Updated returns the copy of the response updated
+// with the header of the 304 response of the
+// revalidation
+func (e *Entry) Updated(header http.Header, requestTime, responseTime time.Time) *Entry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	for name, values := range header {
+		if name == "Content-Length" {
+			continue
+		}
+		updated.Header[name] = values
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	return &updated
+}
+
+// Generation returns the number of invalidations of
+// the host, it is taken before the request is sent
+// to the backend and passed to Store
+func (c *CacheManager) Generation(host string) uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.generations[strings.ToLower(host)]
+}
+
+// Store stores the response, the stored response with the
+// same Vary headers is replaced, the response is not stored
+// if the host has been invalidated since the generation
+func (c *CacheManager) Store(entry *Entry, generation uint64) bool {
+	if c == nil || int64(len(entry.Body)) > c.maxEntrySize {
+		return false
+	}
+	entry.size = entry.calcSize()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.log = logging.NewLogs("cacheManager", "store")
+	if c.generations[entry.host] != generation {
+		c.log.GetInfo().Str("key", entry.key).Msg("host invalidated during request, response not stored")
+		return false
+	}
+	for _, element := range c.entries[entry.key] {
+		if sameVary(element.Value.(*Entry).vary, entry.vary) {
+			c.remove(element)
+			break
+		}
+	}
+	c.entries[entry.key] = append(c.entries[entry.key], c.lru.PushFront(entry))
+	c.size += entry.size
+	for c.size > c.maxSize {
+		oldest := c.lru.Back()
+		c.log.GetInfo().Str("key", oldest.Value.(*Entry).key).Msg("evict response")
+		c.remove(oldest)
+	}
+	return true
+}
+
+// InvalidateURL removes all stored responses
+// to the URI with the query of the host
+func (c *CacheManager) InvalidateURL(host, uri string) int {
+	return c.invalidate(host, func(e *Entry) bool {
+		return e.uri == uri
+	})
+}
+
+// InvalidatePrefix removes all stored responses
+// to the paths of the host with the prefix
+func (c *CacheManager) InvalidatePrefix(host, prefix string) int {
+	return c.invalidate(host, func(e *Entry) bool {
+		return strings.HasPrefix(e.path, prefix)
+	})
+}
+
+// InvalidateTag removes all stored responses
+// of the host tagged with the surrogate key
+func (c *CacheManager) InvalidateTag(host, tag string) int {
+	return c.invalidate(host, func(e *Entry) bool {
+		for _, t := range e.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// InvalidateHost removes all stored
+// responses of the host
+func (c *CacheManager) InvalidateHost(host string) int {
+	return c.invalidate(host, func(e *Entry) bool {
+		return true
+	})
+}
+
+// invalidate removes the matching responses of the host and
+// starts the new generation of the host, so that the
+// responses of the requests in progress are not stored
+func (c *CacheManager) invalidate(host string, match func(e *Entry) bool) int {
+	if c == nil {
+		return 0
+	}
+	host = strings.ToLower(host)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generations[host]++
+	removed := 0
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*Entry)
+		if entry.host == host && match(entry) {
+			c.remove(element)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+// Tags returns the surrogate keys of the stored responses
+// of the host with the number of their responses
+func (c *CacheManager) Tags(host string) map[string]int {
+	tags := make(map[string]int)
+	if c == nil {
+		return tags
+	}
+	host = strings.ToLower(host)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for element := c.lru.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*Entry)
+		if entry.host != host {
+			continue
+		}
+		for _, tag := range entry.Tags {
+			tags[tag]++
+		}
+	}
+	return tags
+}
+
+// Size returns the size of the
+// stored responses
+func (c *CacheManager) Size() int64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.size
+}
+
+// remove removes the element from the list and the
+// responses of its key, the lock must be held
+func (c *CacheManager) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*Entry)
+	c.size -= entry.size
+	elements := c.entries[entry.key]
+	for i, e := range elements {
+		if e == element {
+			elements = append(elements[:i], elements[i+1:]...)
+			break
+		}
+	}
+	if len(elements) == 0 {
+		delete(c.entries, entry.key)
+		return
+	}
+	c.entries[entry.key] = elements
+}
+
+// calcSize estimates the memory
+// of the stored response
+func (e *Entry) calcSize() int64 {
+	size := int64(len(e.key) + len(e.host) + len(e.path) + len(e.uri) + len(e.Body))
+	for _, tag := range e.Tags {
+		size += int64(len(tag))
+	}
+	for name, values := range e.Header {
+		for _, value := range values {
+			size += int64(len(name) + len(value))
+		}
+	}
+	for name, value := range e.vary {
+		size += int64(len(name) + len(value))
+	}
+	return size
+}
+
+// matches determines whether the request has
+// the values of the Vary headers of the response
+func (e *Entry) matches(r *http.Request) bool {
+	for name, value := range e.vary {
+		if headerValue(r.Header, name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// varyValues returns the values of the
+// request headers listed in Vary
+func varyValues(r *http.Request, header http.Header) map[string]string {
+	values := make(map[string]string)
+	for _, name := range headerTokens(header, "Vary") {
+		name = http.CanonicalHeaderKey(name)
+		values[name] = headerValue(r.Header, name)
+	}
+	return values
+}
+
+// sameVary determines whether the
+// values of the Vary headers are equal
+func sameVary(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// headerValue returns the normalized value
+// of the header with all its lines
+func headerValue(header http.Header, name string) string {
+	return strings.Join(headerTokens(header, name), ", ")
+}
+
+// headerTokens returns the comma separated
+// tokens of the header
+func headerTokens(header http.Header, name string) []string {
+	tokens := []string{}
+	for _, value := range header[name] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
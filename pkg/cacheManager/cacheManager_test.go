@@ -0,0 +1,218 @@
+package cacheManager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newRequest(target string, header http.Header) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	return r
+}
+
+func TestCacheManager_Get(t *testing.T) {
+	c := NewCacheManager(1 << 20)
+	now := time.Now()
+	c.Store(NewEntry(newRequest("http://example.com/a", nil), "example.com", http.StatusOK,
+		http.Header{"Cache-Control": {"max-age=60"}}, []byte("a"), now, now), 0)
+	c.Store(NewEntry(newRequest("http://example.com/v", http.Header{"Accept-Encoding": {"gzip"}}), "example.com",
+		http.StatusOK, http.Header{"Vary": {"Accept-Encoding"}}, []byte("gzip"), now, now), 0)
+	c.Store(NewEntry(newRequest("http://example.com/v", http.Header{"Accept-Encoding": {"br, gzip"}}), "example.com",
+		http.StatusOK, http.Header{"Vary": {"accept-encoding"}}, []byte("br"), now, now), 0)
+
+	tests := []struct {
+		name     string
+		request  *http.Request
+		wantBody string
+	}{
+		{
+			name:     "stored response",
+			request:  newRequest("http://EXAMPLE.com/a", nil),
+			wantBody: "a",
+		},
+		{
+			name:    "other query",
+			request: newRequest("http://example.com/a?b=1", nil),
+		},
+		{
+			name:    "other host",
+			request: newRequest("http://example.org/a", nil),
+		},
+		{
+			name:     "vary matches first variant",
+			request:  newRequest("http://example.com/v", http.Header{"Accept-Encoding": {"gzip"}}),
+			wantBody: "gzip",
+		},
+		{
+			name:     "vary matches normalized second variant",
+			request:  newRequest("http://example.com/v", http.Header{"Accept-Encoding": {"br,gzip"}}),
+			wantBody: "br",
+		},
+		{
+			name:    "vary does not match",
+			request: newRequest("http://example.com/v", nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.Get(tt.request, tt.request.Host)
+			if tt.wantBody == "" {
+				if got != nil {
+					t.Errorf("Get() = %q, want nil", got.Body)
+				}
+				return
+			}
+			if got == nil || string(got.Body) != tt.wantBody {
+				t.Errorf("Get() = %v, want body %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCacheManager_Store(t *testing.T) {
+	now := time.Now()
+	body := make([]byte, 100)
+	c := NewCacheManager(1000)
+	for _, key := range []string{"/1", "/2", "/3", "/4", "/5", "/6", "/7"} {
+		c.Store(NewEntry(newRequest("http://example.com"+key, nil), "example.com", http.StatusOK, http.Header{},
+			body, now, now), 0)
+	}
+	// the first response is used, the second
+	// becomes the least recently used one
+	if c.Get(newRequest("http://example.com/1", nil), "example.com") == nil {
+		t.Fatalf("response /1 not stored")
+	}
+	c.Store(NewEntry(newRequest("http://example.com/8", nil), "example.com", http.StatusOK, http.Header{},
+		body, now, now), 0)
+
+	if c.Size() > 1000 {
+		t.Errorf("Size() = %d, want at most 1000", c.Size())
+	}
+	if c.Get(newRequest("http://example.com/2", nil), "example.com") != nil {
+		t.Errorf("least recently used response /2 not evicted")
+	}
+	for _, key := range []string{"/1", "/8"} {
+		if c.Get(newRequest("http://example.com"+key, nil), "example.com") == nil {
+			t.Errorf("response %s evicted", key)
+		}
+	}
+
+	if c.Store(NewEntry(newRequest("http://example.com/big", nil), "example.com", http.StatusOK, http.Header{},
+		make([]byte, 126), now, now), 0) {
+		t.Errorf("response over the entry limit stored")
+	}
+
+	c.Store(NewEntry(newRequest("http://example.com/1", nil), "example.com", http.StatusOK, http.Header{},
+		[]byte("new"), now, now), 0)
+	if got := c.Get(newRequest("http://example.com/1", nil), "example.com"); got == nil || string(got.Body) != "new" {
+		t.Errorf("response /1 not replaced, got %v", got)
+	}
+
+	c.InvalidateURL("example.com", "/1")
+	if c.Get(newRequest("http://example.com/1", nil), "example.com") != nil {
+		t.Errorf("response /1 not invalidated")
+	}
+}
+
+func TestCacheManager_invalidate(t *testing.T) {
+	now := time.Now()
+	newTagged := func(target string, tags ...string) *Entry {
+		r := newRequest(target, nil)
+		entry := NewEntry(r, r.Host, http.StatusOK, http.Header{}, []byte(target), now, now)
+		entry.Tags = tags
+		return entry
+	}
+	stored := []*Entry{
+		newTagged("http://example.com/static/a.css", "static", "css"),
+		newTagged("http://example.com/static/b.js?v=1", "static"),
+		newTagged("http://example.com/page?id=1", "page"),
+		newTagged("http://example.com/page?id=2", "page"),
+		newTagged("http://example.org/static/a.css", "static"),
+	}
+	tests := []struct {
+		name        string
+		invalidate  func(c *CacheManager) int
+		wantRemoved []string
+	}{
+		{
+			name:        "tag",
+			invalidate:  func(c *CacheManager) int { return c.InvalidateTag("example.com", "static") },
+			wantRemoved: []string{"http://example.com/static/a.css", "http://example.com/static/b.js?v=1"},
+		},
+		{
+			name:        "url with query",
+			invalidate:  func(c *CacheManager) int { return c.InvalidateURL("EXAMPLE.com", "/page?id=2") },
+			wantRemoved: []string{"http://example.com/page?id=2"},
+		},
+		{
+			name:        "prefix",
+			invalidate:  func(c *CacheManager) int { return c.InvalidatePrefix("example.com", "/page") },
+			wantRemoved: []string{"http://example.com/page?id=1", "http://example.com/page?id=2"},
+		},
+		{
+			name:       "host",
+			invalidate: func(c *CacheManager) int { return c.InvalidateHost("example.com") },
+			wantRemoved: []string{"http://example.com/static/a.css", "http://example.com/static/b.js?v=1",
+				"http://example.com/page?id=1", "http://example.com/page?id=2"},
+		},
+		{
+			name:       "unknown tag",
+			invalidate: func(c *CacheManager) int { return c.InvalidateTag("example.com", "js") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCacheManager(1 << 20)
+			for _, entry := range stored {
+				copied := *entry
+				c.Store(&copied, 0)
+			}
+			generation := c.Generation("example.com")
+			if got := tt.invalidate(c); got != len(tt.wantRemoved) {
+				t.Errorf("invalidated %d responses, want %d", got, len(tt.wantRemoved))
+			}
+			for _, entry := range stored {
+				removed := false
+				for _, target := range tt.wantRemoved {
+					removed = removed || target == entry.key
+				}
+				if got := c.Get(newRequest(entry.key, nil), entry.host); (got == nil) != removed {
+					t.Errorf("response %s stored = %v, want %v", entry.key, got != nil, !removed)
+				}
+			}
+			// the response of the request started
+			// before the invalidation is not stored
+			if c.Store(newTagged("http://example.com/late"), generation) {
+				t.Errorf("response stored after invalidation of its generation")
+			}
+			if !c.Store(newTagged("http://example.com/late"), c.Generation("example.com")) {
+				t.Errorf("response of new generation not stored")
+			}
+		})
+	}
+}
+
+func TestCacheManager_Tags(t *testing.T) {
+	now := time.Now()
+	c := NewCacheManager(1 << 20)
+	for target, tags := range map[string][]string{
+		"http://example.com/a": {"static", "css"},
+		"http://example.com/b": {"static"},
+		"http://example.org/c": {"other"},
+	} {
+		r := newRequest(target, nil)
+		entry := NewEntry(r, r.Host, http.StatusOK, http.Header{}, nil, now, now)
+		entry.Tags = tags
+		c.Store(entry, 0)
+	}
+	want := map[string]int{"static": 2, "css": 1}
+	if got := c.Tags("example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,7 @@
+// cacheManager stores a structure
+// with the cached responses of backends.
+//
+// Responsible for choosing the stored response
+// of the request and evicting the least recently
+// used responses.
+package cacheManager
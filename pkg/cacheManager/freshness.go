@@ -0,0 +1,321 @@
+package cacheManager
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// heuristicFraction is the share of the time since the last
+	// modification used as the freshness lifetime of the response
+	// without explicit expiration
+	heuristicFraction    = 10
+	maxHeuristicLifetime = 24 * time.Hour
+)
+
+// heuristicStatuses are the statuses of the responses
+// that may be stored without explicit expiration
+var heuristicStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// cacheControl stores the directives
+// of the Cache-Control header
+type cacheControl map[string]string
+
+// parseCacheControl parses the directives
+// of the Cache-Control header
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range headerTokens(header, "Cache-Control") {
+		name, value := directive, ""
+		if i := strings.Index(directive, "="); i >= 0 {
+			name, value = directive[:i], strings.Trim(strings.TrimSpace(directive[i+1:]), "\"")
+		}
+		cc[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return cc
+}
+
+// has determines whether the
+// directive is present
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// sharedWithAuthorization determines whether the response
+// to the request with Authorization may be shared, only the
+// explicit directives allow it (RFC 9111, section 3.5)
+func (cc cacheControl) sharedWithAuthorization() bool {
+	return cc.has("public") || cc.has("s-maxage") || cc.has("must-revalidate")
+}
+
+// seconds returns the value of the directive
+// with the number of seconds
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		// the invalid value is taken as stale
+		return 0, true
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// Storable determines whether the response to the
+// request may be stored in the shared cache
+func Storable(r *http.Request, status int, header http.Header) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if status < http.StatusOK || status == http.StatusPartialContent ||
+		status == http.StatusNotModified {
+		return false
+	}
+	if parseCacheControl(r.Header).has("no-store") {
+		return false
+	}
+	cc := parseCacheControl(header)
+	if cc.has("no-store") || cc.has("private") {
+		return false
+	}
+	if !cc.sharedWithAuthorization() && r.Header.Get("Authorization") != "" {
+		return false
+	}
+	for _, name := range headerTokens(header, "Vary") {
+		if name == "*" {
+			return false
+		}
+	}
+	// the responses setting cookies are personal, the
+	// trailers are not known until the body is read
+	if len(header["Set-Cookie"]) > 0 || len(header["Trailer"]) > 0 {
+		return false
+	}
+	if _, ok := header["Expires"]; ok {
+		return true
+	}
+	if cc.has("max-age") || cc.has("s-maxage") || cc.has("public") {
+		return true
+	}
+	// without expiration only the response which
+	// may be revalidated is worth storing
+	return heuristicStatuses[status] && (header.Get("ETag") != "" || header.Get("Last-Modified") != "")
+}
+
+// Shareable determines whether the stored response may be sent
+// to the request, the request with Authorization only gets the
+// responses explicitly allowed to be shared
+func (e *Entry) Shareable(r *http.Request) bool {
+	return r.Header.Get("Authorization") == "" || parseCacheControl(e.Header).sharedWithAuthorization()
+}
+
+// FreshnessLifetime returns the time during which
+// the response may be used without revalidation
+func (e *Entry) FreshnessLifetime() time.Duration {
+	cc := parseCacheControl(e.Header)
+	if lifetime, ok := cc.seconds("s-maxage"); ok {
+		return lifetime
+	}
+	if lifetime, ok := cc.seconds("max-age"); ok {
+		return lifetime
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		expiresTime, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		if lifetime := expiresTime.Sub(e.date()); lifetime > 0 {
+			return lifetime
+		}
+		return 0
+	}
+	if !heuristicStatuses[e.Status] {
+		return 0
+	}
+	lastModified, err := http.ParseTime(e.Header.Get("Last-Modified"))
+	if err != nil {
+		return 0
+	}
+	lifetime := e.date().Sub(lastModified) / heuristicFraction
+	if lifetime > maxHeuristicLifetime {
+		return maxHeuristicLifetime
+	}
+	if lifetime < 0 {
+		return 0
+	}
+	return lifetime
+}
+
+// date returns the time of the Date header,
+// or the time of the response without it
+func (e *Entry) date() time.Time {
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		return e.ResponseTime
+	}
+	return date
+}
+
+// Age returns the time since the response
+// was generated by the backend
+func (e *Entry) Age(now time.Time) time.Duration {
+	apparentAge := e.ResponseTime.Sub(e.date())
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	ageValue, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64)
+	if err != nil || ageValue < 0 {
+		ageValue = 0
+	}
+	correctedAge := time.Duration(ageValue)*time.Second + e.ResponseTime.Sub(e.RequestTime)
+	if correctedAge < apparentAge {
+		correctedAge = apparentAge
+	}
+	return correctedAge + now.Sub(e.ResponseTime)
+}
+
+// IsFresh determines whether the response may
+// be sent to the request without revalidation
+func (e *Entry) IsFresh(r *http.Request, now time.Time) bool {
+	if parseCacheControl(e.Header).has("no-cache") {
+		return false
+	}
+	requestCC := parseCacheControl(r.Header)
+	if requestCC.has("no-cache") {
+		return false
+	}
+	if len(r.Header["Cache-Control"]) == 0 && strings.EqualFold(r.Header.Get("Pragma"), "no-cache") {
+		return false
+	}
+	lifetime := e.FreshnessLifetime()
+	age := e.Age(now)
+	if maxAge, ok := requestCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := requestCC.seconds("min-fresh"); ok && lifetime-age < minFresh {
+		return false
+	}
+	return lifetime > age
+}
+
+// staleness returns the time since
+// the response became stale
+func (e *Entry) staleness(now time.Time) time.Duration {
+	return e.Age(now) - e.FreshnessLifetime()
+}
+
+// prohibitsStale determines whether the response
+// must not be sent when it is stale, s-maxage
+// implies proxy-revalidate for the shared cache
+func (e *Entry) prohibitsStale() bool {
+	cc := parseCacheControl(e.Header)
+	return cc.has("must-revalidate") || cc.has("proxy-revalidate") || cc.has("s-maxage") ||
+		cc.has("no-cache")
+}
+
+// StaleIfError determines whether the stale response may be
+// sent when the backends fail, force allows it regardless of
+// the stale-if-error directives of the request and response
+func (e *Entry) StaleIfError(r *http.Request, now time.Time, force bool) bool {
+	if e.prohibitsStale() {
+		return false
+	}
+	if force {
+		return true
+	}
+	staleness := e.staleness(now)
+	for _, cc := range []cacheControl{parseCacheControl(r.Header), parseCacheControl(e.Header)} {
+		if limit, ok := cc.seconds("stale-if-error"); ok && staleness <= limit {
+			return true
+		}
+	}
+	return false
+}
+
+// StaleWhileRevalidate determines whether the stale response
+// may be sent while it is revalidated in the background
+func (e *Entry) StaleWhileRevalidate(r *http.Request, now time.Time) bool {
+	if e.prohibitsStale() {
+		return false
+	}
+	requestCC := parseCacheControl(r.Header)
+	if requestCC.has("no-cache") || requestCC.has("max-age") || requestCC.has("min-fresh") {
+		return false
+	}
+	limit, ok := parseCacheControl(e.Header).seconds("stale-while-revalidate")
+	return ok && e.staleness(now) <= limit
+}
+
+// OnlyIfCached determines whether the request
+// must not be sent to the backend
+func OnlyIfCached(r *http.Request) bool {
+	return parseCacheControl(r.Header).has("only-if-cached")
+}
+
+// HasValidators determines whether the
+// response may be revalidated
+func (e *Entry) HasValidators() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// SetValidators sets the conditional headers
+// of the request revalidating the response
+func (e *Entry) SetValidators(req *http.Request) {
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	if etag := e.Header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+// NotModified determines whether the conditional
+// headers of the request match the response
+func (e *Entry) NotModified(r *http.Request) bool {
+	if e.Status != http.StatusOK {
+		return false
+	}
+	if ifNoneMatch := headerTokens(r.Header, "If-None-Match"); len(ifNoneMatch) > 0 {
+		etag := weakETag(e.Header.Get("ETag"))
+		for _, tag := range ifNoneMatch {
+			if tag == "*" || (etag != "" && weakETag(tag) == etag) {
+				return true
+			}
+		}
+		return false
+	}
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(e.Header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
+
+// weakETag returns the opaque tag of the ETag,
+// If-None-Match uses the weak comparison
+func weakETag(etag string) string {
+	return strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+}
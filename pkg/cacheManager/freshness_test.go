@@ -0,0 +1,362 @@
+package cacheManager
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStorable(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		requestHeader http.Header
+		status        int
+		header        http.Header
+		want          bool
+	}{
+		{
+			name:   "max-age",
+			status: http.StatusOK,
+			header: http.Header{"Cache-Control": {"max-age=60"}},
+			want:   true,
+		},
+		{
+			name:   "expires",
+			status: http.StatusInternalServerError,
+			header: http.Header{"Expires": {"Thu, 01 Jan 1970 00:00:00 GMT"}},
+			want:   true,
+		},
+		{
+			name:   "heuristic with validator",
+			status: http.StatusOK,
+			header: http.Header{"Etag": {"\"v1\""}},
+			want:   true,
+		},
+		{
+			name:   "no freshness and no validators",
+			status: http.StatusOK,
+			header: http.Header{},
+		},
+		{
+			name:   "not get",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			header: http.Header{"Cache-Control": {"max-age=60"}},
+		},
+		{
+			name:          "request no-store",
+			requestHeader: http.Header{"Cache-Control": {"no-store"}},
+			status:        http.StatusOK,
+			header:        http.Header{"Cache-Control": {"max-age=60"}},
+		},
+		{
+			name:   "private",
+			status: http.StatusOK,
+			header: http.Header{"Cache-Control": {"private, max-age=60"}},
+		},
+		{
+			name:   "vary all",
+			status: http.StatusOK,
+			header: http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"Accept, *"}},
+		},
+		{
+			name:   "set cookie",
+			status: http.StatusOK,
+			header: http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"id=1"}},
+		},
+		{
+			name:   "partial content",
+			status: http.StatusPartialContent,
+			header: http.Header{"Cache-Control": {"max-age=60"}},
+		},
+		{
+			name:          "authorization",
+			requestHeader: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}},
+			status:        http.StatusOK,
+			header:        http.Header{"Cache-Control": {"max-age=60"}},
+		},
+		{
+			name:          "authorization with public",
+			requestHeader: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}},
+			status:        http.StatusOK,
+			header:        http.Header{"Cache-Control": {"public, max-age=60"}},
+			want:          true,
+		},
+		{
+			name:          "authorization with s-maxage",
+			requestHeader: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}},
+			status:        http.StatusOK,
+			header:        http.Header{"Cache-Control": {"s-maxage=60"}},
+			want:          true,
+		},
+		{
+			name:          "authorization with must-revalidate",
+			requestHeader: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}},
+			status:        http.StatusOK,
+			header:        http.Header{"Cache-Control": {"max-age=60, must-revalidate"}},
+			want:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest("http://example.com/", tt.requestHeader)
+			if tt.method != "" {
+				r.Method = tt.method
+			}
+			if got := Storable(r, tt.status, tt.header); got != tt.want {
+				t.Errorf("Storable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_Shareable(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestHeader http.Header
+		header        http.Header
+		want          bool
+	}{
+		{
+			name:   "without authorization",
+			header: http.Header{"Cache-Control": {"max-age=60"}},
+			want:   true,
+		},
+		{
+			name:          "authorization",
+			requestHeader: http.Header{"Authorization": {"Basic Ym9iOnNlY3JldA=="}},
+			header:        http.Header{"Cache-Control": {"max-age=60"}},
+		},
+		{
+			name:          "authorization with public",
+			requestHeader: http.Header{"Authorization": {"Basic Ym9iOnNlY3JldA=="}},
+			header:        http.Header{"Cache-Control": {"public, max-age=60"}},
+			want:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &Entry{Status: http.StatusOK, Header: tt.header}
+			r := newRequest("http://example.com/", tt.requestHeader)
+			if got := entry.Shareable(r); got != tt.want {
+				t.Errorf("Shareable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_IsFresh(t *testing.T) {
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-30 * time.Second).Format(http.TimeFormat)
+	tests := []struct {
+		name          string
+		header        http.Header
+		requestHeader http.Header
+		want          bool
+	}{
+		{
+			name:   "max-age not expired",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=60"}},
+			want:   true,
+		},
+		{
+			name:   "max-age expired",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=20"}},
+		},
+		{
+			name:   "age header counted",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=60"}, "Age": {"40"}},
+		},
+		{
+			name:   "s-maxage overrides max-age",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=60, s-maxage=10"}},
+		},
+		{
+			name: "expires",
+			header: http.Header{"Date": {date},
+				"Expires": {now.Add(time.Minute).Format(http.TimeFormat)}},
+			want: true,
+		},
+		{
+			name:   "invalid expires",
+			header: http.Header{"Date": {date}, "Expires": {"0"}},
+		},
+		{
+			name: "heuristic",
+			header: http.Header{"Date": {date},
+				"Last-Modified": {now.Add(-time.Hour).Format(http.TimeFormat)}},
+			want: true,
+		},
+		{
+			name:   "response no-cache",
+			header: http.Header{"Date": {date}, "Cache-Control": {"no-cache, max-age=60"}},
+		},
+		{
+			name:          "request no-cache",
+			header:        http.Header{"Date": {date}, "Cache-Control": {"max-age=60"}},
+			requestHeader: http.Header{"Cache-Control": {"no-cache"}},
+		},
+		{
+			name:          "request pragma no-cache",
+			header:        http.Header{"Date": {date}, "Cache-Control": {"max-age=60"}},
+			requestHeader: http.Header{"Pragma": {"no-cache"}},
+		},
+		{
+			name:          "request max-age",
+			header:        http.Header{"Date": {date}, "Cache-Control": {"max-age=60"}},
+			requestHeader: http.Header{"Cache-Control": {"max-age=10"}},
+		},
+		{
+			name:          "request min-fresh",
+			header:        http.Header{"Date": {date}, "Cache-Control": {"max-age=60"}},
+			requestHeader: http.Header{"Cache-Control": {"min-fresh=40"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &Entry{
+				Status:       http.StatusOK,
+				Header:       tt.header,
+				RequestTime:  now.Add(-30 * time.Second),
+				ResponseTime: now.Add(-30 * time.Second),
+			}
+			r := newRequest("http://example.com/", tt.requestHeader)
+			if got := entry.IsFresh(r, now); got != tt.want {
+				t.Errorf("IsFresh() = %v, want %v (lifetime %v, age %v)", got, tt.want,
+					entry.FreshnessLifetime(), entry.Age(now))
+			}
+		})
+	}
+}
+
+func TestEntry_NotModified(t *testing.T) {
+	entry := &Entry{
+		Status: http.StatusOK,
+		Header: http.Header{
+			"Etag":          {"\"v1\""},
+			"Last-Modified": {"Sat, 01 May 2021 10:00:00 GMT"},
+		},
+	}
+	tests := []struct {
+		name          string
+		requestHeader http.Header
+		want          bool
+	}{
+		{
+			name:          "etag matches",
+			requestHeader: http.Header{"If-None-Match": {"\"v0\", W/\"v1\""}},
+			want:          true,
+		},
+		{
+			name:          "etag does not match",
+			requestHeader: http.Header{"If-None-Match": {"\"v2\""}},
+		},
+		{
+			name: "etag takes precedence",
+			requestHeader: http.Header{"If-None-Match": {"\"v2\""},
+				"If-Modified-Since": {"Sat, 01 May 2021 11:00:00 GMT"}},
+		},
+		{
+			name:          "not modified since",
+			requestHeader: http.Header{"If-Modified-Since": {"Sat, 01 May 2021 11:00:00 GMT"}},
+			want:          true,
+		},
+		{
+			name:          "modified since",
+			requestHeader: http.Header{"If-Modified-Since": {"Sat, 01 May 2021 09:00:00 GMT"}},
+		},
+		{
+			name: "not conditional",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest("http://example.com/", tt.requestHeader)
+			if got := entry.NotModified(r); got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_serveStale(t *testing.T) {
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-30 * time.Second).Format(http.TimeFormat)
+	tests := []struct {
+		name          string
+		header        http.Header
+		requestHeader http.Header
+		force         bool
+		wantIfError   bool
+		wantRevalid   bool
+	}{
+		{
+			name:   "no directives",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=10"}},
+		},
+		{
+			name:        "forced by site",
+			header:      http.Header{"Date": {date}, "Cache-Control": {"max-age=10"}},
+			force:       true,
+			wantIfError: true,
+		},
+		{
+			name:        "stale-if-error of response",
+			header:      http.Header{"Date": {date}, "Cache-Control": {"max-age=10, stale-if-error=60"}},
+			wantIfError: true,
+		},
+		{
+			name:   "stale-if-error of response expired",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=10, stale-if-error=5"}},
+		},
+		{
+			name:          "stale-if-error of request",
+			header:        http.Header{"Date": {date}, "Cache-Control": {"max-age=10"}},
+			requestHeader: http.Header{"Cache-Control": {"stale-if-error=60"}},
+			wantIfError:   true,
+		},
+		{
+			name:        "stale-while-revalidate",
+			header:      http.Header{"Date": {date}, "Cache-Control": {"max-age=10, stale-while-revalidate=60"}},
+			wantRevalid: true,
+		},
+		{
+			name:   "stale-while-revalidate expired",
+			header: http.Header{"Date": {date}, "Cache-Control": {"max-age=10, stale-while-revalidate=5"}},
+		},
+		{
+			name:          "stale-while-revalidate with request no-cache",
+			header:        http.Header{"Date": {date}, "Cache-Control": {"max-age=10, stale-while-revalidate=60"}},
+			requestHeader: http.Header{"Cache-Control": {"no-cache"}},
+		},
+		{
+			name: "must-revalidate",
+			header: http.Header{"Date": {date},
+				"Cache-Control": {"max-age=10, must-revalidate, stale-if-error=60, stale-while-revalidate=60"}},
+			force: true,
+		},
+		{
+			name:   "s-maxage",
+			header: http.Header{"Date": {date}, "Cache-Control": {"s-maxage=10, stale-while-revalidate=60"}},
+			force:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &Entry{
+				Status:       http.StatusOK,
+				Header:       tt.header,
+				RequestTime:  now.Add(-30 * time.Second),
+				ResponseTime: now.Add(-30 * time.Second),
+			}
+			r := newRequest("http://example.com/", tt.requestHeader)
+			if got := entry.StaleIfError(r, now, tt.force); got != tt.wantIfError {
+				t.Errorf("StaleIfError() = %v, want %v", got, tt.wantIfError)
+			}
+			if got := entry.StaleWhileRevalidate(r, now); got != tt.wantRevalid {
+				t.Errorf("StaleWhileRevalidate() = %v, want %v", got, tt.wantRevalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package certificateManager
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/certificates"
+	"reverseProxy/pkg/repositories/siteHosts"
+	"reverseProxy/pkg/siteManager"
+	"sync"
+	"time"
+)
+
+var (
+	CertificateMgr         *CertificateManager
+	ErrCertificateNotFound = fmt.Errorf("certificate not found")
+)
+
+type CertificateManager struct {
+	// hosts finds the site of the server name like the
+	// requests are matched, certs are the certificates
+	// of the sites by their id
+	hosts    *siteManager.HostMatcher
+	certs    map[int64]*tls.Certificate
+	loaded   map[int64]*loadedCertificate
+	fallback *tls.Certificate
+	tickDB   *time.Ticker
+	ctx      context.Context
+	mux      sync.RWMutex
+	e        chan error
+	log      *logging.Logger
+}
+
+// loadedCertificate stores the parsed certificate
+// with its PEM data, so that the certificate is
+// parsed again only when it changes
+type loadedCertificate struct {
+	cert    string
+	key     string
+	tlsCert *tls.Certificate
+}
+
+// NewCertificateManager returns new struct CertificateManager,
+// fallback is used when the site has no certificate
+// and may be nil
+func NewCertificateManager(ctx context.Context, fallback *tls.Certificate) *CertificateManager {
+	return &CertificateManager{
+		hosts:    siteManager.NewHostMatcher(),
+		certs:    make(map[int64]*tls.Certificate),
+		loaded:   make(map[int64]*loadedCertificate),
+		fallback: fallback,
+		tickDB:   time.NewTicker(5 * time.Second),
+		ctx:      ctx,
+		e:        make(chan error),
+	}
+}
+
+// syncCertificates parses the new and changed certificates
+// and updates the certificates of hosts, the site hosts of
+// the sites get the certificates of their sites, the host of
+// the site wins over the alias of another site
+func (c *CertificateManager) syncCertificates(list []*certificates.Certificate,
+	aliases []*siteHosts.SiteHost) error {
+	c.log = logging.NewLogs("certificateManager", "syncCertificates")
+
+	loaded := make(map[int64]*loadedCertificate, len(list))
+	hosts := siteManager.NewHostMatcher()
+	certs := make(map[int64]*tls.Certificate, len(list))
+	for _, certificate := range list {
+		current, ok := c.loaded[certificate.Id]
+		if !ok || current.cert != certificate.Cert || current.key != certificate.Key {
+			c.log.GetInfo().Str("host", certificate.Site.Host).Msg("load certificate")
+			tlsCert, err := tls.X509KeyPair([]byte(certificate.Cert), []byte(certificate.Key))
+			if err != nil {
+				c.log.GetWarn().Str("when", "parse certificate").Int64("id", certificate.Id).
+					Err(err).Msg("invalid certificate, skipped")
+				continue
+			}
+			current = &loadedCertificate{
+				cert:    certificate.Cert,
+				key:     certificate.Key,
+				tlsCert: &tlsCert,
+			}
+		}
+		loaded[certificate.Id] = current
+		if _, ok := certs[certificate.Site.Id]; !ok {
+			if err := hosts.Add(certificate.Site.Host, &siteManager.Site{Site: certificate.Site}); err != nil {
+				c.log.GetWarn().Str("when", "add host of certificate").Int64("id", certificate.Id).
+					Err(err).Msg("invalid host of site, certificate skipped")
+				continue
+			}
+		}
+		certs[certificate.Site.Id] = current.tlsCert
+	}
+	for _, alias := range aliases {
+		if _, ok := certs[alias.Site.Id]; !ok {
+			continue
+		}
+		if err := hosts.Add(alias.Pattern, &siteManager.Site{Site: alias.Site}); err != nil {
+			c.log.GetWarn().Str("when", "add site host").Int64("host", alias.Id).
+				Err(err).Msg("invalid site host, skipped")
+		}
+	}
+
+	c.loaded = loaded
+	c.hosts = hosts
+	c.certs = certs
+	return nil
+}
+
+// ValidateCertificate checks that the certificate
+// chain and the private key make a valid pair
+func ValidateCertificate(certificate *certificates.Certificate) error {
+	_, err := tls.X509KeyPair([]byte(certificate.Cert), []byte(certificate.Key))
+	return err
+}
+
+// SyncCertificates updates the current certificates of database
+func (c *CertificateManager) SyncCertificates() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	list, err := certificates.List()
+	if err != nil {
+		c.e <- err
+		return
+	}
+	aliases, err := siteHosts.List()
+	if err != nil {
+		c.e <- err
+		return
+	}
+	if err := c.syncCertificates(list, aliases); err != nil {
+		c.e <- err
+	}
+}
+
+// GetCertificate returns the certificate of the site
+// requested in the TLS handshake, or the fallback
+// certificate. The server name is matched with the
+// hosts and the site hosts of the sites in the order
+// of the requests: the exact host, the wildcards,
+// then the regexes
+func (c *CertificateManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	if site := c.hosts.Match(hello.ServerName); site != nil {
+		return c.certs[site.Id], nil
+	}
+	if c.fallback != nil {
+		return c.fallback, nil
+	}
+	return nil, ErrCertificateNotFound
+}
+
+// Serve with the ticks running SyncCertificates
+// during the operation of the application
+func (c *CertificateManager) Serve() error {
+	defer c.tickDB.Stop()
+	defer close(c.e)
+	go c.SyncCertificates()
+	for {
+		select {
+		case <-c.tickDB.C:
+			go c.SyncCertificates()
+		case err := <-c.e:
+			return err
+		case <-c.ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package certificateManager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reverseProxy/pkg/repositories/certificates"
+	"reverseProxy/pkg/repositories/siteHosts"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+// generateCertificate returns PEM encoded self-signed
+// certificate and key for the host
+func generateCertificate(t *testing.T, host string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %v", err)
+	}
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return string(certPem), string(keyPem)
+}
+
+func TestCertificateManager_GetCertificate(t *testing.T) {
+	vkCert, vkKey := generateCertificate(t, "vk.com")
+	exampleCert, exampleKey := generateCertificate(t, "example.com")
+	wildcardCert, wildcardKey := generateCertificate(t, "*.example.com")
+	regexCert, regexKey := generateCertificate(t, "node.example.net")
+	fallbackCert, fallbackKey := generateCertificate(t, "fallback")
+	fallback, err := tls.X509KeyPair([]byte(fallbackCert), []byte(fallbackKey))
+	if err != nil {
+		t.Fatalf("unable to parse fallback certificate: %v", err)
+	}
+
+	list := []*certificates.Certificate{
+		{Id: 1, Cert: vkCert, Key: vkKey, Site: &sites.Site{Id: 1, Host: "vk.com"}},
+		{Id: 2, Cert: exampleCert, Key: exampleKey, Site: &sites.Site{Id: 2, Host: "Example.com"}},
+		{Id: 3, Cert: "broken", Key: "broken", Site: &sites.Site{Id: 3, Host: "broken.com"}},
+		{Id: 4, Cert: wildcardCert, Key: wildcardKey, Site: &sites.Site{Id: 4, Host: "*.example.com"}},
+		{Id: 5, Cert: regexCert, Key: regexKey, Site: &sites.Site{Id: 5, Host: `~^node[0-9]+\.example\.net$`}},
+	}
+	aliases := []*siteHosts.SiteHost{
+		{Id: 1, Pattern: "www.vk.com", Site: &sites.Site{Id: 1, Host: "vk.com"}},
+		{Id: 2, Pattern: "*.vk.ru", Site: &sites.Site{Id: 1, Host: "vk.com"}},
+		{Id: 3, Pattern: "example.com", Site: &sites.Site{Id: 1, Host: "vk.com"}},
+		{Id: 4, Pattern: "www.broken.com", Site: &sites.Site{Id: 3, Host: "broken.com"}},
+	}
+
+	tests := []struct {
+		name       string
+		fallback   *tls.Certificate
+		serverName string
+		wantHost   string
+		wantErr    bool
+	}{
+		{
+			name:       "certificate of site",
+			serverName: "vk.com",
+			wantHost:   "vk.com",
+		},
+		{
+			name:       "server name in another case",
+			serverName: "EXAMPLE.COM",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "server name with trailing dot",
+			serverName: "vk.com.",
+			wantHost:   "vk.com",
+		},
+		{
+			name:       "wildcard certificate of subdomain",
+			serverName: "shop.Example.com",
+			wantHost:   "*.example.com",
+		},
+		{
+			name:       "regex certificate",
+			serverName: "node12.example.net",
+			wantHost:   "node.example.net",
+		},
+		{
+			name:       "exact host before wildcard",
+			serverName: "example.com",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "site host of site",
+			serverName: "www.vk.com",
+			wantHost:   "vk.com",
+		},
+		{
+			name:       "wildcard site host of site",
+			serverName: "m.vk.ru",
+			wantHost:   "vk.com",
+		},
+		{
+			name:       "host of site before site host of another site",
+			serverName: "example.com",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "fallback certificate of site host of invalid certificate",
+			fallback:   &fallback,
+			serverName: "www.broken.com",
+			wantHost:   "fallback",
+		},
+		{
+			name:       "fallback certificate of unknown site",
+			fallback:   &fallback,
+			serverName: "odnoklassniki.ru",
+			wantHost:   "fallback",
+		},
+		{
+			name:       "fallback certificate of invalid certificate",
+			fallback:   &fallback,
+			serverName: "broken.com",
+			wantHost:   "fallback",
+		},
+		{
+			name:       "error without fallback certificate",
+			serverName: "odnoklassniki.ru",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCertificateManager(nil, tt.fallback)
+			defer c.tickDB.Stop()
+			if err := c.syncCertificates(list, aliases); err != nil {
+				t.Fatalf("syncCertificates() error = %v", err)
+			}
+			got, err := c.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			leaf, err := x509.ParseCertificate(got.Certificate[0])
+			if err != nil {
+				t.Fatalf("unable to parse certificate: %v", err)
+			}
+			if leaf.Subject.CommonName != tt.wantHost {
+				t.Errorf("GetCertificate() got certificate of %s, want %s", leaf.Subject.CommonName, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestCertificateManager_syncCertificates(t *testing.T) {
+	cert, key := generateCertificate(t, "vk.com")
+	newCert, newKey := generateCertificate(t, "vk.com")
+	c := NewCertificateManager(nil, nil)
+	defer c.tickDB.Stop()
+
+	list := []*certificates.Certificate{
+		{Id: 1, Cert: cert, Key: key, Site: &sites.Site{Id: 1, Host: "vk.com"}},
+	}
+	if err := c.syncCertificates(list, nil); err != nil {
+		t.Fatalf("syncCertificates() error = %v", err)
+	}
+	first := c.certs[1]
+
+	if err := c.syncCertificates(list, nil); err != nil {
+		t.Fatalf("syncCertificates() error = %v", err)
+	}
+	if c.certs[1] != first {
+		t.Errorf("unchanged certificate was parsed again")
+	}
+
+	list[0] = &certificates.Certificate{Id: 1, Cert: newCert, Key: newKey, Site: &sites.Site{Id: 1, Host: "vk.com"}}
+	if err := c.syncCertificates(list, nil); err != nil {
+		t.Fatalf("syncCertificates() error = %v", err)
+	}
+	if c.certs[1] == first {
+		t.Errorf("changed certificate was not reloaded")
+	}
+
+	if err := c.syncCertificates([]*certificates.Certificate{}, nil); err != nil {
+		t.Fatalf("syncCertificates() error = %v", err)
+	}
+	if site := c.hosts.Match("vk.com"); site != nil {
+		t.Errorf("deleted certificate is still used")
+	}
+}
+
+func TestValidateCertificate(t *testing.T) {
+	cert, key := generateCertificate(t, "vk.com")
+	_, otherKey := generateCertificate(t, "vk.com")
+	tests := []struct {
+		name        string
+		certificate *certificates.Certificate
+		wantErr     bool
+	}{
+		{name: "valid pair", certificate: &certificates.Certificate{Cert: cert, Key: key}},
+		{name: "key of another certificate", certificate: &certificates.Certificate{Cert: cert, Key: otherKey}, wantErr: true},
+		{name: "not PEM", certificate: &certificates.Certificate{Cert: "broken", Key: "broken"}, wantErr: true},
+		{name: "missing key", certificate: &certificates.Certificate{Cert: cert}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCertificate(tt.certificate); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
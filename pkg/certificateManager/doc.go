@@ -0,0 +1,6 @@
+// certificateManager stores a structure
+// with the TLS certificates of sites.
+//
+// Responsible for choosing the certificate
+// by the server name of the TLS handshake.
+package certificateManager
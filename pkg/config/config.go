@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/rs/zerolog"
 )
 
@@ -14,6 +16,19 @@ type EnvCache struct {
 	Password   string `envconfig:"PASSWORD" required:true`
 	Dbname     string `envconfig:"DBNAME" required:true`
 	Sslmode    string `envconfig:"SSLMODE" required:true`
+	RevTLSPort string `envconfig:"REVTLSPORT"`
+	TLSCert    string `envconfig:"TLSCERT"`
+	TLSKey     string `envconfig:"TLSKEY"`
+
+	RevReadHeaderTimeout time.Duration `envconfig:"REVREADHEADERTIMEOUT" default:"15s"`
+	RevReadTimeout       time.Duration `envconfig:"REVREADTIMEOUT" default:"0s"`
+	RevWriteTimeout      time.Duration `envconfig:"REVWRITETIMEOUT" default:"0s"`
+	RevIdleTimeout       time.Duration `envconfig:"REVIDLETIMEOUT" default:"60s"`
+	RouterReadTimeout    time.Duration `envconfig:"ROUTERREADTIMEOUT" default:"15s"`
+	RouterWriteTimeout   time.Duration `envconfig:"ROUTERWRITETIMEOUT" default:"15s"`
+
+	RevCacheSize      int64 `envconfig:"REVCACHESIZE" default:"67108864"`
+	RevMirrorRequests int64 `envconfig:"REVMIRRORREQUESTS" default:"100"`
 }
 
 // GetSSlmode returns field SSlMode
@@ -56,6 +71,61 @@ func (c EnvCache) GetRouterPort() string {
 	return c.RouterPort
 }
 
+// GetRevTLSPort returns field RevTLSPort
+func (c EnvCache) GetRevTLSPort() string {
+	return c.RevTLSPort
+}
+
+// GetTLSCert returns field TLSCert
+func (c EnvCache) GetTLSCert() string {
+	return c.TLSCert
+}
+
+// GetTLSKey returns field TLSKey
+func (c EnvCache) GetTLSKey() string {
+	return c.TLSKey
+}
+
+// GetRevReadHeaderTimeout returns field RevReadHeaderTimeout
+func (c EnvCache) GetRevReadHeaderTimeout() time.Duration {
+	return c.RevReadHeaderTimeout
+}
+
+// GetRevReadTimeout returns field RevReadTimeout
+func (c EnvCache) GetRevReadTimeout() time.Duration {
+	return c.RevReadTimeout
+}
+
+// GetRevWriteTimeout returns field RevWriteTimeout
+func (c EnvCache) GetRevWriteTimeout() time.Duration {
+	return c.RevWriteTimeout
+}
+
+// GetRevIdleTimeout returns field RevIdleTimeout
+func (c EnvCache) GetRevIdleTimeout() time.Duration {
+	return c.RevIdleTimeout
+}
+
+// GetRouterReadTimeout returns field RouterReadTimeout
+func (c EnvCache) GetRouterReadTimeout() time.Duration {
+	return c.RouterReadTimeout
+}
+
+// GetRouterWriteTimeout returns field RouterWriteTimeout
+func (c EnvCache) GetRouterWriteTimeout() time.Duration {
+	return c.RouterWriteTimeout
+}
+
+// GetRevCacheSize returns field RevCacheSize
+func (c EnvCache) GetRevCacheSize() int64 {
+	return c.RevCacheSize
+}
+
+// GetRevMirrorRequests returns field RevMirrorRequests
+func (c EnvCache) GetRevMirrorRequests() int64 {
+	return c.RevMirrorRequests
+}
+
 // GetLogLevel determines which level
 // the LogLevel environment
 // corresponds to.
@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/cacheManager"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheHeader = "X-Cache"
+	cacheHit    = "HIT"
+	cacheMiss   = "MISS"
+	cacheStale  = "STALE"
+
+	surrogateKeyHeader = "Surrogate-Key"
+
+	warningStale            = "110 - \"Response is Stale\""
+	warningRevalidateFailed = "111 - \"Revalidation Failed\""
+)
+
+var revalidations = &revalidationSet{keys: make(map[string]struct{})}
+
+// cacheLookup stores the state of the cache for the request
+// sent to the backend, stored is the stale stored response
+// which is revalidated by the request if revalidate is set,
+// generation is the generation of the host before the request
+type cacheLookup struct {
+	stored     *cacheManager.Entry
+	revalidate bool
+	generation uint64
+}
+
+// revalidationSet stores the keys of the responses
+// revalidated in the background, so that a response
+// is revalidated by one request at a time
+type revalidationSet struct {
+	keys map[string]struct{}
+	mux  sync.Mutex
+}
+
+// start registers the key, it returns false if the
+// response is already being revalidated
+func (s *revalidationSet) start(key string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+	s.keys[key] = struct{}{}
+	return true
+}
+
+// done unregisters the key
+func (s *revalidationSet) done(key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.keys, key)
+}
+
+// running determines whether any response
+// is being revalidated
+func (s *revalidationSet) running() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.keys) > 0
+}
+
+// serveFromCache sends the fresh stored response to the user
+// and returns true, otherwise it returns the lookup of the
+// request, the lookup is nil when the response is not cached
+func (h RevHandler) serveFromCache(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	route *backendManager.Route) (*cacheLookup, bool) {
+	if !site.IsCacheEnabled() && !site.IsStaleEnabled() {
+		return nil, false
+	}
+	if (r.Method != http.MethodGet && r.Method != http.MethodHead) || isUpgradeRequest(r) {
+		return nil, false
+	}
+	lookup := &cacheLookup{
+		stored:     cacheManager.CacheMgr.Get(r, siteHost(r, site)),
+		generation: cacheManager.CacheMgr.Generation(siteHost(r, site)),
+	}
+	if lookup.stored != nil && !lookup.stored.Shareable(r) {
+		// the response stored for the other users is
+		// not sent to the user with Authorization
+		lookup.stored = nil
+	}
+	if !site.IsCacheEnabled() {
+		// the responses are stored only to
+		// be sent when the backends fail
+		return lookup, false
+	}
+	entry := lookup.stored
+	if entry == nil {
+		if cacheManager.OnlyIfCached(r) {
+			h.getLogs().GetInfo().Msg("response not cached, only-if-cached requested")
+			h.sendError(w, r, site, http.StatusGatewayTimeout, "not cached")
+			return nil, true
+		}
+		return lookup, false
+	}
+
+	now := time.Now()
+	if entry.IsFresh(r, now) {
+		h.getLogs().GetInfo().Msg("send cached response")
+		h.writeEntry(w, r, site, entry, now, cacheHit, "")
+		return nil, true
+	}
+	if cacheManager.OnlyIfCached(r) {
+		h.getLogs().GetInfo().Msg("send stale response, only-if-cached requested")
+		h.writeEntry(w, r, site, entry, now, cacheStale, warningStale)
+		return nil, true
+	}
+	if entry.StaleWhileRevalidate(r, now) && entry.HasValidators() {
+		h.getLogs().GetInfo().Msg("send stale response, revalidate in background")
+		h.writeEntry(w, r, site, entry, now, cacheStale, warningStale)
+		h.revalidateInBackground(r, site, route, entry)
+		return nil, true
+	}
+	if entry.HasValidators() {
+		h.getLogs().GetInfo().Msg("revalidate cached response")
+		lookup.revalidate = true
+	}
+	return lookup, false
+}
+
+// serveStale sends the stale stored response to the user
+// when the backends fail, it returns false if there is
+// no stored response allowed to be sent
+func (h RevHandler) serveStale(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	lookup *cacheLookup) bool {
+	if lookup == nil {
+		return false
+	}
+	// the response stored before the request
+	// may have been invalidated since
+	stored := cacheManager.CacheMgr.Get(r, siteHost(r, site))
+	if stored == nil || !stored.Shareable(r) {
+		return false
+	}
+	now := time.Now()
+	if !stored.StaleIfError(r, now, site.IsStaleEnabled()) {
+		return false
+	}
+	h.getLogs().GetWarn().Msg("backends failed, send stale response")
+	warning := warningStale
+	if lookup.revalidate {
+		warning = warningRevalidateFailed
+	}
+	h.writeEntry(w, r, site, stored, now, cacheStale, warning)
+	return true
+}
+
+// isBackendError determines whether the status of the
+// response means that the backend fails
+func isBackendError(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// revalidateInBackground revalidates the stale response,
+// the request is detached from the request of the user
+func (h RevHandler) revalidateInBackground(r *http.Request, site *siteManager.Site,
+	route *backendManager.Route, entry *cacheManager.Entry) {
+	key := cacheManager.Key(r, siteHost(r, site))
+	if !revalidations.start(key) {
+		return
+	}
+	r = r.Clone(context.Background())
+	r.Method = http.MethodGet
+	r.Body = http.NoBody
+	generation := cacheManager.CacheMgr.Generation(siteHost(r, site))
+	go func() {
+		defer revalidations.done(key)
+		client, err := getClient(siteHost(r, site), route.GetPool())
+		if err != nil {
+			h.getLogs().GetWarn().Str("when", "revalidate in background").
+				Err(err).Msg("unable to get client")
+			return
+		}
+		req := newBackendRequest(r, client, site, route)
+		entry.SetValidators(req)
+		requestTime := time.Now()
+		resp, err := client.Cl.Do(req)
+		responseTime := time.Now()
+		if err != nil {
+			h.getLogs().GetWarn().Str("when", "revalidate in background").
+				Err(err).Msg("unable to get response")
+			return
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				return
+			}
+		}()
+
+		removeHopHeaders(resp.Header)
+		tags := surrogateKeys(resp.Header)
+		if resp.StatusCode == http.StatusNotModified {
+			cacheManager.CacheMgr.Store(updateEntry(entry, resp.Header, tags, requestTime, responseTime),
+				generation)
+			return
+		}
+		if isBackendError(resp.StatusCode) || !cacheManager.Storable(r, resp.StatusCode, resp.Header) {
+			return
+		}
+		limit := cacheManager.CacheMgr.MaxEntrySize()
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil || int64(len(body)) > limit {
+			return
+		}
+		stored := cacheManager.NewEntry(r, siteHost(r, site), resp.StatusCode, resp.Header, body,
+			requestTime, responseTime)
+		stored.Tags = tags
+		cacheManager.CacheMgr.Store(stored, generation)
+	}()
+}
+
+// surrogateKeys removes the surrogate keys from
+// the header of the response and returns them
+func surrogateKeys(header http.Header) []string {
+	tags := []string{}
+	for _, value := range header[surrogateKeyHeader] {
+		tags = append(tags, strings.Fields(value)...)
+	}
+	header.Del(surrogateKeyHeader)
+	return tags
+}
+
+// updateEntry returns the stored response updated by the
+// 304 response, the new surrogate keys replace the old ones
+func updateEntry(entry *cacheManager.Entry, header http.Header, tags []string,
+	requestTime, responseTime time.Time) *cacheManager.Entry {
+	updated := entry.Updated(header, requestTime, responseTime)
+	if len(tags) > 0 {
+		updated.Tags = tags
+	}
+	return updated
+}
+
+// writeEntry sends the stored response to the user,
+// or 304 if the user already has it
+func (h RevHandler) writeEntry(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	entry *cacheManager.Entry, now time.Time, cacheStatus, warning string) {
+	header := w.Header()
+	copyHeader(header, entry.Header)
+	header.Set("Age", strconv.FormatInt(int64(entry.Age(now)/time.Second), 10))
+	header.Set(cacheHeader, cacheStatus)
+	if warning != "" {
+		header.Add("Warning", warning)
+	}
+	encoding := prepareCompression(header, r, site, entry.Status, int64(len(entry.Body)))
+	applyHeaderRules(header, site.GetResponseHeaders(), headerVars(r, site, nil))
+
+	if entry.NotModified(r) {
+		// the 304 response has no representation
+		// metadata except Content-Location
+		for name := range header {
+			if strings.HasPrefix(name, "Content-") && name != "Content-Location" {
+				header.Del(name)
+			}
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if entry.Status != http.StatusNoContent && encoding == "" {
+		header.Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	}
+	w.WriteHeader(entry.Status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if encoding != "" {
+		compressed := newCompressWriter(w, encoding)
+		defer compressed.release()
+		if _, err := compressed.Write(entry.Body); err != nil {
+			h.getLogs().GetError().Str("when", "send cached response").
+				Err(err).Msg("unable to send response")
+			return
+		}
+		if err := compressed.Close(); err != nil {
+			h.getLogs().GetError().Str("when", "send cached response").
+				Err(err).Msg("unable to compress response")
+		}
+		return
+	}
+	if _, err := w.Write(entry.Body); err != nil {
+		h.getLogs().GetError().Str("when", "send cached response").
+			Err(err).Msg("unable to send response")
+	}
+}
+
+// invalidateCache removes the stored responses to the
+// URI changed by the successful unsafe request
+func invalidateCache(r *http.Request, site *siteManager.Site, status int) {
+	if (!site.IsCacheEnabled() && !site.IsStaleEnabled()) || status >= http.StatusBadRequest {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return
+	}
+	cacheManager.CacheMgr.InvalidateURL(siteHost(r, site), r.URL.RequestURI())
+}
+
+// cacheBody keeps the read body of the response
+// to store it, the body over the limit is not kept
+type cacheBody struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+// newCacheBody wraps the body of the response
+func newCacheBody(body io.ReadCloser, limit int64) *cacheBody {
+	return &cacheBody{ReadCloser: body, limit: limit}
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.overflow {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
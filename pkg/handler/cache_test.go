@@ -0,0 +1,465 @@
+package handler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/cacheManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRevHandler_cache(t *testing.T) {
+	var requests int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		switch r.URL.Path {
+		case "/static", "/account":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/revalidate":
+			w.Header().Set("Cache-Control", "max-age=0")
+			w.Header().Set("ETag", "\"v1\"")
+			if r.Header.Get("If-None-Match") == "\"v1\"" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		}
+		if r.Method == http.MethodGet {
+			w.Write([]byte("body of " + r.URL.Path))
+		}
+	}))
+	defer backend.Close()
+
+	oldCache := cacheManager.CacheMgr
+	cacheManager.CacheMgr = cacheManager.NewCacheManager(1 << 20)
+	defer func() { cacheManager.CacheMgr = oldCache }()
+	site := &siteManager.Site{Site: &sites.Site{Cache: sites.CacheSettings{Enabled: true}}}
+	proxy := newTestSiteProxy(backend, site)
+	defer proxy.Close()
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		requestHeader http.Header
+		wantStatus    int
+		wantCache     string
+		wantBody      string
+		// wantRequests is the number of requests
+		// received by the backend
+		wantRequests int64
+	}{
+		{
+			name:         "first request",
+			path:         "/static",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheMiss,
+			wantBody:     "body of /static",
+			wantRequests: 1,
+		},
+		{
+			name:         "fresh response",
+			path:         "/static",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheHit,
+			wantBody:     "body of /static",
+			wantRequests: 1,
+		},
+		{
+			name:          "conditional request to fresh response",
+			path:          "/static",
+			requestHeader: http.Header{"If-Modified-Since": {"Sat, 01 May 2021 10:00:00 GMT"}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheHit,
+			wantBody:      "body of /static",
+			wantRequests:  1,
+		},
+		{
+			name:          "request no-cache",
+			path:          "/static",
+			requestHeader: http.Header{"Cache-Control": {"no-cache"}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheMiss,
+			wantBody:      "body of /static",
+			wantRequests:  2,
+		},
+		{
+			name:         "unsafe request invalidates",
+			method:       http.MethodPost,
+			path:         "/static",
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		{
+			name:         "request after invalidation",
+			path:         "/static",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheMiss,
+			wantBody:     "body of /static",
+			wantRequests: 4,
+		},
+		{
+			name:         "first request of revalidated response",
+			path:         "/revalidate",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheMiss,
+			wantBody:     "body of /revalidate",
+			wantRequests: 5,
+		},
+		{
+			name:         "revalidated response",
+			path:         "/revalidate",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheHit,
+			wantBody:     "body of /revalidate",
+			wantRequests: 6,
+		},
+		{
+			name:          "conditional request to revalidated response",
+			path:          "/revalidate",
+			requestHeader: http.Header{"If-None-Match": {"\"v1\""}},
+			wantStatus:    http.StatusNotModified,
+			wantCache:     cacheHit,
+			wantRequests:  7,
+		},
+		{
+			name:         "private response",
+			path:         "/private",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheMiss,
+			wantBody:     "body of /private",
+			wantRequests: 8,
+		},
+		{
+			name:         "private response not stored",
+			path:         "/private",
+			wantStatus:   http.StatusOK,
+			wantCache:    cacheMiss,
+			wantBody:     "body of /private",
+			wantRequests: 9,
+		},
+		{
+			name:          "only-if-cached not stored",
+			path:          "/private",
+			requestHeader: http.Header{"Cache-Control": {"only-if-cached"}, "X-Request-Id": {"only-if-cached"}},
+			wantStatus:    http.StatusGatewayTimeout,
+			wantBody:      "{\"status\":504,\"message\":\"not cached\",\"request_id\":\"only-if-cached\"}\n",
+			wantRequests:  9,
+		},
+		{
+			name:          "response to first user",
+			path:          "/account",
+			requestHeader: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheMiss,
+			wantBody:      "body of /account",
+			wantRequests:  10,
+		},
+		{
+			name:          "response to first user not sent to second user",
+			path:          "/account",
+			requestHeader: http.Header{"Authorization": {"Basic Ym9iOnNlY3JldA=="}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheMiss,
+			wantBody:      "body of /account",
+			wantRequests:  11,
+		},
+		{
+			name:          "response stored without authorization not sent to user",
+			path:          "/static",
+			requestHeader: http.Header{"Authorization": {"Basic Ym9iOnNlY3JldA=="}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheMiss,
+			wantBody:      "body of /static",
+			wantRequests:  12,
+		},
+		{
+			name:          "public response to first user",
+			path:          "/public",
+			requestHeader: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheMiss,
+			wantBody:      "body of /public",
+			wantRequests:  13,
+		},
+		{
+			name:          "public response sent to second user",
+			path:          "/public",
+			requestHeader: http.Header{"Authorization": {"Basic Ym9iOnNlY3JldA=="}},
+			wantStatus:    http.StatusOK,
+			wantCache:     cacheHit,
+			wantBody:      "body of /public",
+			wantRequests:  13,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequest(method, proxy.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			for name, values := range tt.requestHeader {
+				req.Header[name] = values
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unable to read body: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(cacheHeader); got != tt.wantCache {
+				t.Errorf("%s = %q, want %q", cacheHeader, got, tt.wantCache)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if got := atomic.LoadInt64(&requests); got != tt.wantRequests {
+				t.Errorf("backend requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRevHandler_staleCache(t *testing.T) {
+	type step struct {
+		// backendStatus is sent by the backend, 0 means
+		// the backend closes the connection
+		backendStatus int
+		// user sends the request with basic authorization
+		user        string
+		wantStatus  int
+		wantCache   string
+		wantWarning string
+		wantBody    string
+	}
+	tests := []struct {
+		name         string
+		cacheControl string
+		cache        sites.CacheSettings
+		steps        []step
+		// wantRequests is the number of requests
+		// answered by the backend
+		wantRequests int64
+	}{
+		{
+			name:  "site serves stale when backend is dead",
+			cache: sites.CacheSettings{ServeStale: true},
+			steps: []step{
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantBody: "v1"},
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantBody: "v2"},
+				{backendStatus: 0, wantStatus: http.StatusOK, wantCache: cacheStale,
+					wantWarning: warningStale, wantBody: "v2"},
+				{backendStatus: http.StatusServiceUnavailable, wantStatus: http.StatusOK,
+					wantCache: cacheStale, wantWarning: warningStale, wantBody: "v2"},
+			},
+			wantRequests: 3,
+		},
+		{
+			name: "stale not served without settings",
+			steps: []step{
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantBody: "v1"},
+				{backendStatus: http.StatusServiceUnavailable, wantStatus: http.StatusServiceUnavailable,
+					wantBody: "v2"},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:  "stale not served to user with authorization",
+			cache: sites.CacheSettings{ServeStale: true},
+			steps: []step{
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantBody: "v1"},
+				{backendStatus: http.StatusServiceUnavailable, user: "alice",
+					wantStatus: http.StatusServiceUnavailable, wantBody: "v2"},
+				{backendStatus: http.StatusServiceUnavailable, wantStatus: http.StatusOK,
+					wantCache: cacheStale, wantWarning: warningStale, wantBody: "v1"},
+			},
+			wantRequests: 3,
+		},
+		{
+			name:         "stale-if-error of response",
+			cacheControl: "max-age=0, stale-if-error=60",
+			cache:        sites.CacheSettings{Enabled: true},
+			steps: []step{
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantCache: cacheMiss, wantBody: "v1"},
+				{backendStatus: http.StatusInternalServerError, wantStatus: http.StatusOK,
+					wantCache: cacheStale, wantWarning: warningRevalidateFailed, wantBody: "v1"},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:         "must-revalidate forbids stale",
+			cacheControl: "max-age=0, must-revalidate",
+			cache:        sites.CacheSettings{Enabled: true, ServeStale: true},
+			steps: []step{
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantCache: cacheMiss, wantBody: "v1"},
+				{backendStatus: http.StatusBadGateway, wantStatus: http.StatusBadGateway,
+					wantCache: cacheMiss, wantBody: "v2"},
+			},
+			wantRequests: 2,
+		},
+		{
+			name:         "stale-while-revalidate",
+			cacheControl: "max-age=0, stale-while-revalidate=60",
+			cache:        sites.CacheSettings{Enabled: true},
+			steps: []step{
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantCache: cacheMiss, wantBody: "v1"},
+				{backendStatus: http.StatusOK, wantStatus: http.StatusOK, wantCache: cacheStale,
+					wantWarning: warningStale, wantBody: "v1"},
+			},
+			// the second request revalidates in the background
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests, backendStatus int64
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := int(atomic.LoadInt64(&backendStatus))
+				if status == 0 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err == nil {
+						conn.Close()
+					}
+					return
+				}
+				n := atomic.AddInt64(&requests, 1)
+				w.Header().Set("ETag", fmt.Sprintf("\"v%d\"", n))
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(fmt.Sprintf("v%d", n)))
+			}))
+			defer backend.Close()
+
+			oldCache, oldGetClient := cacheManager.CacheMgr, getClient
+			cacheManager.CacheMgr = cacheManager.NewCacheManager(1 << 20)
+			client := &backendManager.Client{Alive: true, Address: backend.Listener.Addr().String()}
+			getClient = func(host, pool string, tried ...*backendManager.Client) (*backendManager.Client, error) {
+				if containsClient(tried, client) {
+					return nil, backendManager.ErrClientNotFound
+				}
+				return client, nil
+			}
+			defer func() { cacheManager.CacheMgr, getClient = oldCache, oldGetClient }()
+			site := &siteManager.Site{Site: &sites.Site{Cache: tt.cache}}
+			proxy := newTestSiteProxy(backend, site)
+			defer proxy.Close()
+
+			for i, s := range tt.steps {
+				atomic.StoreInt64(&backendStatus, int64(s.backendStatus))
+				req, err := http.NewRequest(http.MethodGet, proxy.URL+"/page", nil)
+				if err != nil {
+					t.Fatalf("step %d: unable to create request: %v", i, err)
+				}
+				if s.user != "" {
+					req.SetBasicAuth(s.user, "secret")
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("step %d: request failed: %v", i, err)
+				}
+				body, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatalf("step %d: unable to read body: %v", i, err)
+				}
+				if resp.StatusCode != s.wantStatus {
+					t.Errorf("step %d: status = %d, want %d", i, resp.StatusCode, s.wantStatus)
+				}
+				if got := resp.Header.Get(cacheHeader); got != s.wantCache {
+					t.Errorf("step %d: %s = %q, want %q", i, cacheHeader, got, s.wantCache)
+				}
+				if got := resp.Header.Get("Warning"); got != s.wantWarning {
+					t.Errorf("step %d: Warning = %q, want %q", i, got, s.wantWarning)
+				}
+				if string(body) != s.wantBody {
+					t.Errorf("step %d: body = %q, want %q", i, body, s.wantBody)
+				}
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for (atomic.LoadInt64(&requests) < tt.wantRequests || revalidations.running()) &&
+				time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if got := atomic.LoadInt64(&requests); got != tt.wantRequests {
+				t.Errorf("backend requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRevHandler_surrogateKeys(t *testing.T) {
+	var requests int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set(surrogateKeyHeader, "static  css")
+		w.Write([]byte("body"))
+	}))
+	defer backend.Close()
+
+	oldCache := cacheManager.CacheMgr
+	cacheManager.CacheMgr = cacheManager.NewCacheManager(1 << 20)
+	defer func() { cacheManager.CacheMgr = oldCache }()
+	site := &siteManager.Site{Site: &sites.Site{Host: "example.com", Cache: sites.CacheSettings{Enabled: true}}}
+	proxy := newTestSiteProxy(backend, site)
+	defer proxy.Close()
+
+	get := func() *http.Response {
+		resp, err := http.Get(proxy.URL + "/style.css")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, wantCache := range []string{cacheMiss, cacheHit} {
+		resp := get()
+		if got := resp.Header.Get(cacheHeader); got != wantCache {
+			t.Errorf("%s = %q, want %q", cacheHeader, got, wantCache)
+		}
+		if got := resp.Header.Get(surrogateKeyHeader); got != "" {
+			t.Errorf("%s = %q sent to the user", surrogateKeyHeader, got)
+		}
+	}
+
+	// the responses are grouped by the host of the site
+	host := site.Host
+	want := map[string]int{"static": 1, "css": 1}
+	if got := cacheManager.CacheMgr.Tags(host); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+	if got := cacheManager.CacheMgr.InvalidateTag(host, "css"); got != 1 {
+		t.Errorf("InvalidateTag() = %d, want 1", got)
+	}
+	if got := get().Header.Get(cacheHeader); got != cacheMiss {
+		t.Errorf("%s after invalidation = %q, want %q", cacheHeader, got, cacheMiss)
+	}
+	if got := atomic.LoadInt64(&requests); got != 2 {
+		t.Errorf("backend requests = %d, want 2", got)
+	}
+}
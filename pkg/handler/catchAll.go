@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+// serveCatchAll sends the response or the redirect of the
+// default site to the request of the host matching no site,
+// it returns false if the request is proxied to the backends
+func (h RevHandler) serveCatchAll(w http.ResponseWriter, r *http.Request, site *siteManager.Site) bool {
+	switch site.GetCatchAllAction() {
+	case sites.CatchAllResponse:
+		h.getLogs().GetInfo().Str("when", "catch all").Str("host", r.Host).Msg("send response of default site")
+		body := []byte(site.CatchAll.Body)
+		w.Header().Set("Content-Type", http.DetectContentType(body))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(site.GetCatchAllStatus())
+		if r.Method != http.MethodHead {
+			if _, err := w.Write(body); err != nil {
+				h.getLogs().GetError().Str("when", "catch all").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+		}
+		return true
+	case sites.CatchAllRedirect:
+		target, err := url.Parse(site.CatchAll.Target)
+		if err != nil {
+			// the target is checked when the site is synced
+			h.sendError(w, r, site, http.StatusInternalServerError, "internal error")
+			return true
+		}
+		// the target without the path keeps
+		// the path and the query of the request
+		if target.Path == "" || target.Path == "/" {
+			target.Path, target.RawPath = r.URL.Path, r.URL.RawPath
+			if target.RawQuery == "" {
+				target.RawQuery = r.URL.RawQuery
+			}
+		}
+		h.getLogs().GetInfo().Str("when", "catch all").Str("host", r.Host).
+			Str("location", target.String()).Msg("redirect to default site")
+		http.Redirect(w, r, target.String(), site.GetCatchAllStatus())
+		return true
+	}
+	return false
+}
+
+// catchAllRoute returns the route of the request of the host
+// matching no site, the default site may send all of them to
+// one pool of its backends
+func catchAllRoute(site *siteManager.Site, route *backendManager.Route) *backendManager.Route {
+	if site.GetCatchAllAction() != sites.CatchAllPool {
+		return route
+	}
+	return &backendManager.Route{Pool: site.CatchAll.Pool}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestRevHandler_serveCatchAll(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     sites.CatchAllSettings
+		target       string
+		method       string
+		want         bool
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:     "served as site",
+			settings: sites.CatchAllSettings{Enabled: true},
+			target:   "http://10.0.0.1/health",
+			want:     false,
+		},
+		{
+			name:       "static response",
+			settings:   sites.CatchAllSettings{Enabled: true, Action: sites.CatchAllResponse, Body: "ok"},
+			target:     "http://10.0.0.1/health",
+			want:       true,
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+		{
+			name: "static response to head",
+			settings: sites.CatchAllSettings{Enabled: true, Action: sites.CatchAllResponse, Status: 404,
+				Body: "<html>not here</html>"},
+			target:     "http://10.0.0.1/",
+			method:     http.MethodHead,
+			want:       true,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "redirect keeps path",
+			settings: sites.CatchAllSettings{Enabled: true, Action: sites.CatchAllRedirect,
+				Target: "https://example.com"},
+			target:       "http://old.example.org/a/b?c=1",
+			want:         true,
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com/a/b?c=1",
+		},
+		{
+			name: "redirect to path",
+			settings: sites.CatchAllSettings{Enabled: true, Action: sites.CatchAllRedirect, Status: 301,
+				Target: "https://example.com/parked"},
+			target:       "http://old.example.org/a/b?c=1",
+			want:         true,
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://example.com/parked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &siteManager.Site{Site: &sites.Site{Host: "example.com", CatchAll: tt.settings}}
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tt.target, nil)
+			w := httptest.NewRecorder()
+			if got := (RevHandler{}).serveCatchAll(w, r, site); got != tt.want {
+				t.Fatalf("serveCatchAll() = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantLocation != "" {
+				if got := w.Header().Get("Location"); got != tt.wantLocation {
+					t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+				}
+				return
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCatchAllRoute(t *testing.T) {
+	route := &backendManager.Route{Pool: "api"}
+	site := &siteManager.Site{Site: &sites.Site{CatchAll: sites.CatchAllSettings{Enabled: true,
+		Action: sites.CatchAllPool, Pool: "parking"}}}
+	if got := catchAllRoute(site, route); got.GetPool() != "parking" {
+		t.Errorf("catchAllRoute() pool = %q, want parking", got.GetPool())
+	}
+	if got := catchAllRoute(&siteManager.Site{Site: &sites.Site{}}, route); got != route {
+		t.Errorf("catchAllRoute() of site action = %v, want route of path", got)
+	}
+	if got := catchAllRoute(nil, nil); got != nil {
+		t.Errorf("catchAllRoute() without default site = %v, want nil", got)
+	}
+}
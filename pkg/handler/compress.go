@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"io"
+	"mime"
+	"net/http"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	encodingGzip   = "gzip"
+	encodingBrotli = "br"
+	// brotliLevel is fast enough to compress
+	// the responses on the fly
+	brotliLevel = 4
+)
+
+// encodings lists the supported encodings
+// in the order of preference
+var encodings = []string{encodingBrotli, encodingGzip}
+
+// encoder compresses the written data
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	encodingGzip: {
+		New: func() interface{} {
+			return gzip.NewWriter(nil)
+		},
+	},
+	encodingBrotli: {
+		New: func() interface{} {
+			return brotli.NewWriterLevel(nil, brotliLevel)
+		},
+	},
+}
+
+// negotiateEncoding returns the supported encoding with
+// the highest quality in Accept-Encoding of the request,
+// empty if none of them is accepted
+func negotiateEncoding(header http.Header) string {
+	qualities := make(map[string]float64)
+	wildcard := 0.0
+	for _, value := range header["Accept-Encoding"] {
+		for _, token := range strings.Split(value, ",") {
+			params := strings.Split(token, ";")
+			coding := strings.ToLower(strings.TrimSpace(params[0]))
+			if coding == "" {
+				continue
+			}
+			quality := 1.0
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(strings.ToLower(param), "q=") {
+					continue
+				}
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					q = 0
+				}
+				quality = q
+			}
+			if coding == "x-gzip" {
+				coding = encodingGzip
+			}
+			if coding == "*" {
+				wildcard = quality
+				continue
+			}
+			qualities[coding] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, coding := range encodings {
+		quality, ok := qualities[coding]
+		if !ok {
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = coding, quality
+		}
+	}
+	return best
+}
+
+// prepareCompression determines the encoding of the response
+// to the user and fixes the headers of the compressed response,
+// empty means the response is sent as it is
+func prepareCompression(header http.Header, r *http.Request, site *siteManager.Site,
+	status int, contentLength int64) string {
+	if !site.IsCompressionEnabled() {
+		return ""
+	}
+	if status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusPartialContent || status == http.StatusNotModified {
+		return ""
+	}
+	// the encoded responses and the ranges
+	// of the body are never compressed again
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return ""
+	}
+	if header.Get("Content-Range") != "" || hasToken(header, "Cache-Control", "no-transform") {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType == "" || !site.Compresses(mediaType) {
+		return ""
+	}
+
+	// the response depends on Accept-Encoding
+	// even if it is not compressed for this user
+	if !hasToken(header, "Vary", "*") && !hasToken(header, "Vary", "Accept-Encoding") {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	// the length of the streamed
+	// response is unknown
+	if contentLength >= 0 && contentLength < site.GetCompressionMinSize() {
+		return ""
+	}
+	encoding := negotiateEncoding(r.Header)
+	if encoding == "" {
+		return ""
+	}
+
+	header.Set("Content-Encoding", encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	// the compressed body differs from the body of the backend,
+	// so the strong validator is no longer valid for it
+	if etag := header.Get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("Etag", "W/"+etag)
+	}
+	return encoding
+}
+
+// hasToken determines whether the comma separated
+// values of the header contain the token
+func hasToken(header http.Header, name, token string) bool {
+	for _, value := range header[name] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compressWriter compresses the body written to
+// the user, Flush sends the compressed data written
+// so far, which keeps the streamed responses going
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	encoder  encoder
+}
+
+// newCompressWriter returns the writer compressing
+// with the pooled encoder of the encoding
+func newCompressWriter(w http.ResponseWriter, encoding string) *compressWriter {
+	enc := encoderPools[encoding].Get().(encoder)
+	enc.Reset(w)
+	return &compressWriter{
+		ResponseWriter: w,
+		encoding:       encoding,
+		encoder:        enc,
+	}
+}
+
+// Write compresses the data
+func (c *compressWriter) Write(p []byte) (int, error) {
+	return c.encoder.Write(p)
+}
+
+// Flush sends the compressed data to the user
+func (c *compressWriter) Flush() {
+	if err := c.encoder.Flush(); err != nil {
+		return
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes the end of the compressed body
+func (c *compressWriter) Close() error {
+	return c.encoder.Close()
+}
+
+// release returns the encoder to the pool, the
+// writer must not be used after release
+func (c *compressWriter) release() {
+	c.encoder.Reset(nil)
+	encoderPools[c.encoding].Put(c.encoder)
+}
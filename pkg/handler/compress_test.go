@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/cacheManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding []string
+		want           string
+	}{
+		{name: "no header"},
+		{name: "gzip", acceptEncoding: []string{"gzip, deflate"}, want: encodingGzip},
+		{name: "brotli preferred", acceptEncoding: []string{"gzip", "br"}, want: encodingBrotli},
+		{name: "quality", acceptEncoding: []string{"br;q=0.5, gzip;q=0.8"}, want: encodingGzip},
+		{name: "refused", acceptEncoding: []string{"br;q=0, gzip;q=0"}},
+		{name: "wildcard", acceptEncoding: []string{"*"}, want: encodingBrotli},
+		{name: "wildcard except brotli", acceptEncoding: []string{"br;q=0, *"}, want: encodingGzip},
+		{name: "x-gzip", acceptEncoding: []string{"X-GZIP"}, want: encodingGzip},
+		{name: "identity", acceptEncoding: []string{"identity"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.acceptEncoding != nil {
+				header["Accept-Encoding"] = tt.acceptEncoding
+			}
+			if got := negotiateEncoding(header); got != tt.want {
+				t.Errorf("negotiateEncoding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevHandler_compression(t *testing.T) {
+	body := strings.Repeat("{\"message\": \"hello\"}\n", 100)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Etag", "\"v1\"")
+		switch r.URL.Path {
+		case "/small":
+			w.Write([]byte("{}"))
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(body))
+			zw.Close()
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(body))
+		case "/stream":
+			// the streamed response has no Content-Length
+			// and is not passed to the minimum size
+			for i := 0; i < 3; i++ {
+				w.Write([]byte("{}\n"))
+				w.(http.Flusher).Flush()
+			}
+		default:
+			w.Write([]byte(body))
+		}
+	}))
+	defer backend.Close()
+
+	site := &siteManager.Site{Site: &sites.Site{Compression: sites.CompressionSettings{Enabled: true}}}
+	proxy := newTestSiteProxy(backend, site)
+	defer proxy.Close()
+	// the client must not decompress
+	// the responses itself
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+		wantEtag       string
+		wantVary       bool
+		wantBody       string
+	}{
+		{
+			name:           "gzip",
+			path:           "/",
+			acceptEncoding: "gzip",
+			wantEncoding:   encodingGzip,
+			wantEtag:       "W/\"v1\"",
+			wantVary:       true,
+			wantBody:       body,
+		},
+		{
+			name:           "brotli",
+			path:           "/",
+			acceptEncoding: "gzip, br",
+			wantEncoding:   encodingBrotli,
+			wantEtag:       "W/\"v1\"",
+			wantVary:       true,
+			wantBody:       body,
+		},
+		{
+			name:     "not accepted",
+			path:     "/",
+			wantEtag: "\"v1\"",
+			wantVary: true,
+			wantBody: body,
+		},
+		{
+			name:           "under minimum size",
+			path:           "/small",
+			acceptEncoding: "gzip",
+			wantEtag:       "\"v1\"",
+			wantVary:       true,
+			wantBody:       "{}",
+		},
+		{
+			name:           "already encoded",
+			path:           "/encoded",
+			acceptEncoding: "br",
+			wantEncoding:   encodingGzip,
+			wantEtag:       "\"v1\"",
+			wantBody:       body,
+		},
+		{
+			name:           "type not listed",
+			path:           "/image",
+			acceptEncoding: "gzip",
+			wantEtag:       "\"v1\"",
+			wantBody:       body,
+		},
+		{
+			name:           "streamed",
+			path:           "/stream",
+			acceptEncoding: "gzip",
+			wantEncoding:   encodingGzip,
+			wantEtag:       "W/\"v1\"",
+			wantVary:       true,
+			wantBody:       "{}\n{}\n{}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, proxy.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if got := resp.Header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := resp.Header.Get("Etag"); got != tt.wantEtag {
+				t.Errorf("Etag = %q, want %q", got, tt.wantEtag)
+			}
+			if got := resp.Header.Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("Vary = %q, want Accept-Encoding %v", resp.Header.Get("Vary"), tt.wantVary)
+			}
+			if tt.wantEncoding != "" && resp.ContentLength != -1 &&
+				resp.ContentLength == int64(len(tt.wantBody)) {
+				t.Errorf("Content-Length = %d of the uncompressed body", resp.ContentLength)
+			}
+
+			var reader io.Reader = resp.Body
+			switch tt.wantEncoding {
+			case encodingGzip:
+				zr, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				reader = zr
+			case encodingBrotli:
+				reader = brotli.NewReader(resp.Body)
+			}
+			got, err := ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("unable to read body: %v", err)
+			}
+			if string(got) != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRevHandler_compressCached(t *testing.T) {
+	body := strings.Repeat("<p>hello</p>\n", 200)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(body))
+	}))
+	defer backend.Close()
+
+	oldCache := cacheManager.CacheMgr
+	cacheManager.CacheMgr = cacheManager.NewCacheManager(1 << 20)
+	defer func() { cacheManager.CacheMgr = oldCache }()
+	site := &siteManager.Site{Site: &sites.Site{Cache: sites.CacheSettings{Enabled: true},
+		Compression: sites.CompressionSettings{Enabled: true}}}
+	proxy := newTestSiteProxy(backend, site)
+	defer proxy.Close()
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	// the body is stored uncompressed and compressed
+	// for each user by the accepted encoding
+	for _, tt := range []struct {
+		acceptEncoding string
+		wantCache      string
+		wantEncoding   string
+	}{
+		{acceptEncoding: "gzip", wantCache: cacheMiss, wantEncoding: encodingGzip},
+		{acceptEncoding: "br", wantCache: cacheHit, wantEncoding: encodingBrotli},
+		{wantCache: cacheHit},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/page", nil)
+		if tt.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var reader io.Reader = resp.Body
+		switch tt.wantEncoding {
+		case encodingGzip:
+			if reader, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatalf("invalid gzip body: %v", err)
+			}
+		case encodingBrotli:
+			reader = brotli.NewReader(resp.Body)
+		}
+		got, err := ioutil.ReadAll(reader)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("unable to read body: %v", err)
+		}
+		if cache := resp.Header.Get(cacheHeader); cache != tt.wantCache {
+			t.Errorf("%s = %q, want %q", cacheHeader, cache, tt.wantCache)
+		}
+		if encoding := resp.Header.Get("Content-Encoding"); encoding != tt.wantEncoding {
+			t.Errorf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+		}
+		if string(got) != body {
+			t.Errorf("body with encoding %q differs", tt.wantEncoding)
+		}
+	}
+}
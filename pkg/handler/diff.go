@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"reverseProxy/pkg/siteManager"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxDiffs limits the differences
+// of the responses in the diff log
+const maxDiffs = 10
+
+// keptResponse is the response compared with the response
+// of the other backend, the body is nil if it is over the
+// limit or has not been read to the end
+type keptResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// compareResponses returns the differences of the response of the
+// shadow backend from the primary response: the status, the compared
+// headers of the site and the body. The JSON bodies are compared as
+// values without the ignored paths, the bodies which are not kept or
+// can not be decoded are not compared and bodyCompared is false
+func compareResponses(primary, shadow *keptResponse, site *siteManager.Site) (diffs []string, bodyCompared bool) {
+	diffs = []string{}
+	if primary.status != shadow.status {
+		diffs = append(diffs, fmt.Sprintf("status %d != %d", primary.status, shadow.status))
+	}
+	for _, name := range site.MirrorCompareHeaders {
+		want, got := strings.Join(primary.header.Values(name), ", "), strings.Join(shadow.header.Values(name), ", ")
+		if want != got {
+			diffs = append(diffs, fmt.Sprintf("header %s %q != %q", name, want, got))
+		}
+	}
+	if primary.body == nil || shadow.body == nil {
+		return diffs, false
+	}
+
+	limit := site.GetMirrorMaxBodySize()
+	primaryBody, primaryOK := decodeBody(primary.header, primary.body, limit)
+	shadowBody, shadowOK := decodeBody(shadow.header, shadow.body, limit)
+	if !primaryOK || !shadowOK {
+		return diffs, false
+	}
+	if isJSON(primary.header) && isJSON(shadow.header) {
+		var primaryValue, shadowValue interface{}
+		if json.Unmarshal(primaryBody, &primaryValue) == nil && json.Unmarshal(shadowBody, &shadowValue) == nil {
+			for _, path := range site.MirrorIgnorePaths {
+				removePath(primaryValue, path)
+				removePath(shadowValue, path)
+			}
+			diffJSON("$", primaryValue, shadowValue, &diffs)
+			return diffs, true
+		}
+	}
+	if !bytes.Equal(primaryBody, shadowBody) {
+		diffs = append(diffs, "body")
+	}
+	return diffs, true
+}
+
+// decodeBody returns the body without the gzip or brotli
+// encoding, ok is false if the encoding is not supported,
+// the body can not be decoded or is over the limit after
+// decoding
+func decodeBody(header http.Header, body []byte, limit int64) ([]byte, bool) {
+	var reader io.Reader
+	switch strings.ToLower(header.Get("Content-Encoding")) {
+	case "", "identity":
+		return body, true
+	case encodingGzip:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, false
+		}
+		reader = gzipReader
+	case encodingBrotli:
+		reader = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return nil, false
+	}
+	decoded, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil || int64(len(decoded)) > limit {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// isJSON determines whether the body
+// of the response is JSON
+func isJSON(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == mediaTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// removePath removes the value of the split path from
+// the JSON value, "*" matches any key of an object or
+// item of an array, the removed items become null
+func removePath(value interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if len(path) == 1 {
+				delete(v, key)
+			} else {
+				removePath(item, path[1:])
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if path[0] != "*" && path[0] != strconv.Itoa(i) {
+				continue
+			}
+			if len(path) == 1 {
+				v[i] = nil
+			} else {
+				removePath(item, path[1:])
+			}
+		}
+	}
+}
+
+// diffJSON adds the paths of the different values of
+// the JSON values to the differences
+func diffJSON(path string, primary, shadow interface{}, diffs *[]string) {
+	if len(*diffs) >= maxDiffs {
+		return
+	}
+	switch p := primary.(type) {
+	case map[string]interface{}:
+		s, ok := shadow.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(p)+len(s))
+		for key := range p {
+			keys = append(keys, key)
+		}
+		for key := range s {
+			if _, ok := p[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			primaryItem, primaryOK := p[key]
+			shadowItem, shadowOK := s[key]
+			if primaryOK != shadowOK {
+				if len(*diffs) < maxDiffs {
+					*diffs = append(*diffs, "body "+path+"."+key)
+				}
+				continue
+			}
+			diffJSON(path+"."+key, primaryItem, shadowItem, diffs)
+		}
+		return
+	case []interface{}:
+		s, ok := shadow.([]interface{})
+		if !ok || len(p) != len(s) {
+			break
+		}
+		for i := range p {
+			diffJSON(path+"."+strconv.Itoa(i), p[i], s[i], diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(primary, shadow) && len(*diffs) < maxDiffs {
+		*diffs = append(*diffs, "body "+path)
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"net/http"
+	"reflect"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestCompareResponses(t *testing.T) {
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write([]byte(`{"name":"a","updated_at":1}`))
+	writer.Close()
+	var compressed bytes.Buffer
+	brotliWriter := brotli.NewWriter(&compressed)
+	brotliWriter.Write([]byte(`{"name":"a","updated_at":1}`))
+	brotliWriter.Close()
+
+	tests := []struct {
+		name    string
+		primary *keptResponse
+		shadow  *keptResponse
+		want    []string
+		// bodySkipped is true if the bodies are not compared
+		bodySkipped bool
+	}{
+		{
+			name:    "same responses",
+			primary: &keptResponse{status: 200, header: http.Header{}, body: []byte("ok")},
+			shadow:  &keptResponse{status: 200, header: http.Header{}, body: []byte("ok")},
+			want:    []string{},
+		},
+		{
+			name: "status and compared header",
+			primary: &keptResponse{status: 200, header: http.Header{"Location": {"/a"}, "Date": {"1"}},
+				body: []byte("ok")},
+			shadow: &keptResponse{status: 201, header: http.Header{"Location": {"/b"}, "Date": {"2"}},
+				body: []byte("ok")},
+			want: []string{"status 200 != 201", `header Location "/a" != "/b"`},
+		},
+		{
+			name:    "text body",
+			primary: &keptResponse{status: 200, header: http.Header{}, body: []byte("ok")},
+			shadow:  &keptResponse{status: 200, header: http.Header{}, body: []byte("ok\n")},
+			want:    []string{"body"},
+		},
+		{
+			name:        "body not kept",
+			primary:     &keptResponse{status: 200, header: http.Header{}},
+			shadow:      &keptResponse{status: 200, header: http.Header{}, body: []byte("ok")},
+			want:        []string{},
+			bodySkipped: true,
+		},
+		{
+			name:    "json with other order and ignored paths",
+			primary: &keptResponse{status: 200, header: jsonHeader, body: []byte(`{"name":"a","updated_at":1,"items":[{"id":1,"v":2}]}`)},
+			shadow:  &keptResponse{status: 200, header: jsonHeader, body: []byte(`{"items":[{"v":2.0,"id":7}], "updated_at":2,"name":"a"}`)},
+			want:    []string{},
+		},
+		{
+			name:    "json paths",
+			primary: &keptResponse{status: 200, header: jsonHeader, body: []byte(`{"name":"a","tags":["x"],"extra":null}`)},
+			shadow:  &keptResponse{status: 200, header: jsonHeader, body: []byte(`{"name":"b","tags":["x","y"],"new":1}`)},
+			want:    []string{"body $.extra", "body $.name", "body $.new", "body $.tags"},
+		},
+		{
+			name:    "gzip json",
+			primary: &keptResponse{status: 200, header: jsonHeader, body: []byte(`{"name":"a","updated_at":5}`)},
+			shadow: &keptResponse{status: 200, header: http.Header{"Content-Type": {"application/json"},
+				"Content-Encoding": {"gzip"}}, body: gzipped.Bytes()},
+			want: []string{},
+		},
+		{
+			name:    "brotli json",
+			primary: &keptResponse{status: 200, header: jsonHeader, body: []byte(`{"name":"a","updated_at":5}`)},
+			shadow: &keptResponse{status: 200, header: http.Header{"Content-Type": {"application/json"},
+				"Content-Encoding": {"br"}}, body: compressed.Bytes()},
+			want: []string{},
+		},
+		{
+			name:    "unsupported encoding",
+			primary: &keptResponse{status: 200, header: http.Header{}, body: []byte("ok")},
+			shadow: &keptResponse{status: 200, header: http.Header{"Content-Encoding": {"zstd"}},
+				body: []byte("zstd")},
+			want:        []string{},
+			bodySkipped: true,
+		},
+		{
+			name:        "status of body not kept",
+			primary:     &keptResponse{status: 200, header: http.Header{}},
+			shadow:      &keptResponse{status: 500, header: http.Header{}, body: []byte("error")},
+			want:        []string{"status 200 != 500"},
+			bodySkipped: true,
+		},
+	}
+	site := &siteManager.Site{
+		Site:                 &sites.Site{},
+		MirrorCompareHeaders: []string{"Location"},
+		MirrorIgnorePaths:    [][]string{{"updated_at"}, {"items", "*", "id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, bodyCompared := compareResponses(tt.primary, tt.shadow, site)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareResponses() = %q, want %q", got, tt.want)
+			}
+			if bodyCompared == tt.bodySkipped {
+				t.Errorf("compareResponses() bodyCompared = %v, want %v", bodyCompared, !tt.bodySkipped)
+			}
+		})
+	}
+}
+
+func TestRemovePath(t *testing.T) {
+	value := map[string]interface{}{
+		"id": 1.0,
+		"items": []interface{}{
+			map[string]interface{}{"id": 1.0, "v": "a"},
+			map[string]interface{}{"id": 2.0, "v": "b"},
+		},
+		"meta": map[string]interface{}{"a": map[string]interface{}{"at": 1.0}, "b": map[string]interface{}{"at": 2.0}},
+	}
+	for _, path := range [][]string{{"items", "*", "id"}, {"items", "1", "v"}, {"meta", "*", "at"}, {"missing", "x"}} {
+		removePath(value, path)
+	}
+	want := map[string]interface{}{
+		"id": 1.0,
+		"items": []interface{}{
+			map[string]interface{}{"v": "a"},
+			map[string]interface{}{},
+		},
+		"meta": map[string]interface{}{"a": map[string]interface{}{}, "b": map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Errorf("removePath() = %v, want %v", value, want)
+	}
+}
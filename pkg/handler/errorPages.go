@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+	"strings"
+)
+
+const (
+	mediaTypeJSON = "application/json"
+	mediaTypeHTML = "text/html"
+)
+
+// defaultErrorPage is the HTML page of the
+// statuses the site has no HTML page of
+var defaultErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p>{{.Message}}</p>
+<p>Request ID: {{.RequestID}}</p>
+</body>
+</html>
+`))
+
+// errorVars are the variables of the error pages
+type errorVars struct {
+	Status     int    `json:"status"`
+	StatusText string `json:"-"`
+	Message    string `json:"message"`
+	RequestID  string `json:"request_id"`
+	Host       string `json:"-"`
+	Path       string `json:"-"`
+}
+
+// sendError sends the error page of the status, the page of
+// the site or the default JSON or HTML page is chosen by the
+// Accept header of the request, the pages of the site win the tie
+func (h RevHandler) sendError(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	status int, message string) {
+	vars := errorVars{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		RequestID:  r.Header.Get(requestIDHeader),
+		Host:       r.Host,
+		Path:       r.URL.Path,
+	}
+	pages := site.GetErrorPages(status)
+	offers := make([]string, 0, len(pages)+2)
+	for _, page := range pages {
+		offers = append(offers, page.MediaType)
+	}
+	offers = append(offers, mediaTypeJSON, mediaTypeHTML)
+	chosen := offers[negotiateType(r.Header.Get("Accept"), offers)]
+
+	body := bytes.Buffer{}
+	contentType := ""
+	for _, page := range pages {
+		if page.MediaType != chosen {
+			continue
+		}
+		if err := page.Execute(&body, vars); err != nil {
+			h.getLogs().GetError().Str("when", "send error").Int64("page", page.Id).
+				Err(err).Msg("unable to execute error page, the default one is sent")
+			body.Reset()
+			if chosen != mediaTypeHTML {
+				chosen = mediaTypeJSON
+			}
+			break
+		}
+		contentType = page.ContentType
+		break
+	}
+	if contentType == "" {
+		var err error
+		if chosen == mediaTypeHTML {
+			contentType = "text/html; charset=utf-8"
+			err = defaultErrorPage.Execute(&body, vars)
+		} else {
+			contentType = "application/json; charset=utf-8"
+			err = json.NewEncoder(&body).Encode(vars)
+		}
+		if err != nil {
+			h.getLogs().GetError().Str("when", "send error").
+				Err(err).Msg("unable to execute default error page")
+		}
+	}
+
+	w.Header().Del("Content-Encoding")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(body.Bytes()); err != nil {
+		h.getLogs().GetError().Str("when", "send error").Str("when", "send response").
+			Err(err).Msg("unable to send response")
+	}
+}
+
+// sendBackendError sends 504 if the client
+// did not respond in time, 502 otherwise
+func (h RevHandler) sendBackendError(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	err error) {
+	if isTimeout(err) {
+		h.sendError(w, r, site, http.StatusGatewayTimeout, "service timed out")
+		return
+	}
+	h.sendError(w, r, site, http.StatusBadGateway, "service failed")
+}
+
+// negotiateType returns the index of the offered media type with
+// the highest quality in the Accept header, the most specific media
+// range gives the quality, the earlier offer wins the tie, the first
+// offer is returned if none of them is accepted
+func negotiateType(accept string, offers []string) int {
+	qualities := make(map[string]float64)
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				q = 0
+			}
+			quality = q
+		}
+		qualities[mediaType] = quality
+	}
+	if len(qualities) == 0 {
+		return 0
+	}
+
+	best, bestQuality := 0, 0.0
+	for i, offer := range offers {
+		quality, ok := qualities[offer]
+		if !ok {
+			quality, ok = qualities[offer[:strings.Index(offer, "/")+1]+"*"]
+		}
+		if !ok {
+			quality = qualities["*/*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = i, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/repositories/errorPages"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestNegotiateType(t *testing.T) {
+	offers := []string{"text/plain", mediaTypeJSON, mediaTypeHTML}
+	tests := []struct {
+		accept string
+		want   int
+	}{
+		{accept: "", want: 0},
+		{accept: "*/*", want: 0},
+		{accept: "application/json", want: 1},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: 2},
+		{accept: "text/*, text/plain;q=0.5", want: 2},
+		{accept: "application/json;q=0.5, text/html;q=0.6", want: 2},
+		{accept: "application/json, text/html", want: 1},
+		{accept: "image/png", want: 0},
+		{accept: "*/*;q=0, application/json;q=0.1", want: 1},
+		{accept: "invalid", want: 0},
+	}
+	for _, tt := range tests {
+		if got := negotiateType(tt.accept, offers); got != tt.want {
+			t.Errorf("negotiateType(%q) = %d, want %d", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestRevHandler_sendError(t *testing.T) {
+	site := &siteManager.Site{ErrorPages: make(map[int][]*siteManager.ErrorPage)}
+	for _, page := range []*errorPages.ErrorPage{
+		{Id: 1, Status: http.StatusBadGateway, ContentType: "text/html; charset=utf-8",
+			Body: "<p>{{.Status}} {{.Message}} {{.RequestID}} {{.Host}}{{.Path}}</p>"},
+		{Id: 2, Status: http.StatusBadGateway, ContentType: "application/problem+json",
+			Body: `{"status": {{json .Status}}, "title": {{json .StatusText}}}`},
+		{Id: 3, Status: http.StatusForbidden, ContentType: "text/html", Body: "{{.Missing}}"},
+	} {
+		parsed, err := siteManager.NewErrorPage(page)
+		if err != nil {
+			t.Fatalf("NewErrorPage() error = %v", err)
+		}
+		site.ErrorPages[int(page.Status)] = append(site.ErrorPages[int(page.Status)], parsed)
+	}
+
+	tests := []struct {
+		name            string
+		site            *siteManager.Site
+		status          int
+		accept          string
+		method          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "page of site",
+			site:            site,
+			status:          http.StatusBadGateway,
+			accept:          "text/html",
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<p>502 failed &lt;id&gt; vk.com/path</p>",
+		},
+		{
+			name:            "first page of site for any type",
+			site:            site,
+			status:          http.StatusBadGateway,
+			accept:          "*/*",
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<p>502 failed &lt;id&gt; vk.com/path</p>",
+		},
+		{
+			name:            "other page of site",
+			site:            site,
+			status:          http.StatusBadGateway,
+			accept:          "application/problem+json",
+			wantContentType: "application/problem+json",
+			wantBody:        `{"status": 502, "title": "Bad Gateway"}`,
+		},
+		{
+			name:            "default JSON of site without type",
+			site:            site,
+			status:          http.StatusBadGateway,
+			accept:          "application/json",
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        "{\"status\":502,\"message\":\"failed\",\"request_id\":\"\\u003cid\\u003e\"}\n",
+		},
+		{
+			name:            "default JSON without site",
+			status:          http.StatusTooManyRequests,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        "{\"status\":429,\"message\":\"failed\",\"request_id\":\"\\u003cid\\u003e\"}\n",
+		},
+		{
+			name:            "default page of failed template",
+			site:            site,
+			status:          http.StatusForbidden,
+			accept:          "text/html",
+			wantContentType: "text/html; charset=utf-8",
+			wantBody: "<!DOCTYPE html>\n<html>\n<head><title>403 Forbidden</title></head>\n<body>\n" +
+				"<h1>403 Forbidden</h1>\n<p>failed</p>\n<p>Request ID: &lt;id&gt;</p>\n</body>\n</html>\n",
+		},
+		{
+			name:            "no body of HEAD request",
+			site:            site,
+			status:          http.StatusBadGateway,
+			accept:          "application/problem+json",
+			method:          http.MethodHead,
+			wantContentType: "application/problem+json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "http://vk.com/path", nil)
+			r.Header.Set("Accept", tt.accept)
+			r.Header.Set(requestIDHeader, "<id>")
+			w := httptest.NewRecorder()
+			RevHandler{}.sendError(w, r, tt.site, tt.status, "failed")
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
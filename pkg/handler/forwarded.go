@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+)
+
+var forwardingHeaders = []string{
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Forwarded-Prefix",
+	"X-Forwarded-Proto",
+}
+
+// remoteIP returns the address of the
+// directly connected user
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// clientIP returns the address of the user, the addresses
+// of X-Forwarded-For are taken only while they are added
+// by the trusted proxies of the site
+func clientIP(r *http.Request, site *siteManager.Site) net.IP {
+	ip := remoteIP(r)
+	if !site.IsTrusted(ip) {
+		return ip
+	}
+	forwardedFor := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		prior := net.ParseIP(strings.TrimSpace(forwardedFor[i]))
+		if prior == nil {
+			return ip
+		}
+		ip = prior
+		if !site.IsTrusted(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// requestScheme returns the scheme of the request
+// received by the reverseProxy
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// clientScheme returns the scheme of the request of the
+// user, X-Forwarded-Proto is taken only from the trusted
+// proxies of the site
+func clientScheme(r *http.Request, site *siteManager.Site) string {
+	if site.IsTrusted(remoteIP(r)) {
+		proto := strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0]
+		if proto = strings.ToLower(strings.TrimSpace(proto)); proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+	return requestScheme(r)
+}
+
+// setForwardedHeaders sets the forwarding headers of the
+// request to the client. The incoming forwarding headers
+// are kept only when the user is a trusted proxy of the
+// site, otherwise they are overwritten
+func setForwardedHeaders(req *http.Request, r *http.Request, site *siteManager.Site) {
+	ip := remoteIP(r)
+	if !site.IsTrusted(ip) {
+		for _, header := range forwardingHeaders {
+			req.Header.Del(header)
+		}
+	}
+
+	forwardedFor := r.RemoteAddr
+	if ip != nil {
+		forwardedFor = ip.String()
+	}
+	if prior, ok := req.Header["X-Forwarded-For"]; ok {
+		forwardedFor = strings.Join(prior, ", ") + ", " + forwardedFor
+	}
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		req.Header.Set("X-Forwarded-Proto", requestScheme(r))
+	}
+	if req.Header.Get("X-Forwarded-Host") == "" {
+		req.Header.Set("X-Forwarded-Host", r.Host)
+	}
+
+	if site == nil || !site.Forwarded {
+		return
+	}
+	element := "for=" + forwardedNode(ip) +
+		";host=" + forwardedValue(r.Host) +
+		";proto=" + requestScheme(r)
+	if prior, ok := req.Header["Forwarded"]; ok {
+		element = strings.Join(prior, ", ") + ", " + element
+	}
+	req.Header.Set("Forwarded", element)
+}
+
+// forwardedNode formats the address as the node
+// of the Forwarded header (RFC 7239, section 6)
+func forwardedNode(ip net.IP) string {
+	if ip == nil {
+		return "unknown"
+	}
+	if ip.To4() == nil {
+		return "\"[" + ip.String() + "]\""
+	}
+	return ip.String()
+}
+
+// forwardedValue quotes the value of the Forwarded
+// header parameter, if it is not a token
+func forwardedValue(value string) string {
+	for _, c := range value {
+		if !isTokenChar(c) {
+			return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+		}
+	}
+	return value
+}
+
+// isTokenChar determines whether the character
+// is allowed in the token (RFC 7230, section 3.2.6)
+func isTokenChar(c rune) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
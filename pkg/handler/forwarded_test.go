@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestSetForwardedHeaders(t *testing.T) {
+	trustedProxies, err := siteManager.ParseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDRs() error = %v", err)
+	}
+	site := &siteManager.Site{
+		Site:           &sites.Site{Host: "vk.com"},
+		TrustedProxies: trustedProxies,
+	}
+	forwardedSite := &siteManager.Site{
+		Site:           &sites.Site{Host: "vk.com", Forwarded: true},
+		TrustedProxies: trustedProxies,
+	}
+
+	tests := []struct {
+		name       string
+		site       *siteManager.Site
+		remoteAddr string
+		tls        bool
+		header     http.Header
+		want       http.Header
+	}{
+		{
+			name:       "request without forwarding headers",
+			site:       site,
+			remoteAddr: "1.2.3.4:5555",
+			header:     http.Header{},
+			want: http.Header{
+				"X-Forwarded-For":   {"1.2.3.4"},
+				"X-Forwarded-Proto": {"http"},
+				"X-Forwarded-Host":  {"vk.com"},
+			},
+		},
+		{
+			name:       "untrusted user headers are overwritten",
+			site:       site,
+			remoteAddr: "1.2.3.4:5555",
+			tls:        true,
+			header: http.Header{
+				"X-Forwarded-For":   {"6.6.6.6"},
+				"X-Forwarded-Proto": {"http"},
+				"X-Forwarded-Host":  {"evil.com"},
+				"Forwarded":         {"for=6.6.6.6"},
+			},
+			want: http.Header{
+				"X-Forwarded-For":   {"1.2.3.4"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"vk.com"},
+			},
+		},
+		{
+			name:       "trusted proxy headers are kept",
+			site:       site,
+			remoteAddr: "10.0.0.1:5555",
+			header: http.Header{
+				"X-Forwarded-For":   {"1.2.3.4, 5.6.7.8"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"www.vk.com"},
+			},
+			want: http.Header{
+				"X-Forwarded-For":   {"1.2.3.4, 5.6.7.8, 10.0.0.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"www.vk.com"},
+			},
+		},
+		{
+			name:       "headers of unknown site are overwritten",
+			site:       nil,
+			remoteAddr: "10.0.0.1:5555",
+			header: http.Header{
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			want: http.Header{
+				"X-Forwarded-For":   {"10.0.0.1"},
+				"X-Forwarded-Proto": {"http"},
+				"X-Forwarded-Host":  {"vk.com"},
+			},
+		},
+		{
+			name:       "forwarded header of ipv6 user",
+			site:       forwardedSite,
+			remoteAddr: "[2001:db8::1]:5555",
+			header:     http.Header{},
+			want: http.Header{
+				"X-Forwarded-For":   {"2001:db8::1"},
+				"X-Forwarded-Proto": {"http"},
+				"X-Forwarded-Host":  {"vk.com"},
+				"Forwarded":         {"for=\"[2001:db8::1]\";host=vk.com;proto=http"},
+			},
+		},
+		{
+			name:       "forwarded header is appended for trusted proxy",
+			site:       forwardedSite,
+			remoteAddr: "10.0.0.1:5555",
+			header: http.Header{
+				"Forwarded": {"for=1.2.3.4;proto=https"},
+			},
+			want: http.Header{
+				"X-Forwarded-For":   {"10.0.0.1"},
+				"X-Forwarded-Proto": {"http"},
+				"X-Forwarded-Host":  {"vk.com"},
+				"Forwarded":         {"for=1.2.3.4;proto=https, for=10.0.0.1;host=vk.com;proto=http"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://vk.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header = tt.header
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			req := r.Clone(r.Context())
+			setForwardedHeaders(req, r, tt.site)
+			if len(req.Header) != len(tt.want) {
+				t.Errorf("setForwardedHeaders() got %v, want %v", req.Header, tt.want)
+			}
+			for header, values := range tt.want {
+				if got := req.Header.Get(header); got != values[0] {
+					t.Errorf("setForwardedHeaders() %s = %q, want %q", header, got, values[0])
+				}
+			}
+		})
+	}
+}
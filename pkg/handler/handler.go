@@ -1,18 +1,16 @@
 package handler
 
 import (
-	"context"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/url"
 	"reverseProxy/pkg/authorizeManager"
 	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/cacheManager"
 	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+	"time"
 )
 
-const message = "If you see this page, an error has occurred"
-
 type RevHandler struct {
 	log *logging.Logger
 }
@@ -24,24 +22,27 @@ func (h RevHandler) getLogs() *logging.Logger {
 	return h.log
 }
 
-func (h RevHandler) sendAuthorizationQuery(w http.ResponseWriter) error {
+func (h RevHandler) sendAuthorizationQuery(w http.ResponseWriter, r *http.Request, site *siteManager.Site) {
 	w.Header().Set("WWW-Authenticate", "Basic realm=myProxy")
-	w.Header().Set("Content-Type", "text/json; charset=utf-8")
-	w.WriteHeader(http.StatusUnauthorized)
+	h.sendError(w, r, site, http.StatusUnauthorized, "unauthorized")
+}
 
-	if _, err := fmt.Fprint(w, "{\"status\": \"unauthorized\"}"); err != nil {
-		h.log.GetError().Str("when", "send authorization query").
-			Str("when", "send response").Err(err).Msg("unable to send response")
-		return err
+// siteHost returns the host of the site of the request,
+// the aliases and the patterns of the hosts of the site
+// share its settings, backends, users and responses
+func siteHost(r *http.Request, site *siteManager.Site) string {
+	if site == nil {
+		return siteManager.NormalizeHost(r.Host)
 	}
-	return nil
+	return site.Host
 }
 
-func (h RevHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.getLogs().GetInfo().Str("when", "start processing request").
-		Str("url", r.RequestURI).Msg("start RevHandler")
-
-	host := r.Host
+// authorize checks the user's data if authorization
+// is required on the requested host, and sends the
+// authorization query when the check fails, it returns
+// the login of the authorized user
+func (h RevHandler) authorize(w http.ResponseWriter, r *http.Request, site *siteManager.Site) (string, bool) {
+	host := siteHost(r, site)
 	h.getLogs().GetInfo().Msg("verifying authorization requirements")
 	needAuth, err := authorizeManager.AuthorizeMnr.NeedAuth(host)
 	if err != nil {
@@ -55,12 +56,8 @@ func (h RevHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		login, password, ok := r.BasicAuth()
 		if !ok {
 			h.getLogs().GetInfo().Msg("basic authorization")
-			if err := h.sendAuthorizationQuery(w); err != nil {
-				h.getLogs().GetError().Str("when", "send basic authorization query").
-					Err(err).Msg("unable to authorization query")
-				err.Error()
-			}
-			return
+			h.sendAuthorizationQuery(w, r, site)
+			return "", false
 		}
 
 		h.getLogs().GetInfo().Msg("checking the user's data in the database")
@@ -73,103 +70,212 @@ func (h RevHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if !authorized {
 			h.getLogs().GetWarn().Str("when", "entering user data").Msg("invalid user data")
 			h.getLogs().GetInfo().Msg("re-attempt to enter user data")
-			if err := h.sendAuthorizationQuery(w); err != nil {
-				h.getLogs().GetError().Str("when", "re-attempt to enter user data").
-					Err(err).Msg("unable to authorization query")
-				err.Error()
-			}
-			return
+			h.sendAuthorizationQuery(w, r, site)
+			return "", false
 		}
+		return login, true
+	}
+	return "", true
+}
+
+func (h RevHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.getLogs().GetInfo().Str("when", "start processing request").
+		Str("url", r.RequestURI).Msg("start RevHandler")
+
+	requestID := setRequestID(r)
+	h.getLogs().GetInfo().Str("request_id", requestID).Msg("request ID assigned")
+
+	site, err := siteManager.SiteMgr.GetSite(r.Host)
+	if err != nil && err != siteManager.ErrSiteNotFound {
+		h.getLogs().GetError().Str("when", "get site").
+			Err(err).Msg("failed to get site")
+	}
+	// the hosts matching no site get the default site
+	unmatched := site == nil
+	if unmatched {
+		site = siteManager.SiteMgr.GetDefaultSite()
+	}
+
+	if !h.checkIP(w, r, site) {
+		return
+	}
+	if h.redirect(w, r, site) {
+		return
+	}
+	if unmatched && h.serveCatchAll(w, r, site) {
+		return
+	}
+	if !h.limitRate(w, r, site, "", false) {
+		return
+	}
+	login, authorized := h.authorize(w, r, site)
+	if !authorized {
+		return
+	}
+	if !h.checkMaintenance(w, r, site, login) {
+		return
+	}
+	if !h.limitRate(w, r, site, login, true) {
+		return
 	}
 
-	h.getLogs().GetInfo().Msg("get client for specified host")
-	client, err := backendManager.BackendMgr.GetClient(host)
+	host := siteHost(r, site)
+	route := backendManager.BackendMgr.GetRoute(host, r.URL.Path)
+	if unmatched {
+		route = catchAllRoute(site, route)
+	}
+	lookup, served := h.serveFromCache(w, r, site, route)
+	if served {
+		return
+	}
+	h.getLogs().GetInfo().Str("pool", route.GetPool()).Msg("get client for specified host")
+	client, err := getClient(host, route.GetPool())
 	if err != nil {
+		if h.serveStale(w, r, site, lookup) {
+			return
+		}
 		switch err {
 		case backendManager.ErrNoHost:
-			w.Header().Set("Content-Type", "text/json; charset=utf-8")
-			w.WriteHeader(http.StatusBadGateway)
-			if _, err := fmt.Fprint(w, "{\"message\": \"service not found\"}"); err != nil {
-				h.getLogs().GetError().Str("when", "get client").
-					Str("when", "no hosts").Str("when", "send response").
-					Err(err).Msg("unable to send response")
-				err.Error()
-			}
-			return
+			h.sendError(w, r, site, http.StatusBadGateway, "service not found")
 		case backendManager.ErrClientNotFound:
-			w.Header().Set("Content-Type", "text/json; charset=utf-8")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			if _, err := fmt.Fprint(w, "{\"message\": \"service unavailable\"}"); err != nil {
-				h.getLogs().GetError().Str("when", "get client").
-					Str("when", "no clients").Str("when", "send response").
-					Err(err).Msg("unable to send response")
-				err.Error()
-			}
-			return
+			h.sendError(w, r, site, http.StatusServiceUnavailable, "service unavailable")
 		default:
-			w.Header().Set("Content-Type", "text/plain; text/json; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := fmt.Fprint(w, message); err != nil {
-				h.getLogs().GetError().Str("when", "get client").
-					Str("when", "send response").Err(err).Msg("unable to send response")
-				err.Error()
-			}
-			return
+			h.getLogs().GetError().Str("when", "get client").Err(err).Msg("unable to get client")
+			h.sendError(w, r, site, http.StatusInternalServerError, "internal error")
 		}
+		return
 	}
 
-	h.getLogs().GetInfo().Msg("completed request, start response")
-	ctx := context.TODO()
-	req := r.Clone(ctx)
-	req.URL, err = url.Parse("http://" + client.Address + req.RequestURI)
+	if isUpgradeRequest(r) {
+		h.serveUpgrade(w, r, client, site, route)
+		return
+	}
+
+	mirror := h.startMirror(r, site)
+	h.proxy(w, r, client, site, route, lookup, mirror)
+	if mirror != nil {
+		h.sendMirror(r, site, route, mirror)
+	}
+}
+
+// newBackendRequest creates the request to the client
+// from the request of the user
+func newBackendRequest(r *http.Request, client *backendManager.Client, site *siteManager.Site,
+	route *backendManager.Route) *http.Request {
+	req := r.Clone(r.Context())
+	req.URL.Scheme = client.GetScheme()
+	req.URL.Host = client.Address
 	req.RequestURI = ""
-	if err != nil {
-		h.log.GetError().Str("when", "parse raw url into url structure").
-			Err(err).Msg("unable to parse raw url")
-		err.Error()
+	req.Close = false
+	removeHopHeaders(req.Header)
+	if acceptsTrailers(r.Header) {
+		req.Header.Set("Te", "trailers")
 	}
 	req.Header.Del("Authorization")
+	setForwardedHeaders(req, r, site)
+	rewritePath(req, route, site)
+	applyHeaderRules(req.Header, site.GetRequestHeaders(), headerVars(r, site, client))
+	return req
+}
 
-	h.getLogs().GetInfo().Msg("start send HTTP request")
-	resp, err := client.Cl.Do(req)
-	if err != nil {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		bytesMessage := []byte(message)
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(bytesMessage)))
-		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write(bytesMessage); err != nil {
+// proxy sends the request to the client, retries it on
+// another client if it fails, and streams the response
+// to the user, the response is cached if lookup is not nil
+// and kept for the comparison if mirror is not nil
+func (h RevHandler) proxy(w http.ResponseWriter, r *http.Request, client *backendManager.Client,
+	site *siteManager.Site, route *backendManager.Route, lookup *cacheLookup, mirror *mirrorRequest) {
+	h.getLogs().GetInfo().Msg("completed request, start response")
+	body := newRetryBody(r)
+	tried := []*backendManager.Client{client}
+	var resp *http.Response
+	var requestTime, responseTime time.Time
+	for attempt := 1; ; attempt++ {
+		req := newBackendRequest(r, client, site, route)
+		if body != nil {
+			req.Body = body
+		}
+		if lookup != nil && lookup.revalidate {
+			lookup.stored.SetValidators(req)
+		}
+
+		h.getLogs().GetInfo().Int("attempt", attempt).Str("address", client.Address).
+			Msg("start send HTTP request")
+		var err error
+		requestTime = time.Now()
+		resp, err = client.Cl.Do(req)
+		responseTime = time.Now()
+		if err == nil {
+			break
+		}
+		h.getLogs().GetWarn().Str("when", "completed request, start response").
+			Str("url", req.URL.String()).Int("attempt", attempt).Err(err).Msg("unable to get response")
+
+		next, ok := h.nextClient(r, err, body, site, route, attempt, tried)
+		if !ok {
 			h.getLogs().GetError().Str("when", "completed request, start response").
-				Str("when", "send response").Err(err).Msg("unable to send response")
+				Strs("tried", clientAddresses(tried)).Err(err).Msg("all attempts failed")
+			if h.serveStale(w, r, site, lookup) {
+				return
+			}
+			h.sendBackendError(w, r, site, err)
 			return
 		}
-		h.getLogs().GetError().Str("when", "completed request, start response").
-			Str("url", req.RequestURI).Err(err).Msg("unable to get response")
-		return
+		client = next
+		tried = append(tried, client)
 	}
-
-	h.getLogs().GetInfo().Msg("read response body")
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		h.getLogs().GetError().Str("when", "read response body").
-			Err(err).Msg("unable to read body")
-		err.Error()
+	if len(tried) > 1 {
+		h.getLogs().GetInfo().Strs("tried", clientAddresses(tried)).Msg("request retried")
 	}
+
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			h.getLogs().GetError().Str("when", "close body").
 				Err(err).Msg("unable to close body")
-			err.Error()
 		}
 	}()
 
 	h.getLogs().GetInfo().Msg("set headers")
-	for header, headerVal := range resp.Header {
-		for _, headerValue := range headerVal {
-			w.Header().Set(header, headerValue)
-		}
+	removeHopHeaders(resp.Header)
+	tags := surrogateKeys(resp.Header)
+	if lookup != nil && lookup.revalidate && resp.StatusCode == http.StatusNotModified {
+		h.getLogs().GetInfo().Msg("cached response revalidated")
+		entry := updateEntry(lookup.stored, resp.Header, tags, requestTime, responseTime)
+		cacheManager.CacheMgr.Store(entry, lookup.generation)
+		h.writeEntry(w, r, site, entry, time.Now(), cacheHit, "")
+		return
+	}
+	if isBackendError(resp.StatusCode) && h.serveStale(w, r, site, lookup) {
+		return
 	}
+	invalidateCache(r, site, resp.StatusCode)
+	mirror.keepResponse(resp, site)
 
-	resp.Header.Del("Authorization")
+	var store *cacheBody
+	if lookup != nil {
+		if site.IsCacheEnabled() {
+			w.Header().Set(cacheHeader, cacheMiss)
+		}
+		// the failed response does not replace
+		// the last stored good one
+		if !isBackendError(resp.StatusCode) && cacheManager.Storable(r, resp.StatusCode, resp.Header) {
+			store = newCacheBody(resp.Body, cacheManager.CacheMgr.MaxEntrySize())
+			resp.Body = store
+		}
+	}
+	copyHeader(w.Header(), resp.Header)
+	encoding := prepareCompression(w.Header(), r, site, resp.StatusCode, resp.ContentLength)
+	applyHeaderRules(w.Header(), site.GetResponseHeaders(), headerVars(r, site, client))
+	// the trailers must be announced before the header is
+	// written, otherwise a short body is sent with
+	// Content-Length and the trailers are lost
+	announcedTrailers := len(resp.Trailer)
+	if announcedTrailers > 0 {
+		trailerKeys := make([]string, 0, announcedTrailers)
+		for header := range resp.Trailer {
+			trailerKeys = append(trailerKeys, header)
+		}
+		w.Header().Add("Trailer", strings.Join(trailerKeys, ", "))
+	}
 
 	w.WriteHeader(resp.StatusCode)
 	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
@@ -178,11 +284,47 @@ func (h RevHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.getLogs().GetWarn().Str("when", "status code").Msg("5xx")
 	}
 
-	h.getLogs().GetInfo().Msg("write response body")
-	if _, err := w.Write(body); err != nil {
-		h.getLogs().GetError().Str("when", "write response body").
-			Err(err).Msg("unable to write body")
-		err.Error()
+	h.getLogs().GetInfo().Msg("stream response body")
+	var dst http.ResponseWriter = w
+	var compressed *compressWriter
+	if encoding != "" && r.Method != http.MethodHead {
+		h.getLogs().GetInfo().Str("encoding", encoding).Msg("compress response body")
+		compressed = newCompressWriter(w, encoding)
+		defer compressed.release()
+		dst = compressed
+	}
+	if err := h.copyResponse(dst, resp); err != nil {
+		h.getLogs().GetError().Str("when", "stream response body").
+			Err(err).Msg("unable to stream body")
+		if r.Context().Err() == nil {
+			// abort the client connection, so that the
+			// truncated body is not taken as complete
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+	if compressed != nil {
+		if err := compressed.Close(); err != nil {
+			h.getLogs().GetError().Str("when", "compress response body").
+				Err(err).Msg("unable to compress body")
+			return
+		}
+	}
+	if store != nil && !store.overflow {
+		entry := cacheManager.NewEntry(r, siteHost(r, site), resp.StatusCode, resp.Header, store.buf.Bytes(),
+			requestTime, responseTime)
+		entry.Tags = tags
+		cacheManager.CacheMgr.Store(entry, lookup.generation)
+	}
+
+	if len(resp.Trailer) == announcedTrailers {
+		copyHeader(w.Header(), resp.Trailer)
+	} else {
+		for header, headerVal := range resp.Trailer {
+			for _, headerValue := range headerVal {
+				w.Header().Add(http.TrailerPrefix+header, headerValue)
+			}
+		}
 	}
 	h.getLogs().GetInfo().Msg("response complete")
 }
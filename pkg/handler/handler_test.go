@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/headerRules"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+// newTestProxy starts the reverseProxy, which sends
+// all requests to the given backend
+func newTestProxy(backend *httptest.Server) *httptest.Server {
+	return newTestSiteProxy(backend, nil)
+}
+
+// newTestSiteProxy starts the reverseProxy of the site,
+// which sends all requests to the given backend
+func newTestSiteProxy(backend *httptest.Server, site *siteManager.Site) *httptest.Server {
+	client := &backendManager.Client{
+		Alive:   true,
+		Address: backend.Listener.Addr().String(),
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isUpgradeRequest(r) {
+			RevHandler{}.serveUpgrade(w, r, client, site, nil)
+			return
+		}
+		setRequestID(r)
+		lookup, served := RevHandler{}.serveFromCache(w, r, site, nil)
+		if served {
+			return
+		}
+		RevHandler{}.proxy(w, r, client, site, nil, lookup, nil)
+	}))
+}
+
+func TestRevHandler_proxyHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		// requestHeader is sent by the user
+		requestHeader http.Header
+		// responseHeader is sent by the backend
+		responseHeader http.Header
+		// trailer is sent by the backend after the body
+		trailer http.Header
+		// wantRequest must be received by the backend
+		wantRequest http.Header
+		// wantNoRequest must not be received by the backend
+		wantNoRequest []string
+		// wantResponse must be received by the user
+		wantResponse http.Header
+		// wantNoResponse must not be received by the user
+		wantNoResponse []string
+		wantTrailer    http.Header
+	}{
+		{
+			name: "multi-value response headers are preserved",
+			responseHeader: http.Header{
+				"Set-Cookie": {"a=1; Path=/", "b=2; Path=/"},
+				"Vary":       {"Accept", "Accept-Encoding"},
+			},
+			wantResponse: http.Header{
+				"Set-Cookie": {"a=1; Path=/", "b=2; Path=/"},
+				"Vary":       {"Accept", "Accept-Encoding"},
+			},
+		},
+		{
+			name: "multi-value request headers are preserved",
+			requestHeader: http.Header{
+				"Accept":  {"text/html", "application/json"},
+				"X-Token": {"1", "2"},
+			},
+			wantRequest: http.Header{
+				"Accept":  {"text/html", "application/json"},
+				"X-Token": {"1", "2"},
+			},
+		},
+		{
+			name: "request hop-by-hop headers are removed",
+			requestHeader: http.Header{
+				"Connection":          {"X-Hop, x-other-hop"},
+				"X-Hop":               {"1"},
+				"X-Other-Hop":         {"1"},
+				"Keep-Alive":          {"timeout=5"},
+				"Proxy-Authorization": {"Basic Zm9vOmJhcg=="},
+				"Proxy-Connection":    {"keep-alive"},
+				"Upgrade":             {"h2c"},
+				"X-End-To-End":        {"1"},
+			},
+			wantRequest: http.Header{
+				"X-End-To-End": {"1"},
+			},
+			wantNoRequest: []string{"Connection", "X-Hop", "X-Other-Hop", "Keep-Alive",
+				"Proxy-Authorization", "Proxy-Connection", "Upgrade"},
+		},
+		{
+			name: "response hop-by-hop headers are removed",
+			responseHeader: http.Header{
+				"Connection":         {"X-Hop"},
+				"X-Hop":              {"1"},
+				"Keep-Alive":         {"timeout=5"},
+				"Proxy-Authenticate": {"Basic"},
+				"Upgrade":            {"h2c"},
+				"X-End-To-End":       {"1"},
+			},
+			wantResponse: http.Header{
+				"X-End-To-End": {"1"},
+			},
+			wantNoResponse: []string{"X-Hop", "Keep-Alive", "Proxy-Authenticate", "Upgrade"},
+		},
+		{
+			name: "authorization of the proxy is not forwarded",
+			requestHeader: http.Header{
+				"Authorization": {"Basic Zm9vOmJhcg=="},
+			},
+			wantNoRequest: []string{"Authorization"},
+		},
+		{
+			name: "te trailers is forwarded",
+			requestHeader: http.Header{
+				"Te": {"trailers, deflate"},
+			},
+			wantRequest: http.Header{
+				"Te": {"trailers"},
+			},
+		},
+		{
+			name: "other te values are removed",
+			requestHeader: http.Header{
+				"Te": {"deflate"},
+			},
+			wantNoRequest: []string{"Te"},
+		},
+		{
+			name: "trailers are forwarded",
+			trailer: http.Header{
+				"X-Checksum": {"abc"},
+			},
+			wantTrailer: http.Header{
+				"X-Checksum": {"abc"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRequest http.Header
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequest = r.Header
+				for header := range tt.trailer {
+					w.Header().Add("Trailer", header)
+				}
+				copyHeader(w.Header(), tt.responseHeader)
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, "body")
+				copyHeader(w.Header(), tt.trailer)
+			}))
+			defer backend.Close()
+			proxy := newTestProxy(backend)
+			defer proxy.Close()
+
+			req, err := http.NewRequest(http.MethodGet, proxy.URL+"/path?query=1", nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			copyHeader(req.Header, tt.requestHeader)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to send request: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unable to read body: %v", err)
+			}
+			if string(body) != "body" {
+				t.Errorf("body = %q, want %q", body, "body")
+			}
+
+			for header, values := range tt.wantRequest {
+				if !reflect.DeepEqual(gotRequest[header], values) {
+					t.Errorf("backend got %s = %q, want %q", header, gotRequest[header], values)
+				}
+			}
+			for _, header := range tt.wantNoRequest {
+				if _, ok := gotRequest[header]; ok {
+					t.Errorf("backend got hop-by-hop header %s = %q", header, gotRequest[header])
+				}
+			}
+			for header, values := range tt.wantResponse {
+				if !reflect.DeepEqual(resp.Header[header], values) {
+					t.Errorf("user got %s = %q, want %q", header, resp.Header[header], values)
+				}
+			}
+			for _, header := range tt.wantNoResponse {
+				if _, ok := resp.Header[header]; ok {
+					t.Errorf("user got hop-by-hop header %s = %q", header, resp.Header[header])
+				}
+			}
+			for header, values := range tt.wantTrailer {
+				if !reflect.DeepEqual(resp.Trailer[header], values) {
+					t.Errorf("user got trailer %s = %q, want %q", header, resp.Trailer[header], values)
+				}
+			}
+		})
+	}
+}
+
+func TestRevHandler_headerRules(t *testing.T) {
+	site := &siteManager.Site{
+		Site: &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+		RequestHeaders: []*headerRules.HeaderRule{
+			{Action: headerRules.ActionSet, Name: "X-Env", Value: "prod"},
+			{Action: headerRules.ActionAdd, Name: "X-Trace", Value: "{request_id}@{client_ip}"},
+			{Action: headerRules.ActionRemove, Name: "X-Debug"},
+		},
+		ResponseHeaders: []*headerRules.HeaderRule{
+			{Action: headerRules.ActionRemove, Name: "Server"},
+			{Action: headerRules.ActionSet, Name: "X-Backend", Value: "{backend}"},
+			{Action: headerRules.ActionAdd, Name: "Set-Cookie", Value: "proxy=1"},
+		},
+	}
+	var gotRequest http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequest = r.Header
+		w.Header().Set("Server", "internal/1.0")
+		w.Header().Add("Set-Cookie", "backend=1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	proxy := newTestSiteProxy(backend, site)
+	defer proxy.Close()
+
+	req, err := http.NewRequest(http.MethodGet, proxy.URL, nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	req.Header.Set("X-Env", "dev")
+	req.Header.Set("X-Debug", "1")
+	req.Header.Set(requestIDHeader, "abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	wantRequest := http.Header{
+		"X-Env":         {"prod"},
+		"X-Trace":       {"abc@127.0.0.1"},
+		requestIDHeader: {"abc"},
+	}
+	for header, values := range wantRequest {
+		if !reflect.DeepEqual(gotRequest[header], values) {
+			t.Errorf("backend got %s = %q, want %q", header, gotRequest[header], values)
+		}
+	}
+	if _, ok := gotRequest["X-Debug"]; ok {
+		t.Errorf("backend got removed header X-Debug")
+	}
+	wantResponse := http.Header{
+		"X-Backend":  {backend.Listener.Addr().String()},
+		"Set-Cookie": {"backend=1", "proxy=1"},
+	}
+	for header, values := range wantResponse {
+		if !reflect.DeepEqual(resp.Header[header], values) {
+			t.Errorf("user got %s = %q, want %q", header, resp.Header[header], values)
+		}
+	}
+	if _, ok := resp.Header["Server"]; ok {
+		t.Errorf("user got removed header Server")
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/headerRules"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+)
+
+// hopHeaders are the hop-by-hop headers, they are
+// meaningful only for a single connection and must
+// not be forwarded (RFC 7230, section 6.1)
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders removes the hop-by-hop headers
+// and the headers listed in the Connection header
+func removeHopHeaders(header http.Header) {
+	for _, value := range header["Connection"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
+// copyHeader adds all values of the
+// src headers to the dst headers
+func copyHeader(dst, src http.Header) {
+	for header, headerVal := range src {
+		for _, headerValue := range headerVal {
+			dst.Add(header, headerValue)
+		}
+	}
+}
+
+// acceptsTrailers determines whether the user
+// accepts trailers in the chunked response
+func acceptsTrailers(header http.Header) bool {
+	for _, value := range header["Te"] {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "trailers") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headerVars returns the replacer of the placeholders in
+// the values of the header rules, the client is nil when
+// the response is sent from the cache. The address of the
+// user is taken through the trusted proxies of the site
+func headerVars(r *http.Request, site *siteManager.Site, client *backendManager.Client) *strings.Replacer {
+	userIP := r.RemoteAddr
+	if ip := clientIP(r, site); ip != nil {
+		userIP = ip.String()
+	}
+	backend := ""
+	if client != nil {
+		backend = client.Address
+	}
+	return strings.NewReplacer(
+		"{client_ip}", userIP,
+		"{request_id}", r.Header.Get(requestIDHeader),
+		"{backend}", backend,
+		"{host}", r.Host,
+	)
+}
+
+// applyHeaderRules adds, sets and removes the
+// headers by the rules of the site in their order
+func applyHeaderRules(header http.Header, rules []*headerRules.HeaderRule, vars *strings.Replacer) {
+	for _, rule := range rules {
+		switch rule.Action {
+		case headerRules.ActionAdd:
+			header.Add(rule.Name, vars.Replace(rule.Value))
+		case headerRules.ActionSet:
+			header.Set(rule.Name, vars.Replace(rule.Value))
+		case headerRules.ActionRemove:
+			header.Del(rule.Name)
+		}
+	}
+}
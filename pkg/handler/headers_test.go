@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestHeaderVars(t *testing.T) {
+	trustedProxies, err := siteManager.ParseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDRs() error = %v", err)
+	}
+	site := &siteManager.Site{
+		Site:           &sites.Site{Host: "vk.com"},
+		TrustedProxies: trustedProxies,
+	}
+	client := &backendManager.Client{Address: "192.168.0.5:80"}
+
+	tests := []struct {
+		name         string
+		site         *siteManager.Site
+		remoteAddr   string
+		forwardedFor string
+		wantClientIP string
+	}{
+		{name: "user behind the trusted proxy", site: site, remoteAddr: "10.0.0.1:1234",
+			forwardedFor: "203.0.113.7", wantClientIP: "203.0.113.7"},
+		{name: "forwarded for from untrusted peer is ignored", site: site, remoteAddr: "198.51.100.1:1234",
+			forwardedFor: "203.0.113.7", wantClientIP: "198.51.100.1"},
+		{name: "no site", remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.7", wantClientIP: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			vars := headerVars(r, tt.site, client)
+			if got := vars.Replace("{client_ip}"); got != tt.wantClientIP {
+				t.Errorf("{client_ip} = %q, want %q", got, tt.wantClientIP)
+			}
+			if got := vars.Replace("{backend}"); got != client.Address {
+				t.Errorf("{backend} = %q, want %q", got, client.Address)
+			}
+		})
+	}
+}
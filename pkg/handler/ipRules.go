@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"reverseProxy/pkg/siteManager"
+)
+
+// checkIP sends 403 if the ip rules of the site
+// deny the requests from the client IP
+func (h RevHandler) checkIP(w http.ResponseWriter, r *http.Request, site *siteManager.Site) bool {
+	ip := clientIP(r, site)
+	rule, allowed := site.CheckIP(ip)
+	if allowed {
+		return true
+	}
+
+	event := h.getLogs().GetWarn().Str("when", "check ip").Str("host", r.Host).Str("ip", ip.String())
+	if rule != nil {
+		event = event.Int64("rule", rule.Id).Str("action", rule.Action).Str("cidr", rule.CIDR)
+	}
+	event.Msg("request denied by ip rules")
+
+	h.sendError(w, r, site, http.StatusForbidden, "forbidden")
+	return false
+}
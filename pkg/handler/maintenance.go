@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+// checkMaintenance sends 503 without contacting the backends
+// if the site is in maintenance, the listed addresses and the
+// logins of the authorized users reach the backends
+func (h RevHandler) checkMaintenance(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	login string) bool {
+	if !site.InMaintenance() {
+		return true
+	}
+	ip := clientIP(r, site)
+	if site.BypassesMaintenance(ip, login) {
+		h.getLogs().GetInfo().Str("when", "check maintenance").Str("ip", ip.String()).
+			Str("login", login).Msg("maintenance bypassed")
+		return true
+	}
+
+	if retryAfter := site.GetMaintenanceRetryAfter(); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	}
+	h.sendError(w, r, site, http.StatusServiceUnavailable, "maintenance")
+	return false
+}
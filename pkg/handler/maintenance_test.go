@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestRevHandler_checkMaintenance(t *testing.T) {
+	bypassIPs, err := siteManager.ParseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDRs() error = %v", err)
+	}
+	site := &siteManager.Site{
+		Site:                    &sites.Site{Maintenance: sites.MaintenanceSettings{Enabled: true, RetryAfter: 600}},
+		MaintenanceBypassIPs:    bypassIPs,
+		MaintenanceBypassLogins: []string{"admin"},
+	}
+
+	tests := []struct {
+		name           string
+		site           *siteManager.Site
+		remoteAddr     string
+		login          string
+		want           bool
+		wantRetryAfter string
+	}{
+		{
+			name:       "site not in maintenance",
+			site:       &siteManager.Site{Site: &sites.Site{}},
+			remoteAddr: "1.2.3.4:5555",
+			want:       true,
+		},
+		{
+			name:       "no site",
+			remoteAddr: "1.2.3.4:5555",
+			want:       true,
+		},
+		{
+			name:           "client in maintenance",
+			site:           site,
+			remoteAddr:     "1.2.3.4:5555",
+			login:          "user",
+			want:           false,
+			wantRetryAfter: "600",
+		},
+		{
+			name:       "bypass address",
+			site:       site,
+			remoteAddr: "10.1.2.3:5555",
+			want:       true,
+		},
+		{
+			name:       "bypass login",
+			site:       site,
+			remoteAddr: "1.2.3.4:5555",
+			login:      "admin",
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://vk.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			if got := (RevHandler{}).checkMaintenance(w, r, tt.site, tt.login); got != tt.want {
+				t.Fatalf("checkMaintenance() = %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				return
+			}
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/mirrorManager"
+	"reverseProxy/pkg/siteManager"
+	"sync"
+	"time"
+)
+
+// mirrorTimeout limits the shadow request, so that
+// the stuck shadow backends do not keep the slots
+const mirrorTimeout = 30 * time.Second
+
+// mirrorSample selects the request to be copied
+// with the percentage of the site
+var mirrorSample = func(percent int64) bool {
+	return rand.Int63n(100) < percent
+}
+
+// mirrorBody keeps the body of the request read by the primary
+// backend to copy it to the shadow backend, the body over the
+// limit is not kept. The transport may still read the body
+// after the response is received, so the buffer is locked
+type mirrorBody struct {
+	io.ReadCloser
+	mux      sync.Mutex
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+	eof      bool
+}
+
+func (b *mirrorBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if n > 0 && !b.overflow {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+// content returns the whole body, ok is false if the body
+// is over the limit or has not been read to the end
+func (b *mirrorBody) content() ([]byte, bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.overflow || !b.eof {
+		return nil, false
+	}
+	return append([]byte(nil), b.buf.Bytes()...), true
+}
+
+// mirrorRequest is the request copied to the shadow backend,
+// the response of the primary backend is kept if the site
+// compares it with the response of the shadow backend
+type mirrorRequest struct {
+	// body is nil if the request has no body
+	body     *mirrorBody
+	response *primaryResponse
+}
+
+// primaryResponse is the response of the primary
+// backend with its body kept while it is sent
+type primaryResponse struct {
+	status int
+	header http.Header
+	body   *mirrorBody
+}
+
+// startMirror selects the request to be copied to the shadow
+// backends of the site and keeps its body while the primary
+// backend reads it. It returns nil if the request is not copied
+func (h RevHandler) startMirror(r *http.Request, site *siteManager.Site) *mirrorRequest {
+	if !site.IsMirrorEnabled() || isUpgradeRequest(r) || !mirrorSample(site.Mirror.Percent) {
+		return nil
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return &mirrorRequest{}
+	}
+	limit := site.GetMirrorMaxBodySize()
+	if r.ContentLength > limit {
+		h.getLogs().GetInfo().Str("when", "start mirror").Int64("size", r.ContentLength).
+			Msg("body is too large to mirror, dropped")
+		mirrorManager.MirrorMgr.Count(siteHost(r, site), mirrorManager.Dropped)
+		return nil
+	}
+	body := &mirrorBody{ReadCloser: r.Body, limit: limit}
+	r.Body = body
+	return &mirrorRequest{body: body}
+}
+
+// keepResponse keeps the response of the primary backend
+// while it is sent to the user, if the site compares it
+// with the response of the shadow backend
+func (m *mirrorRequest) keepResponse(resp *http.Response, site *siteManager.Site) {
+	if m == nil || !site.IsMirrorCompared() {
+		return
+	}
+	body := &mirrorBody{ReadCloser: resp.Body, limit: site.GetMirrorMaxBodySize()}
+	m.response = &primaryResponse{status: resp.StatusCode, header: resp.Header.Clone(), body: body}
+	resp.Body = body
+}
+
+// sendMirror sends the copy of the request served by the primary
+// backend to the shadow backend in the background, the response
+// of the shadow backend is compared with the kept primary response
+// and discarded. The request is dropped if its body was not kept,
+// all slots are taken or there is no alive shadow backend
+func (h RevHandler) sendMirror(r *http.Request, site *siteManager.Site, route *backendManager.Route,
+	mirror *mirrorRequest) {
+	host := siteHost(r, site)
+	var content []byte
+	if mirror.body != nil {
+		var ok bool
+		if content, ok = mirror.body.content(); !ok {
+			h.getLogs().GetInfo().Str("when", "send mirror").Msg("body is not kept, dropped")
+			mirrorManager.MirrorMgr.Count(host, mirrorManager.Dropped)
+			return
+		}
+	}
+	client, err := getClient(host, site.Mirror.Pool)
+	if err != nil {
+		h.getLogs().GetWarn().Str("when", "send mirror").Str("pool", site.Mirror.Pool).
+			Err(err).Msg("no shadow client, dropped")
+		mirrorManager.MirrorMgr.Count(host, mirrorManager.Dropped)
+		return
+	}
+	if !mirrorManager.MirrorMgr.Acquire() {
+		h.getLogs().GetWarn().Str("when", "send mirror").Msg("too many shadow requests, dropped")
+		mirrorManager.MirrorMgr.Count(host, mirrorManager.Dropped)
+		return
+	}
+
+	// the request of the user is done when
+	// the shadow request is being sent
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	req := newBackendRequest(r, client, site, route).WithContext(ctx)
+	req.Body, req.ContentLength = http.NoBody, 0
+	if len(content) > 0 {
+		req.Body, req.ContentLength = ioutil.NopCloser(bytes.NewReader(content)), int64(len(content))
+	}
+	var primary *keptResponse
+	if mirror.response != nil {
+		primary = &keptResponse{status: mirror.response.status, header: mirror.response.header}
+		primary.body, _ = mirror.response.body.content()
+	}
+	requestID := r.Header.Get(requestIDHeader)
+	go func() {
+		defer mirrorManager.MirrorMgr.Release()
+		defer cancel()
+		resp, err := client.Cl.Do(req)
+		if err != nil {
+			h.getLogs().GetWarn().Str("when", "send mirror").Str("address", client.Address).
+				Err(err).Msg("shadow request failed")
+			mirrorManager.MirrorMgr.Count(host, mirrorManager.Failed)
+			return
+		}
+		defer resp.Body.Close()
+		shadow := &keptResponse{status: resp.StatusCode, header: resp.Header}
+		if primary != nil {
+			limit := site.GetMirrorMaxBodySize()
+			body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+			if err == nil && int64(len(body)) <= limit {
+				shadow.body = body
+			}
+		}
+		if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+			h.getLogs().GetWarn().Str("when", "send mirror").Str("address", client.Address).
+				Err(err).Msg("shadow response failed")
+			mirrorManager.MirrorMgr.Count(host, mirrorManager.Failed)
+			return
+		}
+		mirrorManager.MirrorMgr.Count(host, mirrorManager.Mirrored)
+		if primary != nil {
+			compareMirror(req, requestID, site, route, primary, shadow)
+		}
+	}()
+}
+
+// compareMirror counts the comparison of the responses of the
+// route and writes their differences to the diff log. The responses
+// with the bodies not compared and no other difference are counted
+// as skipped, they are neither matched nor mismatched
+func compareMirror(req *http.Request, requestID string, site *siteManager.Site, route *backendManager.Route,
+	primary, shadow *keptResponse) {
+	diffs, bodyCompared := compareResponses(primary, shadow, site)
+	if !bodyCompared && len(diffs) == 0 {
+		mirrorManager.MirrorMgr.CountSkipped(site.Host, route.GetId(), route.GetPattern())
+		return
+	}
+	mirrorManager.MirrorMgr.CountDiff(site.Host, route.GetId(), route.GetPattern(), len(diffs) > 0)
+	if len(diffs) == 0 {
+		return
+	}
+	logging.NewLogs("diffLog", "compareMirror").GetWarn().Str("request_id", requestID).
+		Str("site", site.Host).Int64("route", route.GetId()).Str("request_method", req.Method).
+		Str("uri", req.URL.RequestURI()).Strs("diffs", diffs).Msg("shadow response differs")
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/mirrorManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevHandler_sendMirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	shadowBodies := make(chan string, 10)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		shadowBodies <- r.Method + " " + r.URL.RequestURI() + " " + string(body)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("shadow"))
+	}))
+	defer shadow.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		shadow     *httptest.Server
+		slots      int64
+		want       mirrorManager.Counters
+		wantShadow string
+	}{
+		{
+			name:       "request without body",
+			method:     http.MethodGet,
+			shadow:     shadow,
+			slots:      1,
+			want:       mirrorManager.Counters{Mirrored: 1},
+			wantShadow: "GET /a?b=1 ",
+		},
+		{
+			name:       "request with body",
+			method:     http.MethodPost,
+			body:       "12345",
+			shadow:     shadow,
+			slots:      1,
+			want:       mirrorManager.Counters{Mirrored: 1},
+			wantShadow: "POST /a?b=1 12345",
+		},
+		{
+			name:   "body over limit",
+			method: http.MethodPost,
+			body:   "1234567890",
+			shadow: shadow,
+			slots:  1,
+			want:   mirrorManager.Counters{Dropped: 1},
+		},
+		{
+			name:   "no free slots",
+			method: http.MethodGet,
+			shadow: shadow,
+			want:   mirrorManager.Counters{Dropped: 1},
+		},
+		{
+			name:   "shadow backend is down",
+			method: http.MethodGet,
+			shadow: dead,
+			slots:  1,
+			want:   mirrorManager.Counters{Failed: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldMirror, oldGetClient := mirrorManager.MirrorMgr, getClient
+			mirrorManager.MirrorMgr = mirrorManager.NewMirrorManager(tt.slots)
+			primaryClient := &backendManager.Client{Alive: true, Address: primary.Listener.Addr().String()}
+			shadowClient := &backendManager.Client{Alive: true, Address: tt.shadow.Listener.Addr().String()}
+			getClient = func(host, pool string, tried ...*backendManager.Client) (*backendManager.Client, error) {
+				if pool == "shadow" {
+					return shadowClient, nil
+				}
+				return primaryClient, nil
+			}
+			defer func() { mirrorManager.MirrorMgr, getClient = oldMirror, oldGetClient }()
+
+			site := &siteManager.Site{Site: &sites.Site{Host: "example.com",
+				Mirror: sites.MirrorSettings{Pool: "shadow", Percent: 100, MaxBodySize: 8}}}
+			r := httptest.NewRequest(tt.method, "http://example.com/a?b=1", strings.NewReader(tt.body))
+			if tt.body == "" {
+				r.Body = http.NoBody
+			}
+			w := httptest.NewRecorder()
+			mirror := RevHandler{}.startMirror(r, site)
+			RevHandler{}.proxy(w, r, primaryClient, site, nil, nil, mirror)
+			if mirror != nil {
+				RevHandler{}.sendMirror(r, site, nil, mirror)
+			}
+			if got := w.Body.String(); got != "primary" {
+				t.Errorf("response = %q, want primary", got)
+			}
+
+			if tt.wantShadow != "" {
+				select {
+				case got := <-shadowBodies:
+					if got != tt.wantShadow {
+						t.Errorf("shadow request = %q, want %q", got, tt.wantShadow)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("shadow request is not sent")
+				}
+			}
+			deadline := time.Now().Add(time.Second)
+			for mirrorManager.MirrorMgr.Counters("example.com") != tt.want && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if got := mirrorManager.MirrorMgr.Counters("example.com"); got != tt.want {
+				t.Errorf("Counters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevHandler_startMirror(t *testing.T) {
+	site := &siteManager.Site{Site: &sites.Site{Mirror: sites.MirrorSettings{Pool: "shadow", Percent: 10}}}
+	oldSample := mirrorSample
+	defer func() { mirrorSample = oldSample }()
+	var percent int64
+	mirrorSample = func(p int64) bool {
+		percent = p
+		return false
+	}
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if mirror := (RevHandler{}).startMirror(r, site); mirror != nil || percent != 10 {
+		t.Errorf("startMirror() of not sampled request = %v with percent %d", mirror, percent)
+	}
+	percent = 0
+	if mirror := (RevHandler{}).startMirror(r, &siteManager.Site{Site: &sites.Site{}}); mirror != nil || percent != 0 {
+		t.Errorf("startMirror() of site without mirroring = %v", mirror)
+	}
+}
+
+func TestRevHandler_compareMirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"a","updated_at":1}`))
+	}))
+	defer primary.Close()
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/diff" {
+			w.Write([]byte(`{"name":"b","updated_at":2}`))
+			return
+		}
+		if r.URL.Path == "/api/zstd" {
+			w.Header().Set("Content-Encoding", "zstd")
+			w.Write([]byte("zstd"))
+			return
+		}
+		w.Write([]byte(`{"updated_at":2,"name":"a"}`))
+	}))
+	defer shadow.Close()
+
+	oldMirror, oldGetClient := mirrorManager.MirrorMgr, getClient
+	mirrorManager.MirrorMgr = mirrorManager.NewMirrorManager(10)
+	primaryClient := &backendManager.Client{Alive: true, Address: primary.Listener.Addr().String()}
+	shadowClient := &backendManager.Client{Alive: true, Address: shadow.Listener.Addr().String()}
+	getClient = func(host, pool string, tried ...*backendManager.Client) (*backendManager.Client, error) {
+		return shadowClient, nil
+	}
+	defer func() { mirrorManager.MirrorMgr, getClient = oldMirror, oldGetClient }()
+
+	site := &siteManager.Site{
+		Site: &sites.Site{Host: "example.com", Mirror: sites.MirrorSettings{Pool: "shadow", Percent: 100,
+			Compare: true}},
+		MirrorIgnorePaths: [][]string{{"updated_at"}},
+	}
+	route := &backendManager.Route{Id: 3, Prefix: "/api", Pool: "new"}
+	for _, path := range []string{"/api/same", "/api/diff", "/api/zstd"} {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		setRequestID(r)
+		w := httptest.NewRecorder()
+		mirror := RevHandler{}.startMirror(r, site)
+		RevHandler{}.proxy(w, r, primaryClient, site, route, nil, mirror)
+		RevHandler{}.sendMirror(r, site, route, mirror)
+		if got := w.Body.String(); got != `{"name":"a","updated_at":1}` {
+			t.Errorf("response = %q, want primary response", got)
+		}
+	}
+
+	want := []mirrorManager.RouteDiffs{{Route: 3, Pattern: "/api", Compared: 2, Mismatched: 1, Skipped: 1,
+		MismatchRate: 0.5}}
+	deadline := time.Now().Add(time.Second)
+	for !reflect.DeepEqual(mirrorManager.MirrorMgr.Diffs("example.com"), want) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := mirrorManager.MirrorMgr.Diffs("example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diffs() = %+v, want %+v", got, want)
+	}
+}
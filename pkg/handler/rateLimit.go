@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"reverseProxy/pkg/rateManager"
+	"reverseProxy/pkg/repositories/rateLimits"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+	"time"
+)
+
+// rateLimitKey returns the key of the bucket of the
+// request, the requests without the login or the
+// header are limited by the client IP
+func rateLimitKey(r *http.Request, site *siteManager.Site, limit *rateLimits.RateLimit, login string) string {
+	switch limit.Key {
+	case rateLimits.KeySite:
+		return limit.Key
+	case rateLimits.KeyLogin:
+		if login != "" {
+			return limit.Key + " " + login
+		}
+	case rateLimits.KeyHeader:
+		if value := r.Header.Get(limit.Header); value != "" {
+			return limit.Key + " " + value
+		}
+	}
+	ip := clientIP(r, site)
+	if ip == nil {
+		return rateLimits.KeyIP + " " + r.RemoteAddr
+	}
+	return rateLimits.KeyIP + " " + ip.String()
+}
+
+// limitRate takes the tokens of the request from the
+// buckets of the rate limits of the site and sends 429
+// if any bucket is empty, the RateLimit headers show
+// the bucket with the fewest remaining tokens; byLogin
+// selects the limits keyed by the login, which are taken
+// after the authorization, the others are taken before it
+func (h RevHandler) limitRate(w http.ResponseWriter, r *http.Request, site *siteManager.Site,
+	login string, byLogin bool) bool {
+	now := time.Now()
+	var tightest *rateManager.Result
+	for _, limit := range site.GetRateLimits() {
+		if (limit.Key == rateLimits.KeyLogin) != byLogin {
+			continue
+		}
+		result := rateManager.RateMgr.Take(limit, rateLimitKey(r, site, limit, login), now)
+		if !result.Allowed {
+			h.getLogs().GetWarn().Str("when", "limit rate").Int64("limit", limit.Id).
+				Msg("rate limit exceeded")
+			setRateLimitHeaders(w.Header(), result)
+			w.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			h.sendError(w, r, site, http.StatusTooManyRequests, "too many requests")
+			return false
+		}
+		if tightest == nil || result.Remaining < tightest.Remaining {
+			tightest = &result
+		}
+	}
+	if tightest == nil {
+		return true
+	}
+	// the bucket taken before the
+	// authorization may have fewer tokens
+	remaining, err := strconv.ParseInt(w.Header().Get("RateLimit-Remaining"), 10, 64)
+	if err == nil && remaining <= tightest.Remaining {
+		return true
+	}
+	setRateLimitHeaders(w.Header(), *tightest)
+	return true
+}
+
+// setRateLimitHeaders sets the RateLimit headers
+// of the state of the bucket
+func setRateLimitHeaders(header http.Header, result rateManager.Result) {
+	header.Set("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	header.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+}
+
+// ceilSeconds rounds the duration up to seconds,
+// at least one second
+func ceilSeconds(d time.Duration) int64 {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/rateManager"
+	"reverseProxy/pkg/repositories/rateLimits"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	trustedProxies, err := siteManager.ParseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDRs() error = %v", err)
+	}
+	site := &siteManager.Site{Site: &sites.Site{Host: "vk.com"}, TrustedProxies: trustedProxies}
+
+	tests := []struct {
+		name       string
+		limit      *rateLimits.RateLimit
+		login      string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{
+			name:       "client ip",
+			limit:      &rateLimits.RateLimit{Key: rateLimits.KeyIP},
+			remoteAddr: "1.2.3.4:5555",
+			header:     http.Header{"X-Forwarded-For": {"5.6.7.8"}},
+			want:       "ip 1.2.3.4",
+		},
+		{
+			name:       "client ip behind trusted proxies",
+			limit:      &rateLimits.RateLimit{Key: rateLimits.KeyIP},
+			remoteAddr: "10.0.0.1:5555",
+			header:     http.Header{"X-Forwarded-For": {"5.6.7.8, 1.2.3.4", "10.0.0.2"}},
+			want:       "ip 1.2.3.4",
+		},
+		{
+			name:       "login",
+			limit:      &rateLimits.RateLimit{Key: rateLimits.KeyLogin},
+			login:      "user",
+			remoteAddr: "1.2.3.4:5555",
+			want:       "login user",
+		},
+		{
+			name:       "no login",
+			limit:      &rateLimits.RateLimit{Key: rateLimits.KeyLogin},
+			remoteAddr: "1.2.3.4:5555",
+			want:       "ip 1.2.3.4",
+		},
+		{
+			name:       "header",
+			limit:      &rateLimits.RateLimit{Key: rateLimits.KeyHeader, Header: "X-Api-Key"},
+			remoteAddr: "1.2.3.4:5555",
+			header:     http.Header{"X-Api-Key": {"secret"}},
+			want:       "header secret",
+		},
+		{
+			name:       "site",
+			limit:      &rateLimits.RateLimit{Key: rateLimits.KeySite},
+			remoteAddr: "1.2.3.4:5555",
+			want:       "site",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://vk.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			if got := rateLimitKey(r, site, tt.limit, tt.login); got != tt.want {
+				t.Errorf("rateLimitKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevHandler_limitRate(t *testing.T) {
+	oldRateMgr := rateManager.RateMgr
+	rateManager.RateMgr = rateManager.NewRateManager(nil)
+	defer func() { rateManager.RateMgr = oldRateMgr }()
+	site := &siteManager.Site{
+		Site: &sites.Site{Host: "vk.com"},
+		RateLimits: []*rateLimits.RateLimit{
+			{Id: 1, Key: rateLimits.KeyIP, Requests: 1, Period: 60000, Burst: 2},
+			{Id: 2, Key: rateLimits.KeySite, Requests: 100, Period: 1000},
+		},
+	}
+
+	for i, want := range []struct {
+		status    int
+		remaining string
+	}{
+		{status: http.StatusOK, remaining: "1"},
+		{status: http.StatusOK, remaining: "0"},
+		{status: http.StatusTooManyRequests, remaining: "0"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://vk.com/", nil)
+		w := httptest.NewRecorder()
+		if allowed := (RevHandler{}).limitRate(w, r, site, "", false); allowed != (want.status == http.StatusOK) {
+			t.Errorf("request %d: limitRate() = %v", i, allowed)
+		}
+		if w.Code != want.status {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, want.status)
+		}
+		header := w.Header()
+		if header.Get("RateLimit-Limit") != "2" || header.Get("RateLimit-Remaining") != want.remaining {
+			t.Errorf("request %d: RateLimit headers = %v, want limit 2 and remaining %s",
+				i, header, want.remaining)
+		}
+		if want.status == http.StatusTooManyRequests && header.Get("Retry-After") != "60" {
+			t.Errorf("request %d: Retry-After = %q, want 60", i, header.Get("Retry-After"))
+		}
+	}
+
+	// other users are not limited by
+	// the bucket of the client ip
+	r := httptest.NewRequest(http.MethodGet, "http://vk.com/", nil)
+	r.RemoteAddr = "1.2.3.4:5555"
+	if !(RevHandler{}).limitRate(httptest.NewRecorder(), r, site, "", false) {
+		t.Errorf("request of other client ip rejected")
+	}
+}
+
+func TestRevHandler_limitRateByLogin(t *testing.T) {
+	oldRateMgr := rateManager.RateMgr
+	rateManager.RateMgr = rateManager.NewRateManager(nil)
+	defer func() { rateManager.RateMgr = oldRateMgr }()
+	site := &siteManager.Site{
+		Site: &sites.Site{Host: "vk.com"},
+		RateLimits: []*rateLimits.RateLimit{
+			{Id: 1, Key: rateLimits.KeyIP, Requests: 1, Period: 60000, Burst: 5},
+			{Id: 2, Key: rateLimits.KeyLogin, Requests: 1, Period: 60000, Burst: 1},
+		},
+	}
+
+	// the limits of the client ip are taken before the
+	// authorization, the limits of the login after it
+	r := httptest.NewRequest(http.MethodGet, "http://vk.com/", nil)
+	w := httptest.NewRecorder()
+	if !(RevHandler{}).limitRate(w, r, site, "", false) {
+		t.Fatalf("request before authorization rejected")
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "4" {
+		t.Errorf("RateLimit-Remaining before authorization = %q, want 4", got)
+	}
+	if !(RevHandler{}).limitRate(w, r, site, "user", true) {
+		t.Fatalf("request after authorization rejected")
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining after authorization = %q, want 0", got)
+	}
+
+	// the empty bucket of the login does not
+	// reject the requests before the authorization
+	w = httptest.NewRecorder()
+	if !(RevHandler{}).limitRate(w, r, site, "", false) {
+		t.Errorf("request before authorization rejected by the limit of the login")
+	}
+	if (RevHandler{}).limitRate(w, r, site, "user", true) {
+		t.Errorf("request after authorization not rejected by the limit of the login")
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+)
+
+// redirect sends the redirect of the request by the redirect rules:
+// the redirected hosts go to the host of their site, the http requests
+// go to https and the paths go to the target of the first matching
+// rule, all of them in one redirect. It returns false if no rule applies
+func (h RevHandler) redirect(w http.ResponseWriter, r *http.Request, site *siteManager.Site) bool {
+	scheme, host, status := clientScheme(r, site), r.Host, 0
+	if rule, target := siteManager.SiteMgr.GetHostRedirect(r.Host); rule != nil && target != nil {
+		site, host, status = target, target.Host, rule.GetStatus()
+	}
+	if rule := site.GetHTTPSRedirect(); rule != nil && scheme != "https" {
+		scheme = "https"
+		// the port of the http listener is not the port of https
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		if status == 0 {
+			status = rule.GetStatus()
+		}
+	}
+
+	location := &url.URL{Scheme: scheme, Host: host, Path: r.URL.Path, RawPath: r.URL.RawPath,
+		RawQuery: r.URL.RawQuery}
+	for _, rule := range site.GetPathRedirects() {
+		target, ok := rule.RedirectPath(r.URL.Path)
+		if !ok {
+			continue
+		}
+		targetURL, err := url.Parse(target)
+		if err != nil {
+			h.getLogs().GetWarn().Str("when", "redirect path").Int64("rule", rule.Id).
+				Str("target", target).Err(err).Msg("invalid target of redirect, skipped")
+			continue
+		}
+		if !targetURL.IsAbs() {
+			targetURL.Scheme, targetURL.Host = scheme, host
+		}
+		if !strings.Contains(target, "?") {
+			targetURL.RawQuery = r.URL.RawQuery
+		}
+		if targetURL.String() == location.String() {
+			continue
+		}
+		location = targetURL
+		if status == 0 {
+			status = rule.GetStatus()
+		}
+		break
+	}
+	if status == 0 {
+		return false
+	}
+
+	h.getLogs().GetInfo().Str("when", "redirect").Str("location", location.String()).
+		Int("status", status).Msg("request redirected")
+	http.Redirect(w, r, location.String(), status)
+	return true
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/repositories/redirectRules"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestRevHandler_redirect(t *testing.T) {
+	newRedirect := func(rule *redirectRules.RedirectRule) *siteManager.Redirect {
+		rule.Site = &sites.Site{Host: "example.com"}
+		redirect, err := siteManager.NewRedirect(rule)
+		if err != nil {
+			t.Fatalf("NewRedirect() error = %v", err)
+		}
+		return redirect
+	}
+	trustedProxies, err := siteManager.ParseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDRs() error = %v", err)
+	}
+	site := &siteManager.Site{
+		Site:           &sites.Site{Host: "example.com"},
+		TrustedProxies: trustedProxies,
+		HTTPSRedirect:  newRedirect(&redirectRules.RedirectRule{Kind: redirectRules.KindHTTPS, Status: 308}),
+		PathRedirects: []*siteManager.Redirect{
+			newRedirect(&redirectRules.RedirectRule{Kind: redirectRules.KindPath, Source: "^/old/(.*)$",
+				Target: "/new/$1", Status: 302}),
+			newRedirect(&redirectRules.RedirectRule{Kind: redirectRules.KindPath, Source: "^/moved$",
+				Target: "https://other.com/landing?from=moved"}),
+			newRedirect(&redirectRules.RedirectRule{Kind: redirectRules.KindPath, Source: "^/same$",
+				Target: "/same"}),
+		},
+	}
+	pathOnly := &siteManager.Site{Site: &sites.Site{Host: "example.com"}, PathRedirects: site.PathRedirects}
+
+	tests := []struct {
+		name         string
+		site         *siteManager.Site
+		url          string
+		remoteAddr   string
+		header       http.Header
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "http to https",
+			site:         site,
+			url:          "http://example.com:8080/page?q=1",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://example.com/page?q=1",
+		},
+		{
+			name:       "https behind trusted proxy",
+			site:       site,
+			url:        "http://example.com/page",
+			remoteAddr: "10.0.0.1:5555",
+			header:     http.Header{"X-Forwarded-Proto": {"https"}},
+		},
+		{
+			name:         "forwarded proto of untrusted user",
+			site:         site,
+			url:          "http://example.com/page",
+			header:       http.Header{"X-Forwarded-Proto": {"https"}},
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://example.com/page",
+		},
+		{
+			name:         "https and path in one redirect",
+			site:         site,
+			url:          "http://example.com/old/a/b?q=1",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://example.com/new/a/b?q=1",
+		},
+		{
+			name:         "path",
+			site:         pathOnly,
+			url:          "http://example.com/old/a",
+			wantStatus:   http.StatusFound,
+			wantLocation: "http://example.com/new/a",
+		},
+		{
+			name:         "path to other site keeps its query",
+			site:         pathOnly,
+			url:          "http://example.com/moved?q=1",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://other.com/landing?from=moved",
+		},
+		{
+			name: "path to itself",
+			site: pathOnly,
+			url:  "http://example.com/same",
+		},
+		{
+			name: "no rules",
+			site: &siteManager.Site{Site: &sites.Site{Host: "example.com"}},
+			url:  "http://example.com/old/a",
+		},
+		{
+			name: "no site",
+			url:  "http://example.com/old/a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			redirected := RevHandler{}.redirect(w, r, tt.site)
+			if redirected != (tt.wantStatus != 0) {
+				t.Fatalf("redirect() = %v, want %v", redirected, tt.wantStatus != 0)
+			}
+			if !redirected {
+				return
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
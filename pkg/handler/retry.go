@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/siteManager"
+	"sync/atomic"
+	"time"
+)
+
+// getClient selects the client of the pool for the host,
+// the tried clients are not selected
+var getClient = func(host, pool string, tried ...*backendManager.Client) (*backendManager.Client, error) {
+	return backendManager.BackendMgr.GetClient(host, pool, tried...)
+}
+
+// retryBody is the body of the user's request, which
+// counts the read bytes, so that the request is not
+// retried after a part of the body has been sent
+type retryBody struct {
+	body io.ReadCloser
+	read int64
+}
+
+// newRetryBody wraps the body of the request,
+// it returns nil if the request has no body
+func newRetryBody(r *http.Request) *retryBody {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	return &retryBody{body: r.Body}
+}
+
+// Read reads the body and counts the read bytes
+func (b *retryBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	atomic.AddInt64(&b.read, int64(n))
+	return n, err
+}
+
+// Close does not close the body, the transport closes
+// it on errors and the body must survive the retry,
+// the body of the user is closed by the server
+func (b *retryBody) Close() error {
+	return nil
+}
+
+// consumed determines whether a part of
+// the body has been read
+func (b *retryBody) consumed() bool {
+	if b == nil {
+		return false
+	}
+	return atomic.LoadInt64(&b.read) > 0
+}
+
+// isIdempotent determines whether the request can be
+// sent again regardless of the failure
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isDialError determines whether the connection to
+// the client failed before the request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isTimeout determines whether the
+// client did not respond in time
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// canRetry determines whether the failed request can be
+// sent to another client: idempotent requests are retried
+// on any failure, other requests only if the connection
+// failed, and never after a part of the body was sent
+func canRetry(r *http.Request, err error, body *retryBody) bool {
+	if r.Context().Err() != nil || body.consumed() {
+		return false
+	}
+	return isIdempotent(r.Method) || isDialError(err)
+}
+
+// waitRetry waits for the delay, it returns false
+// if the user has gone away in the meantime
+func waitRetry(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nextClient returns another client for the failed attempt,
+// ok is false if the request must not be retried
+func (h RevHandler) nextClient(r *http.Request, err error, body *retryBody, site *siteManager.Site,
+	route *backendManager.Route, attempt int, tried []*backendManager.Client) (*backendManager.Client, bool) {
+	if attempt >= site.GetRetryAttempts() || !canRetry(r, err, body) {
+		return nil, false
+	}
+	if !waitRetry(r.Context(), site.GetRetryBackoff(attempt+1)) {
+		return nil, false
+	}
+	client, err := getClient(siteHost(r, site), route.GetPool(), tried...)
+	if err != nil {
+		h.getLogs().GetWarn().Str("when", "get client for retry").
+			Err(err).Msg("no other client to retry")
+		return nil, false
+	}
+	return client, true
+}
+
+// clientAddresses returns the addresses of the clients
+func clientAddresses(clients []*backendManager.Client) []string {
+	addresses := make([]string, 0, len(clients))
+	for _, client := range clients {
+		addresses = append(addresses, client.Address)
+	}
+	return addresses
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+	"testing"
+)
+
+// deadAddress returns the address, which
+// refuses the connections
+func deadAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatalf("unable to close listener: %v", err)
+	}
+	return address
+}
+
+// containsClient determines whether the
+// client is in the list
+func containsClient(list []*backendManager.Client, client *backendManager.Client) bool {
+	for _, c := range list {
+		if c == client {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRevHandler_proxyRetry(t *testing.T) {
+	// echo answers with the method and the body of the request
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer echo.Close()
+	// broken reads the request and closes the connection without response
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer broken.Close()
+	dead := deadAddress(t)
+	defaultGetClient := getClient
+	defer func() { getClient = defaultGetClient }()
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		first      string
+		attempts   int64
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "idempotent request is retried after dial error",
+			method:     http.MethodGet,
+			first:      dead,
+			wantStatus: http.StatusOK,
+			wantBody:   "GET ",
+		},
+		{
+			name:       "idempotent request is retried after broken response",
+			method:     http.MethodGet,
+			first:      broken.Listener.Addr().String(),
+			wantStatus: http.StatusOK,
+			wantBody:   "GET ",
+		},
+		{
+			name:       "request with body is retried after dial error",
+			method:     http.MethodPost,
+			body:       "payload",
+			first:      dead,
+			wantStatus: http.StatusOK,
+			wantBody:   "POST payload",
+		},
+		{
+			name:       "sent request is not retried",
+			method:     http.MethodPost,
+			body:       "payload",
+			first:      broken.Listener.Addr().String(),
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "single attempt is not retried",
+			method:     http.MethodGet,
+			first:      dead,
+			attempts:   1,
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &backendManager.Client{Alive: true, Address: tt.first}
+			second := &backendManager.Client{Alive: true, Address: echo.Listener.Addr().String()}
+			getClient = func(host, pool string, tried ...*backendManager.Client) (*backendManager.Client, error) {
+				for _, client := range []*backendManager.Client{first, second} {
+					if !containsClient(tried, client) {
+						return client, nil
+					}
+				}
+				return nil, backendManager.ErrClientNotFound
+			}
+			site := &siteManager.Site{Site: &sites.Site{Retry: sites.RetrySettings{Attempts: tt.attempts}}}
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				RevHandler{}.proxy(w, r, first, site, nil, nil, nil)
+			}))
+			defer proxy.Close()
+
+			req, err := http.NewRequest(tt.method, proxy.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to send request: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unable to read body: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
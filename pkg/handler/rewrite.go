@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/siteManager"
+)
+
+// rewritePath rewrites the path of the request to the client by
+// the rules of the route, or by the rules of the site if the route
+// has none. The escaped path is rewritten, so that the encoded
+// characters like %2F are kept. X-Forwarded-Prefix of the rewritten
+// request is always the original escaped path asked by the user,
+// after the prefix sent by a trusted proxy, so that the client can
+// build the links of the original path
+func rewritePath(req *http.Request, route *backendManager.Route, site *siteManager.Site) {
+	rewriter := route.GetRewriter()
+	if rewriter == nil {
+		rewriter = site.GetRewriter()
+	}
+	original := req.URL.EscapedPath()
+	escaped := rewriter.Rewrite(original)
+	if escaped == original {
+		return
+	}
+	if path, err := url.PathUnescape(escaped); err == nil {
+		req.URL.Path = path
+		req.URL.RawPath = escaped
+	} else {
+		// the replacement made an invalid escape,
+		// the path is escaped as it is
+		req.URL.Path = escaped
+		req.URL.RawPath = ""
+	}
+	req.Header.Set("X-Forwarded-Prefix", req.Header.Get("X-Forwarded-Prefix")+original)
+}
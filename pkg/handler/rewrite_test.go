@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/repositories/routes"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"testing"
+)
+
+func TestRewritePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite routes.Rewrite
+		// siteRewrite is used by the routes without rules
+		siteRewrite sites.Rewrite
+		target      string
+		// prefix is sent by a trusted proxy
+		prefix     string
+		wantURI    string
+		wantPrefix string
+	}{
+		{
+			name:       "strip prefix",
+			rewrite:    routes.Rewrite{StripPrefix: "/billing"},
+			target:     "/billing/invoices?page=2",
+			wantURI:    "/invoices?page=2",
+			wantPrefix: "/billing/invoices",
+		},
+		{
+			name:       "strip prefix to root",
+			rewrite:    routes.Rewrite{StripPrefix: "/billing"},
+			target:     "/billing",
+			wantURI:    "/",
+			wantPrefix: "/billing",
+		},
+		{
+			name:       "regex",
+			rewrite:    routes.Rewrite{Regex: "^/v([0-9]+)/(.*)$", Replacement: "/api/$2/version/$1"},
+			target:     "/v2/users",
+			wantURI:    "/api/users/version/2",
+			wantPrefix: "/v2/users",
+		},
+		{
+			name:       "regex removing the front",
+			rewrite:    routes.Rewrite{Regex: "^/v[0-9]+/", Replacement: "/"},
+			target:     "/v2/users",
+			wantURI:    "/users",
+			wantPrefix: "/v2/users",
+		},
+		{
+			name:       "add prefix",
+			rewrite:    routes.Rewrite{AddPrefix: "/internal"},
+			target:     "/users",
+			wantURI:    "/internal/users",
+			wantPrefix: "/users",
+		},
+		{
+			name:       "encoded slash is kept",
+			rewrite:    routes.Rewrite{StripPrefix: "/files"},
+			target:     "/files/a%2Fb/c",
+			wantURI:    "/a%2Fb/c",
+			wantPrefix: "/files/a%2Fb/c",
+		},
+		{
+			name:       "prefix of the trusted proxy is kept in front",
+			rewrite:    routes.Rewrite{StripPrefix: "/billing"},
+			target:     "/billing/invoices",
+			prefix:     "/shop",
+			wantURI:    "/invoices",
+			wantPrefix: "/shop/billing/invoices",
+		},
+		{
+			name:    "unchanged path sends no prefix",
+			rewrite: routes.Rewrite{StripPrefix: "/billing"},
+			target:  "/users",
+			wantURI: "/users",
+		},
+		{
+			name:        "rules of the site",
+			siteRewrite: sites.Rewrite{StripPrefix: "/shop"},
+			target:      "/shop/cart",
+			wantURI:     "/cart",
+			wantPrefix:  "/shop/cart",
+		},
+		{
+			name:        "rules of the route replace the site",
+			rewrite:     routes.Rewrite{AddPrefix: "/internal"},
+			siteRewrite: sites.Rewrite{StripPrefix: "/shop"},
+			target:      "/shop/cart",
+			wantURI:     "/internal/shop/cart",
+			wantPrefix:  "/shop/cart",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := backendManager.NewRoute(&routes.Route{Prefix: "/", Rewrite: tt.rewrite})
+			if err != nil {
+				t.Fatalf("NewRoute() error = %v", err)
+			}
+			rewriter, err := backendManager.NewRewriter(tt.siteRewrite)
+			if err != nil {
+				t.Fatalf("NewRewriter() error = %v", err)
+			}
+			site := &siteManager.Site{Site: &sites.Site{Rewrite: tt.siteRewrite}, Rewriter: rewriter}
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.prefix != "" {
+				req.Header.Set("X-Forwarded-Prefix", tt.prefix)
+			}
+			rewritePath(req, route, site)
+			if got := req.URL.RequestURI(); got != tt.wantURI {
+				t.Errorf("RequestURI() = %q, want %q", got, tt.wantURI)
+			}
+			if got := req.Header.Get("X-Forwarded-Prefix"); got != tt.wantPrefix {
+				t.Errorf("X-Forwarded-Prefix = %q, want %q", got, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	bufferSize    = 32 * 1024
+	flushInterval = 100 * time.Millisecond
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, bufferSize)
+		return &buf
+	},
+}
+
+// getFlushInterval determines how often the response
+// must be flushed to the client: a negative value
+// means after each write, zero means never
+func getFlushInterval(resp *http.Response) time.Duration {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return -1
+	}
+	if resp.ContentLength == -1 {
+		return flushInterval
+	}
+	return 0
+}
+
+// copyResponse streams the response body to the
+// client using the pooled buffer
+func (h RevHandler) copyResponse(w http.ResponseWriter, resp *http.Response) error {
+	var dst io.Writer = w
+	if interval := getFlushInterval(resp); interval != 0 {
+		if flusher, ok := w.(http.Flusher); ok {
+			mlw := &maxLatencyWriter{
+				dst:     w,
+				flusher: flusher,
+				latency: interval,
+			}
+			defer mlw.stop()
+			dst = mlw
+		}
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	for {
+		n, readErr := resp.Body.Read(*buf)
+		if n > 0 {
+			if _, err := dst.Write((*buf)[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// maxLatencyWriter flushes the written data to the
+// client no later than latency after the write
+type maxLatencyWriter struct {
+	dst     io.Writer
+	flusher http.Flusher
+	latency time.Duration
+
+	mux          sync.Mutex
+	timer        *time.Timer
+	flushPending bool
+}
+
+// Write writes data and schedules the flush
+func (m *maxLatencyWriter) Write(p []byte) (int, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	n, err := m.dst.Write(p)
+	if m.latency < 0 {
+		m.flusher.Flush()
+		return n, err
+	}
+	if m.flushPending {
+		return n, err
+	}
+	if m.timer == nil {
+		m.timer = time.AfterFunc(m.latency, m.delayedFlush)
+	} else {
+		m.timer.Reset(m.latency)
+	}
+	m.flushPending = true
+	return n, err
+}
+
+// delayedFlush flushes the data scheduled by Write
+func (m *maxLatencyWriter) delayedFlush() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if !m.flushPending {
+		return
+	}
+	m.flusher.Flush()
+	m.flushPending = false
+}
+
+// stop cancels the scheduled flush
+func (m *maxLatencyWriter) stop() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.flushPending = false
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetFlushInterval(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		contentLength int64
+		want          time.Duration
+	}{
+		{name: "event stream", contentType: "text/event-stream; charset=utf-8", contentLength: -1, want: -1},
+		{name: "unknown length", contentType: "text/plain", contentLength: -1, want: flushInterval},
+		{name: "known length", contentType: "text/plain", contentLength: 10, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header:        http.Header{"Content-Type": {tt.contentType}},
+				ContentLength: tt.contentLength,
+			}
+			if got := getFlushInterval(resp); got != tt.want {
+				t.Errorf("getFlushInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevHandler_streamFlush(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		// wantWithin is the time the first chunk must reach
+		// the user while the backend holds the response
+		wantWithin time.Duration
+	}{
+		{name: "event stream is flushed after each write", contentType: "text/event-stream", wantWithin: flushInterval},
+		{name: "unknown length is flushed after the interval", contentType: "text/plain", wantWithin: 5 * flushInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				fmt.Fprint(w, "first\n")
+				w.(http.Flusher).Flush()
+				<-release
+				fmt.Fprint(w, "second\n")
+			}))
+			defer backend.Close()
+			proxy := newTestProxy(backend)
+			defer proxy.Close()
+			defer close(release)
+
+			resp, err := http.Get(proxy.URL)
+			if err != nil {
+				t.Fatalf("unable to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			start := time.Now()
+			chunk := make([]byte, len("first\n"))
+			if _, err := resp.Body.Read(chunk); err != nil {
+				t.Fatalf("unable to read the first chunk: %v", err)
+			}
+			if string(chunk) != "first\n" {
+				t.Errorf("first chunk = %q, want %q", chunk, "first\n")
+			}
+			if elapsed := time.Since(start); elapsed > tt.wantWithin {
+				t.Errorf("first chunk took %v, want within %v", elapsed, tt.wantWithin)
+			}
+		})
+	}
+}
+
+func TestRevHandler_streamAbort(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// the chunked body is cut off without the last chunk,
+		// without the abort the user would take it as complete
+		fmt.Fprint(buf, "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n9\r\ntruncated\r\n")
+		buf.Flush()
+		// the user gets the header and the chunk before the cut
+		time.Sleep(2 * flushInterval)
+	}))
+	defer backend.Close()
+	proxy := newTestProxy(backend)
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("body of %d bytes read without error, want the connection aborted", len(body))
+	}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/siteManager"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+var tunnels = &tunnelRegistry{conns: make(map[net.Conn]struct{})}
+
+// tunnelRegistry stores the connections of the
+// active tunnels, so that they can be closed
+// on shutdown
+type tunnelRegistry struct {
+	conns  map[net.Conn]struct{}
+	closed bool
+	mux    sync.Mutex
+}
+
+// add registers the connection, it returns false
+// if the registry has already been closed
+func (t *tunnelRegistry) add(conn net.Conn) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.closed {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+// remove unregisters the connection
+func (t *tunnelRegistry) remove(conn net.Conn) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.conns, conn)
+}
+
+// closeAll closes all registered connections
+// and rejects new ones
+func (t *tunnelRegistry) closeAll() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.closed = true
+	for conn := range t.conns {
+		if err := conn.Close(); err != nil {
+			continue
+		}
+	}
+	t.conns = make(map[net.Conn]struct{})
+}
+
+// CloseTunnels closes all upgraded connections,
+// http.Server.Shutdown does not track them
+func CloseTunnels() {
+	tunnels.closeAll()
+}
+
+// isUpgradeRequest determines whether the request
+// asks to switch the protocol
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header["Connection"] {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveUpgrade sends the upgrade request to the client
+// and splices the connections of the user and the
+// client when the client switches the protocol
+func (h RevHandler) serveUpgrade(w http.ResponseWriter, r *http.Request, client *backendManager.Client,
+	site *siteManager.Site, route *backendManager.Route) {
+	h.getLogs().GetInfo().Str("upgrade", r.Header.Get("Upgrade")).Msg("start upgrade tunnel")
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		h.getLogs().GetError().Str("when", "upgrade connection").
+			Msg("response writer does not support hijacking")
+		h.sendError(w, r, site, http.StatusInternalServerError, "upgrade not supported")
+		return
+	}
+
+	tried := []*backendManager.Client{client}
+	backendConn, err := client.Dial(dialTimeout)
+	for attempt := 1; err != nil; attempt++ {
+		h.getLogs().GetWarn().Str("when", "dial client").Str("address", client.Address).
+			Int("attempt", attempt).Err(err).Msg("unable to dial client")
+		next, ok := h.nextClient(r, err, nil, site, route, attempt, tried)
+		if !ok {
+			h.getLogs().GetError().Str("when", "dial client").
+				Strs("tried", clientAddresses(tried)).Err(err).Msg("all attempts failed")
+			h.sendBackendError(w, r, site, err)
+			return
+		}
+		client = next
+		tried = append(tried, client)
+		backendConn, err = client.Dial(dialTimeout)
+	}
+	defer func() {
+		if err := backendConn.Close(); err != nil {
+			return
+		}
+	}()
+
+	req := newBackendRequest(r, client, site, route)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+	req.Header.Del("Te")
+	if err := req.Write(backendConn); err != nil {
+		h.getLogs().GetError().Str("when", "send upgrade request").
+			Err(err).Msg("unable to send request")
+		h.sendError(w, r, site, http.StatusBadGateway, "service failed")
+		return
+	}
+
+	backendReader := bufio.NewReader(backendConn)
+	resp, err := http.ReadResponse(backendReader, req)
+	if err != nil {
+		h.getLogs().GetError().Str("when", "read upgrade response").
+			Err(err).Msg("unable to read response")
+		h.sendBackendError(w, r, site, err)
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			return
+		}
+	}()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		h.getLogs().GetWarn().Int("status", resp.StatusCode).Msg("client refused the upgrade")
+		removeHopHeaders(resp.Header)
+		resp.Header.Del(surrogateKeyHeader)
+		copyHeader(w.Header(), resp.Header)
+		applyHeaderRules(w.Header(), site.GetResponseHeaders(), headerVars(r, site, client))
+		w.WriteHeader(resp.StatusCode)
+		if err := h.copyResponse(w, resp); err != nil {
+			h.getLogs().GetError().Str("when", "stream response body").
+				Err(err).Msg("unable to stream body")
+		}
+		return
+	}
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		h.getLogs().GetError().Str("when", "hijack connection").
+			Err(err).Msg("unable to hijack connection")
+		return
+	}
+	defer func() {
+		if err := clientConn.Close(); err != nil {
+			return
+		}
+	}()
+	if err := clientConn.SetDeadline(time.Time{}); err != nil {
+		h.getLogs().GetError().Str("when", "reset deadline").
+			Err(err).Msg("unable to reset deadline")
+		return
+	}
+
+	if !tunnels.add(clientConn) {
+		h.getLogs().GetWarn().Msg("server is shutting down, tunnel rejected")
+		return
+	}
+	defer tunnels.remove(clientConn)
+	if !tunnels.add(backendConn) {
+		return
+	}
+	defer tunnels.remove(backendConn)
+
+	upgrade := resp.Header.Get("Upgrade")
+	removeHopHeaders(resp.Header)
+	resp.Header.Set("Connection", "Upgrade")
+	resp.Header.Set("Upgrade", upgrade)
+	applyHeaderRules(resp.Header, site.GetResponseHeaders(), headerVars(r, site, client))
+	if _, err := fmt.Fprintf(clientBuf, "HTTP/1.1 %s\r\n", resp.Status); err != nil {
+		h.getLogs().GetError().Str("when", "send upgrade response").
+			Err(err).Msg("unable to send response")
+		return
+	}
+	if err := resp.Header.Write(clientBuf); err != nil {
+		h.getLogs().GetError().Str("when", "send upgrade response").
+			Err(err).Msg("unable to send response")
+		return
+	}
+	if _, err := clientBuf.WriteString("\r\n"); err != nil {
+		h.getLogs().GetError().Str("when", "send upgrade response").
+			Err(err).Msg("unable to send response")
+		return
+	}
+	if err := clientBuf.Flush(); err != nil {
+		h.getLogs().GetError().Str("when", "send upgrade response").
+			Err(err).Msg("unable to send response")
+		return
+	}
+
+	h.getLogs().GetInfo().Msg("protocol switched, splice connections")
+	errc := make(chan error, 2)
+	go splice(backendConn, clientBuf.Reader, errc)
+	go splice(clientConn, backendReader, errc)
+	if err := <-errc; err != nil {
+		h.getLogs().GetWarn().Str("when", "splice connections").
+			Err(err).Msg("tunnel closed with error")
+	}
+	h.getLogs().GetInfo().Msg("tunnel closed")
+}
+
+// splice copies data from src to dst until
+// one of them is closed
+func splice(dst io.Writer, src io.Reader, errc chan<- error) {
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+	_, err := io.CopyBuffer(dst, src, *buf)
+	errc <- err
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsUpgradeRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{name: "websocket", header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}}, want: true},
+		{name: "upgrade among tokens", header: http.Header{"Connection": {"keep-alive, upgrade"}, "Upgrade": {"h2c"}},
+			want: true},
+		{name: "no upgrade header", header: http.Header{"Connection": {"Upgrade"}}},
+		{name: "no connection token", header: http.Header{"Connection": {"keep-alive"}, "Upgrade": {"websocket"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+			if got := isUpgradeRequest(r); got != tt.want {
+				t.Errorf("isUpgradeRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevHandler_serveUpgrade(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" || r.Header.Get("X-Hop") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(buf, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		buf.Flush()
+		line, err := buf.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(buf, "echo: %s", line)
+		buf.Flush()
+	}))
+	defer backend.Close()
+	proxy := newTestProxy(backend)
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial proxy: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /ws HTTP/1.1\r\nHost: vk.com\r\nConnection: Upgrade, X-Hop\r\nX-Hop: 1\r\nUpgrade: echo\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("unable to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if resp.Header.Get("Upgrade") != "echo" {
+		t.Errorf("Upgrade = %q, want %q", resp.Header.Get("Upgrade"), "echo")
+	}
+
+	fmt.Fprint(conn, "hello\n")
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("unable to read from tunnel: %v", err)
+	}
+	if line != "echo: hello\n" {
+		t.Errorf("tunnel got %q, want %q", line, "echo: hello\n")
+	}
+}
+
+func TestRevHandler_serveUpgradeRefused(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "no upgrade")
+	}))
+	defer backend.Close()
+	proxy := newTestProxy(backend)
+	defer proxy.Close()
+
+	r, err := http.NewRequest(http.MethodGet, proxy.URL+"/ws", nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "echo")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("unable to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unable to read body: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden || string(body) != "no upgrade" {
+		t.Errorf("got %d %q, want %d %q", resp.StatusCode, body, http.StatusForbidden, "no upgrade")
+	}
+}
+
+func TestTunnelRegistry_closeAll(t *testing.T) {
+	registry := &tunnelRegistry{conns: make(map[net.Conn]struct{})}
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	if !registry.add(conn) {
+		t.Fatalf("add() = false before closeAll")
+	}
+	registry.closeAll()
+	if _, err := conn.Write([]byte("x")); err == nil {
+		t.Errorf("connection is open after closeAll")
+	}
+	other, otherPeer := net.Pipe()
+	defer other.Close()
+	defer otherPeer.Close()
+	if registry.add(other) {
+		t.Errorf("add() = true after closeAll")
+	}
+}
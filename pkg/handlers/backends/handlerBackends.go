@@ -5,6 +5,7 @@ import (
 	"github.com/gorilla/mux"
 	"io/ioutil"
 	"net/http"
+	"reverseProxy/pkg/backendManager"
 	"reverseProxy/pkg/formatters"
 	"reverseProxy/pkg/logging"
 	"reverseProxy/pkg/repositories/backends"
@@ -23,6 +24,7 @@ const resourceName = "backends"
 // @Produce json
 // @Param input body models.SwagBackends true "backend info"
 // @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid backend"
 // @Failure 404 {string} string backends.ErrBackendsNotFound
 // @Router /backends [post]
 // Create creates backends data
@@ -46,7 +48,7 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	backend := backends.Backend{}
+	backend := backends.Backend{Weight: backends.DefaultWeight}
 	log.GetInfo().Msg("unmarshal request body")
 	if err := json.Unmarshal(buf, &backend); err != nil {
 		log.GetError().Str("when", "unmarshal request body").
@@ -93,6 +95,18 @@ func Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	backend.Site = &site
+	log.GetInfo().Msg("validate backend")
+	if err := backendManager.ValidateBackend(&backend); err != nil {
+		log.GetWarn().Str("when", "validate backend").
+			Err(err).Msg("invalid backend")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid backend").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
 	log.GetInfo().Msg("create backend")
 	if err := backends.Create(&backend); err != nil {
 		log.GetError().Str("when", "create backend").
@@ -117,6 +131,8 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 
+	log.GetInfo().Msg("hide client key, it is write-only")
+	backend.TLS.ClientKey = ""
 	log.GetInfo().Msg("marshal created backend")
 	bytes, err := json.Marshal(&backend)
 	if err != nil {
@@ -183,6 +199,8 @@ func Read(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 
+	log.GetInfo().Msg("hide client key, it is write-only")
+	backend.TLS.ClientKey = ""
 	log.GetInfo().Msg("marshal read backend")
 	bytes, err := json.Marshal(&backend)
 	if err != nil {
@@ -210,6 +228,7 @@ func Read(w http.ResponseWriter, r *http.Request) {
 // @Param id path integer true "backends ID"
 // @Param input body models.SwagBackends true "backends info"
 // @Success 200 {object} backends.Backend
+// @Failure 400 {string} string "invalid backend"
 // @Failure 404 {string} string backends.ErrBackendsNotFound
 // @Router /backends/{id} [put]
 // Update updates backends data
@@ -226,6 +245,11 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 	backend := backends.Backend{Id: int64(id)}
+	log.GetInfo().Msg("read current backend, omitted fields keep their values")
+	if err := backends.Read(&backend); err != nil {
+		log.GetError().Str("when", "read current backend").
+			Err(err).Msg("unable to read backend")
+	}
 	log.GetInfo().Msg("read request body")
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -248,6 +272,18 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 
+	log.GetInfo().Msg("validate backend")
+	if err := backendManager.ValidateBackend(&backend); err != nil {
+		log.GetWarn().Str("when", "validate backend").
+			Err(err).Msg("invalid backend")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid backend").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
 	log.GetInfo().Msg("update backend")
 	if err := backends.Update(&backend); err != nil {
 		log.GetError().Str("when", "update backend").
@@ -272,6 +308,8 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 
+	log.GetInfo().Msg("hide client key, it is write-only")
+	backend.TLS.ClientKey = ""
 	log.GetInfo().Msg("marshal update backend")
 	bytes, err := json.Marshal(&backend)
 	if err != nil {
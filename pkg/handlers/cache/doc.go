@@ -0,0 +1,3 @@
+// package handlers\cache implements the invalidation
+// of the cache for handlersCache
+package cache
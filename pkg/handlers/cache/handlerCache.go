@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"reverseProxy/pkg/cacheManager"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/sites"
+	"strconv"
+)
+
+const resourceName = "cache"
+
+// getSiteHost returns the host of the site with the id of
+// the request, it sends the error response if the site
+// is not found
+func getSiteHost(w http.ResponseWriter, r *http.Request, log *logging.Logger, operation string) (string, bool) {
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	site := sites.Site{Id: int64(id)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, operation); err != nil {
+			log.GetError().Str("when", "get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return "", false
+	}
+	return site.Host, true
+}
+
+// sendInvalidated sends the number
+// of invalidated responses
+func sendInvalidated(w http.ResponseWriter, log *logging.Logger, invalidated int) {
+	log.GetInfo().Int("invalidated", invalidated).Msg("send response invalidated")
+	if _, err := formatters.WriteJsonOp(w, fmt.Sprintf("{\"invalidated\": %d}", invalidated),
+		resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response invalidated").
+			Err(err).Msg("unable to send response")
+	}
+}
+
+// InvalidateSite godoc
+// @Swagger:operation DELETE /sites/{id}/cache Invalidate site cache
+// @Summary Invalidate all cached responses of the site
+// @Tags Cache
+// @Description invalidate site cache
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Success 200 {object} models.SwagInvalidated
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/cache [delete]
+// InvalidateSite removes all cached responses of the site
+func InvalidateSite(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCache", "invalidateSite")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler InvalidateSite")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	host, ok := getSiteHost(w, r, log, formatters.OpDelete)
+	if !ok {
+		return
+	}
+	log.GetInfo().Str("host", host).Msg("invalidate site")
+	sendInvalidated(w, log, cacheManager.CacheMgr.InvalidateHost(host))
+	log.GetInfo().Msg("exiting handler InvalidateSite")
+}
+
+// InvalidateTag godoc
+// @Swagger:operation DELETE /sites/{id}/cache/tags/{tag} Invalidate tag
+// @Summary Invalidate cached responses of the site with the surrogate key
+// @Tags Cache
+// @Description invalidate tag
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Param tag path string true "surrogate key"
+// @Success 200 {object} models.SwagInvalidated
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/cache/tags/{tag} [delete]
+// InvalidateTag removes the cached responses of
+// the site tagged with the surrogate key
+func InvalidateTag(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCache", "invalidateTag")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler InvalidateTag")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	host, ok := getSiteHost(w, r, log, formatters.OpDelete)
+	if !ok {
+		return
+	}
+	tag := mux.Vars(r)["tag"]
+	log.GetInfo().Str("host", host).Str("tag", tag).Msg("invalidate tag")
+	sendInvalidated(w, log, cacheManager.CacheMgr.InvalidateTag(host, tag))
+	log.GetInfo().Msg("exiting handler InvalidateTag")
+}
+
+// InvalidateURL godoc
+// @Swagger:operation DELETE /sites/{id}/cache/url Invalidate URL
+// @Summary Invalidate cached responses of the site to the URL
+// @Tags Cache
+// @Description invalidate URL, the URL is the path with the query
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Param url query string true "path with the query" example(/page?id=1)
+// @Success 200 {object} models.SwagInvalidated
+// @Failure 400 {string} string "url is required"
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/cache/url [delete]
+// InvalidateURL removes the cached responses
+// of the site to the URL
+func InvalidateURL(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCache", "invalidateURL")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler InvalidateURL")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		log.GetWarn().Str("when", "get url").Msg("url is required")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "get url").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+	host, ok := getSiteHost(w, r, log, formatters.OpDelete)
+	if !ok {
+		return
+	}
+	log.GetInfo().Str("host", host).Str("url", url).Msg("invalidate url")
+	sendInvalidated(w, log, cacheManager.CacheMgr.InvalidateURL(host, url))
+	log.GetInfo().Msg("exiting handler InvalidateURL")
+}
+
+// InvalidatePrefix godoc
+// @Swagger:operation DELETE /sites/{id}/cache/prefix Invalidate prefix
+// @Summary Invalidate cached responses of the site to the paths with the prefix
+// @Tags Cache
+// @Description invalidate prefix
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Param prefix query string true "prefix of the path" example(/static/)
+// @Success 200 {object} models.SwagInvalidated
+// @Failure 400 {string} string "prefix is required"
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/cache/prefix [delete]
+// InvalidatePrefix removes the cached responses of
+// the site to the paths with the prefix
+func InvalidatePrefix(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCache", "invalidatePrefix")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler InvalidatePrefix")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		log.GetWarn().Str("when", "get prefix").Msg("prefix is required")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "get prefix").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+	host, ok := getSiteHost(w, r, log, formatters.OpDelete)
+	if !ok {
+		return
+	}
+	log.GetInfo().Str("host", host).Str("prefix", prefix).Msg("invalidate prefix")
+	sendInvalidated(w, log, cacheManager.CacheMgr.InvalidatePrefix(host, prefix))
+	log.GetInfo().Msg("exiting handler InvalidatePrefix")
+}
+
+// ListTags godoc
+// @Swagger:operation GET /sites/{id}/cache/tags List tags
+// @Summary List surrogate keys of cached responses of the site
+// @Tags Cache
+// @Description list tags with the number of their responses
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Success 200 {object} models.SwagTags
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/cache/tags [get]
+// ListTags returns the surrogate keys of the cached
+// responses of the site
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCache", "listTags")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler ListTags")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	host, ok := getSiteHost(w, r, log, formatters.OpGet)
+	if !ok {
+		return
+	}
+
+	log.GetInfo().Msg("marshal tags")
+	bytes, err := json.Marshal(map[string]map[string]int{"tags": cacheManager.CacheMgr.Tags(host)})
+	if err != nil {
+		log.GetError().Str("when", "marshal tags").
+			Err(err).Msg("unable to marshal tags")
+	}
+
+	log.GetInfo().Msg("send response tags")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response tags").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler ListTags")
+}
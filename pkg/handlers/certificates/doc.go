@@ -0,0 +1,3 @@
+// package handlers\certificates implements CRUD
+// for handlersCertificates
+package certificates
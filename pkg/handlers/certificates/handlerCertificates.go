@@ -0,0 +1,366 @@
+package certificates
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/certificateManager"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/certificates"
+	"reverseProxy/pkg/repositories/sites"
+	"strconv"
+)
+
+const resourceName = "certificates"
+
+// Create godoc
+// @Swagger:operation POST /certificates Create certificates
+// @Summary Create new certificates
+// @Tags Certificates
+// @Description Create certificates
+// @Accept json
+// @Produce json
+// @Param input body models.SwagCertificates true "certificate info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid certificate"
+// @Failure 404 {string} string certificates.ErrCertificateNotFound
+// @Router /certificates [post]
+// Create creates certificates data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCertificate", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	certificate := certificates.Certificate{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &certificate); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	log.GetInfo().Msg("validate certificate")
+	if err := certificateManager.ValidateCertificate(&certificate); err != nil {
+		log.GetWarn().Str("when", "validate certificate").
+			Err(err).Msg("invalid certificate")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid certificate").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	certificate.Site = &site
+	log.GetInfo().Msg("create certificate")
+	if err := certificates.Create(&certificate); err != nil {
+		log.GetError().Str("when", "create certificate").
+			Err(err).Msg("failed to create certificate")
+		if err == certificates.ErrCertificateNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "certificates not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create certificate").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("hide key, it is write-only")
+	certificate.Key = ""
+	log.GetInfo().Msg("marshal created certificate")
+	bytes, err := json.Marshal(&certificate)
+	if err != nil {
+		log.GetError().Str("when", "marshal created certificate").
+			Err(err).Msg("unable marshal created certificate")
+	}
+
+	log.GetInfo().Msg("send response created certificate")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created certificate").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /certificates/{id} Get certificates
+// @Summary Get certificates based on given id
+// @Tags Certificates
+// @Description get certificates
+// @Accept json
+// @Produce json
+// @Param id path integer true "certificates ID"
+// @Success 200 {object} certificates.Certificate
+// @Failure 404 {string} string certificates.ErrCertificateNotFound
+// @Router /certificates/{id} [get]
+// Read reads certificates data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCertificates", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	certificate := certificates.Certificate{Id: int64(id)}
+	log.GetInfo().Msg("start read certificate with specified id")
+	if err := certificates.Read(&certificate); err != nil {
+		log.GetError().Str("when", "read certificate").
+			Err(err).Msg("failed to read certificates")
+		if err == certificates.ErrCertificateNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read certificate").
+					Str("when", "certificates not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read certificate").
+				Str("when", "failed to read certificates").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("hide key, it is write-only")
+	certificate.Key = ""
+	log.GetInfo().Msg("marshal read certificate")
+	bytes, err := json.Marshal(&certificate)
+	if err != nil {
+		log.GetError().Str("when", "marshal read certificate").
+			Err(err).Msg("unable to marshal certificate")
+	}
+
+	log.GetInfo().Msg("send response read certificate")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read certificate").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /certificates/{id} Update certificates
+// @Summary Update certificates based on given id
+// @Tags Certificates
+// @Description update certificates
+// @Accept json
+// @Produce json
+// @Param id path integer true "certificates ID"
+// @Param input body models.SwagCertificates true "certificates info"
+// @Success 200 {object} certificates.Certificate
+// @Failure 400 {string} string "invalid certificate"
+// @Failure 404 {string} string certificates.ErrCertificateNotFound
+// @Router /certificates/{id} [put]
+// Update updates certificates data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCertificate", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	certificate := certificates.Certificate{Id: int64(id)}
+	log.GetInfo().Msg("read current certificate, omitted fields keep their values")
+	if err := certificates.Read(&certificate); err != nil {
+		log.GetError().Str("when", "read current certificate").
+			Err(err).Msg("unable to read certificate")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &certificate); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate certificate")
+	if err := certificateManager.ValidateCertificate(&certificate); err != nil {
+		log.GetWarn().Str("when", "validate certificate").
+			Err(err).Msg("invalid certificate")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid certificate").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update certificate")
+	if err := certificates.Update(&certificate); err != nil {
+		log.GetError().Str("when", "update certificate").
+			Err(err).Msg("failed to update certificate")
+		if err == certificates.ErrCertificateNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update certificate").
+					Str("when", "certificates not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update certificate").
+				Str("when", "failed to update certificate").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("hide key, it is write-only")
+	certificate.Key = ""
+	log.GetInfo().Msg("marshal update certificate")
+	bytes, err := json.Marshal(&certificate)
+	if err != nil {
+		log.GetError().Str("when", "marshal update certificate").
+			Err(err).Msg("unable to marshal certificate")
+	}
+
+	log.GetInfo().Msg("send response with update certificate")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update certificate").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /certificates/{id} Delete certificates
+// @Summary Delete certificates based on given id
+// @Tags Certificates
+// @Description delete certificates
+// @Accept json
+// @Produce json
+// @Param id path integer true "certificates ID"
+// @Success 200 {object} certificates.Certificate
+// @Failure 404 {string} string certificates.ErrCertificateNotFound
+// @Router /certificates/{id} [delete]
+// Delete deletes certificates data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerCertificates", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	certificate := certificates.Certificate{Id: int64(id)}
+	log.GetInfo().Msg("delete certificate with specified id")
+	if err := certificates.Delete(&certificate); err != nil {
+		log.GetError().Str("when", "delete certificate").
+			Err(err).Msg("failed to delete certificate")
+		if err == certificates.ErrCertificateNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete certificate").
+					Str("when", "certificates not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete certificate").
+				Str("when", "failed to delete certificate").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal certificate")
+	bytes, err := json.Marshal(&certificate)
+	if err != nil {
+		log.GetError().Str("when", "marshal certificate").
+			Err(err).Msg("unable to marshal certificate")
+	}
+
+	log.GetInfo().Msg("send response deleted certificate")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted certificate").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
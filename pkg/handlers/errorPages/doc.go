@@ -0,0 +1,3 @@
+// package handlers\errorPages implements CRUD
+// for handlersErrorPages
+package errorPages
@@ -0,0 +1,360 @@
+package errorPages
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/errorPages"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+const resourceName = "errorPages"
+
+// Create godoc
+// @Swagger:operation POST /errorPages Create error pages
+// @Summary Create new error pages
+// @Tags ErrorPages
+// @Description Create error pages
+// @Accept json
+// @Produce json
+// @Param input body models.SwagErrorPages true "error page info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid error page"
+// @Failure 404 {string} string errorPages.ErrErrorPageNotFound
+// @Router /errorPages [post]
+// Create creates error pages data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerErrorPages", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	page := errorPages.ErrorPage{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &page); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	page.Site = &site
+	log.GetInfo().Msg("validate error page")
+	if _, err := siteManager.NewErrorPage(&page); err != nil {
+		log.GetWarn().Str("when", "validate error page").
+			Err(err).Msg("invalid error page")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid error page").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create error page")
+	if err := errorPages.Create(&page); err != nil {
+		log.GetError().Str("when", "create error page").
+			Err(err).Msg("failed to create error page")
+		if err == errorPages.ErrErrorPageNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "error pages not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create error page").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created error page")
+	bytes, err := json.Marshal(&page)
+	if err != nil {
+		log.GetError().Str("when", "marshal created error page").
+			Err(err).Msg("unable marshal created error page")
+	}
+
+	log.GetInfo().Msg("send response created error page")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created error page").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /errorPages/{id} Get error pages
+// @Summary Get error pages based on given id
+// @Tags ErrorPages
+// @Description get error pages
+// @Accept json
+// @Produce json
+// @Param id path integer true "error pages ID"
+// @Success 200 {object} errorPages.ErrorPage
+// @Failure 404 {string} string errorPages.ErrErrorPageNotFound
+// @Router /errorPages/{id} [get]
+// Read reads error pages data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerErrorPages", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	page := errorPages.ErrorPage{Id: int64(id)}
+	log.GetInfo().Msg("start read error page with specified id")
+	if err := errorPages.Read(&page); err != nil {
+		log.GetError().Str("when", "read error page").
+			Err(err).Msg("failed to read error pages")
+		if err == errorPages.ErrErrorPageNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read error page").
+					Str("when", "error pages not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read error page").
+				Str("when", "failed to read error pages").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read error page")
+	bytes, err := json.Marshal(&page)
+	if err != nil {
+		log.GetError().Str("when", "marshal read error page").
+			Err(err).Msg("unable to marshal error page")
+	}
+
+	log.GetInfo().Msg("send response read error page")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read error page").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /errorPages/{id} Update error pages
+// @Summary Update error pages based on given id
+// @Tags ErrorPages
+// @Description update error pages
+// @Accept json
+// @Produce json
+// @Param id path integer true "error pages ID"
+// @Param input body models.SwagErrorPages true "error pages info"
+// @Success 200 {object} errorPages.ErrorPage
+// @Failure 400 {string} string "invalid error page"
+// @Failure 404 {string} string errorPages.ErrErrorPageNotFound
+// @Router /errorPages/{id} [put]
+// Update updates error pages data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerErrorPages", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	page := errorPages.ErrorPage{Id: int64(id)}
+	log.GetInfo().Msg("read current error page, omitted fields keep their values")
+	if err := errorPages.Read(&page); err != nil {
+		log.GetError().Str("when", "read current error page").
+			Err(err).Msg("unable to read error page")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &page); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate error page")
+	if _, err := siteManager.NewErrorPage(&page); err != nil {
+		log.GetWarn().Str("when", "validate error page").
+			Err(err).Msg("invalid error page")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid error page").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update error page")
+	if err := errorPages.Update(&page); err != nil {
+		log.GetError().Str("when", "update error page").
+			Err(err).Msg("failed to update error page")
+		if err == errorPages.ErrErrorPageNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update error page").
+					Str("when", "error pages not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update error page").
+				Str("when", "failed to update error page").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update error page")
+	bytes, err := json.Marshal(&page)
+	if err != nil {
+		log.GetError().Str("when", "marshal update error page").
+			Err(err).Msg("unable to marshal error page")
+	}
+
+	log.GetInfo().Msg("send response with update error page")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update error page").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /errorPages/{id} Delete error pages
+// @Summary Delete error pages based on given id
+// @Tags ErrorPages
+// @Description delete error pages
+// @Accept json
+// @Produce json
+// @Param id path integer true "error pages ID"
+// @Success 200 {object} errorPages.ErrorPage
+// @Failure 404 {string} string errorPages.ErrErrorPageNotFound
+// @Router /errorPages/{id} [delete]
+// Delete deletes error pages data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerErrorPages", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	page := errorPages.ErrorPage{Id: int64(id)}
+	log.GetInfo().Msg("delete error page with specified id")
+	if err := errorPages.Delete(&page); err != nil {
+		log.GetError().Str("when", "delete error page").
+			Err(err).Msg("failed to delete error page")
+		if err == errorPages.ErrErrorPageNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete error page").
+					Str("when", "error pages not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete error page").
+				Str("when", "failed to delete error page").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal error page")
+	bytes, err := json.Marshal(&page)
+	if err != nil {
+		log.GetError().Str("when", "marshal error page").
+			Err(err).Msg("unable to marshal error page")
+	}
+
+	log.GetInfo().Msg("send response deleted error page")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted error page").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
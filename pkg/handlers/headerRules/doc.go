@@ -0,0 +1,3 @@
+// package handlers\headerRules implements CRUD
+// for handlersHeaderRules
+package headerRules
@@ -0,0 +1,360 @@
+package headerRules
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/headerRules"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+const resourceName = "headerRules"
+
+// Create godoc
+// @Swagger:operation POST /headerRules Create header rules
+// @Summary Create new header rules
+// @Tags HeaderRules
+// @Description Create header rules
+// @Accept json
+// @Produce json
+// @Param input body models.SwagHeaderRules true "header rule info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid header rule"
+// @Failure 404 {string} string headerRules.ErrHeaderRuleNotFound
+// @Router /headerRules [post]
+// Create creates header rules data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerHeaderRule", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	rule := headerRules.HeaderRule{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &rule); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	rule.Site = &site
+	log.GetInfo().Msg("validate header rule")
+	if err := siteManager.ValidateHeaderRule(&rule); err != nil {
+		log.GetWarn().Str("when", "validate header rule").
+			Err(err).Msg("invalid header rule")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid header rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create header rule")
+	if err := headerRules.Create(&rule); err != nil {
+		log.GetError().Str("when", "create header rule").
+			Err(err).Msg("failed to create header rule")
+		if err == headerRules.ErrHeaderRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "header rules not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create header rule").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created header rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal created header rule").
+			Err(err).Msg("unable marshal created header rule")
+	}
+
+	log.GetInfo().Msg("send response created header rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created header rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /headerRules/{id} Get header rules
+// @Summary Get header rules based on given id
+// @Tags HeaderRules
+// @Description get header rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "header rules ID"
+// @Success 200 {object} headerRules.HeaderRule
+// @Failure 404 {string} string headerRules.ErrHeaderRuleNotFound
+// @Router /headerRules/{id} [get]
+// Read reads header rules data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerHeaderRules", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	rule := headerRules.HeaderRule{Id: int64(id)}
+	log.GetInfo().Msg("start read header rule with specified id")
+	if err := headerRules.Read(&rule); err != nil {
+		log.GetError().Str("when", "read header rule").
+			Err(err).Msg("failed to read header rules")
+		if err == headerRules.ErrHeaderRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read header rule").
+					Str("when", "header rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read header rule").
+				Str("when", "failed to read header rules").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read header rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal read header rule").
+			Err(err).Msg("unable to marshal header rule")
+	}
+
+	log.GetInfo().Msg("send response read header rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read header rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /headerRules/{id} Update header rules
+// @Summary Update header rules based on given id
+// @Tags HeaderRules
+// @Description update header rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "header rules ID"
+// @Param input body models.SwagHeaderRules true "header rules info"
+// @Success 200 {object} headerRules.HeaderRule
+// @Failure 400 {string} string "invalid header rule"
+// @Failure 404 {string} string headerRules.ErrHeaderRuleNotFound
+// @Router /headerRules/{id} [put]
+// Update updates header rules data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerHeaderRule", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	rule := headerRules.HeaderRule{Id: int64(id)}
+	log.GetInfo().Msg("read current header rule, omitted fields keep their values")
+	if err := headerRules.Read(&rule); err != nil {
+		log.GetError().Str("when", "read current header rule").
+			Err(err).Msg("unable to read header rule")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &rule); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate header rule")
+	if err := siteManager.ValidateHeaderRule(&rule); err != nil {
+		log.GetWarn().Str("when", "validate header rule").
+			Err(err).Msg("invalid header rule")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid header rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update header rule")
+	if err := headerRules.Update(&rule); err != nil {
+		log.GetError().Str("when", "update header rule").
+			Err(err).Msg("failed to update header rule")
+		if err == headerRules.ErrHeaderRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update header rule").
+					Str("when", "header rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update header rule").
+				Str("when", "failed to update header rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update header rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal update header rule").
+			Err(err).Msg("unable to marshal header rule")
+	}
+
+	log.GetInfo().Msg("send response with update header rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update header rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /headerRules/{id} Delete header rules
+// @Summary Delete header rules based on given id
+// @Tags HeaderRules
+// @Description delete header rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "header rules ID"
+// @Success 200 {object} headerRules.HeaderRule
+// @Failure 404 {string} string headerRules.ErrHeaderRuleNotFound
+// @Router /headerRules/{id} [delete]
+// Delete deletes header rules data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerHeaderRules", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	rule := headerRules.HeaderRule{Id: int64(id)}
+	log.GetInfo().Msg("delete header rule with specified id")
+	if err := headerRules.Delete(&rule); err != nil {
+		log.GetError().Str("when", "delete header rule").
+			Err(err).Msg("failed to delete header rule")
+		if err == headerRules.ErrHeaderRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete header rule").
+					Str("when", "header rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete header rule").
+				Str("when", "failed to delete header rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal header rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal header rule").
+			Err(err).Msg("unable to marshal header rule")
+	}
+
+	log.GetInfo().Msg("send response deleted header rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted header rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
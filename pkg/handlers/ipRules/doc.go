@@ -0,0 +1,3 @@
+// package handlers\ipRules implements CRUD
+// for handlersIPRules
+package ipRules
@@ -0,0 +1,360 @@
+package ipRules
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/ipRules"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+const resourceName = "ipRules"
+
+// Create godoc
+// @Swagger:operation POST /ipRules Create ip rules
+// @Summary Create new ip rules
+// @Tags IPRules
+// @Description Create ip rules
+// @Accept json
+// @Produce json
+// @Param input body models.SwagIPRules true "ip rule info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid ip rule"
+// @Failure 404 {string} string ipRules.ErrIPRuleNotFound
+// @Router /ipRules [post]
+// Create creates ip rules data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerIPRules", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	rule := ipRules.IPRule{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &rule); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	rule.Site = &site
+	log.GetInfo().Msg("validate ip rule")
+	if err := siteManager.ValidateIPRule(&rule); err != nil {
+		log.GetWarn().Str("when", "validate ip rule").
+			Err(err).Msg("invalid ip rule")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid ip rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create ip rule")
+	if err := ipRules.Create(&rule); err != nil {
+		log.GetError().Str("when", "create ip rule").
+			Err(err).Msg("failed to create ip rule")
+		if err == ipRules.ErrIPRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "ip rules not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create ip rule").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created ip rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal created ip rule").
+			Err(err).Msg("unable marshal created ip rule")
+	}
+
+	log.GetInfo().Msg("send response created ip rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created ip rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /ipRules/{id} Get ip rules
+// @Summary Get ip rules based on given id
+// @Tags IPRules
+// @Description get ip rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "ip rules ID"
+// @Success 200 {object} ipRules.IPRule
+// @Failure 404 {string} string ipRules.ErrIPRuleNotFound
+// @Router /ipRules/{id} [get]
+// Read reads ip rules data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerIPRules", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	rule := ipRules.IPRule{Id: int64(id)}
+	log.GetInfo().Msg("start read ip rule with specified id")
+	if err := ipRules.Read(&rule); err != nil {
+		log.GetError().Str("when", "read ip rule").
+			Err(err).Msg("failed to read ip rules")
+		if err == ipRules.ErrIPRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read ip rule").
+					Str("when", "ip rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read ip rule").
+				Str("when", "failed to read ip rules").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read ip rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal read ip rule").
+			Err(err).Msg("unable to marshal ip rule")
+	}
+
+	log.GetInfo().Msg("send response read ip rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read ip rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /ipRules/{id} Update ip rules
+// @Summary Update ip rules based on given id
+// @Tags IPRules
+// @Description update ip rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "ip rules ID"
+// @Param input body models.SwagIPRules true "ip rules info"
+// @Success 200 {object} ipRules.IPRule
+// @Failure 400 {string} string "invalid ip rule"
+// @Failure 404 {string} string ipRules.ErrIPRuleNotFound
+// @Router /ipRules/{id} [put]
+// Update updates ip rules data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerIPRules", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	rule := ipRules.IPRule{Id: int64(id)}
+	log.GetInfo().Msg("read current ip rule, omitted fields keep their values")
+	if err := ipRules.Read(&rule); err != nil {
+		log.GetError().Str("when", "read current ip rule").
+			Err(err).Msg("unable to read ip rule")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &rule); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate ip rule")
+	if err := siteManager.ValidateIPRule(&rule); err != nil {
+		log.GetWarn().Str("when", "validate ip rule").
+			Err(err).Msg("invalid ip rule")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid ip rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update ip rule")
+	if err := ipRules.Update(&rule); err != nil {
+		log.GetError().Str("when", "update ip rule").
+			Err(err).Msg("failed to update ip rule")
+		if err == ipRules.ErrIPRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update ip rule").
+					Str("when", "ip rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update ip rule").
+				Str("when", "failed to update ip rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update ip rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal update ip rule").
+			Err(err).Msg("unable to marshal ip rule")
+	}
+
+	log.GetInfo().Msg("send response with update ip rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update ip rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /ipRules/{id} Delete ip rules
+// @Summary Delete ip rules based on given id
+// @Tags IPRules
+// @Description delete ip rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "ip rules ID"
+// @Success 200 {object} ipRules.IPRule
+// @Failure 404 {string} string ipRules.ErrIPRuleNotFound
+// @Router /ipRules/{id} [delete]
+// Delete deletes ip rules data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerIPRules", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	rule := ipRules.IPRule{Id: int64(id)}
+	log.GetInfo().Msg("delete ip rule with specified id")
+	if err := ipRules.Delete(&rule); err != nil {
+		log.GetError().Str("when", "delete ip rule").
+			Err(err).Msg("failed to delete ip rule")
+		if err == ipRules.ErrIPRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete ip rule").
+					Str("when", "ip rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete ip rule").
+				Str("when", "failed to delete ip rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal ip rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal ip rule").
+			Err(err).Msg("unable to marshal ip rule")
+	}
+
+	log.GetInfo().Msg("send response deleted ip rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted ip rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
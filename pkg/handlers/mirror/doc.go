@@ -0,0 +1,3 @@
+// package handlers\mirror implements the counters and
+// the diffs of the shadow requests for handlersMirror
+package mirror
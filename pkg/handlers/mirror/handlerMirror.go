@@ -0,0 +1,119 @@
+package mirror
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/mirrorManager"
+	"reverseProxy/pkg/repositories/sites"
+	"strconv"
+)
+
+const resourceName = "mirror"
+
+// getSiteHost returns the host of the site with the id of
+// the request, it sends the error response if the site
+// is not found
+func getSiteHost(w http.ResponseWriter, r *http.Request, log *logging.Logger) (string, bool) {
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	site := sites.Site{Id: int64(id)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return "", false
+	}
+	return site.Host, true
+}
+
+// GetCounters godoc
+// @Swagger:operation GET /sites/{id}/mirror Get mirror counters
+// @Summary Get counters of shadow requests of the site
+// @Tags Mirror
+// @Description get the numbers of mirrored, dropped and failed shadow requests
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Success 200 {object} models.SwagMirror
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/mirror [get]
+// GetCounters returns the counters of the shadow
+// requests of the site since the start
+func GetCounters(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerMirror", "getCounters")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler GetCounters")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	host, ok := getSiteHost(w, r, log)
+	if !ok {
+		return
+	}
+
+	log.GetInfo().Msg("marshal counters")
+	bytes, err := json.Marshal(mirrorManager.MirrorMgr.Counters(host))
+	if err != nil {
+		log.GetError().Str("when", "marshal counters").
+			Err(err).Msg("unable to marshal counters")
+	}
+
+	log.GetInfo().Msg("send response counters")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response counters").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler GetCounters")
+}
+
+// GetDiffs godoc
+// @Swagger:operation GET /sites/{id}/mirror/diffs Get mirror diffs
+// @Summary Get mismatch rates of shadow responses per route of the site
+// @Tags Mirror
+// @Description get the numbers of compared, mismatched and skipped shadow responses per route
+// @Accept json
+// @Produce json
+// @Param id path integer true "sites ID"
+// @Success 200 {object} models.SwagMirrorDiffs
+// @Failure 404 {string} string sites.ErrSiteNotFound
+// @Router /sites/{id}/mirror/diffs [get]
+// GetDiffs returns the comparisons of the responses
+// of the shadow backends per route of the site
+func GetDiffs(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerMirror", "getDiffs")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler GetDiffs")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	host, ok := getSiteHost(w, r, log)
+	if !ok {
+		return
+	}
+
+	log.GetInfo().Msg("marshal diffs")
+	bytes, err := json.Marshal(map[string][]mirrorManager.RouteDiffs{"routes": mirrorManager.MirrorMgr.Diffs(host)})
+	if err != nil {
+		log.GetError().Str("when", "marshal diffs").
+			Err(err).Msg("unable to marshal diffs")
+	}
+
+	log.GetInfo().Msg("send response diffs")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response diffs").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler GetDiffs")
+}
@@ -0,0 +1,3 @@
+// package handlers\rateLimits implements CRUD
+// for handlersRateLimits
+package rateLimits
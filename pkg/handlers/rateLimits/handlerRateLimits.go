@@ -0,0 +1,360 @@
+package rateLimits
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/rateLimits"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+const resourceName = "rateLimits"
+
+// Create godoc
+// @Swagger:operation POST /rateLimits Create rate limits
+// @Summary Create new rate limits
+// @Tags RateLimits
+// @Description Create rate limits
+// @Accept json
+// @Produce json
+// @Param input body models.SwagRateLimits true "rate limit info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid rate limit"
+// @Failure 404 {string} string rateLimits.ErrRateLimitNotFound
+// @Router /rateLimits [post]
+// Create creates rate limits data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRateLimits", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	limit := rateLimits.RateLimit{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &limit); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	limit.Site = &site
+	log.GetInfo().Msg("validate rate limit")
+	if err := siteManager.ValidateRateLimit(&limit); err != nil {
+		log.GetWarn().Str("when", "validate rate limit").
+			Err(err).Msg("invalid rate limit")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid rate limit").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create rate limit")
+	if err := rateLimits.Create(&limit); err != nil {
+		log.GetError().Str("when", "create rate limit").
+			Err(err).Msg("failed to create rate limit")
+		if err == rateLimits.ErrRateLimitNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "rate limits not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create rate limit").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created rate limit")
+	bytes, err := json.Marshal(&limit)
+	if err != nil {
+		log.GetError().Str("when", "marshal created rate limit").
+			Err(err).Msg("unable marshal created rate limit")
+	}
+
+	log.GetInfo().Msg("send response created rate limit")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created rate limit").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /rateLimits/{id} Get rate limits
+// @Summary Get rate limits based on given id
+// @Tags RateLimits
+// @Description get rate limits
+// @Accept json
+// @Produce json
+// @Param id path integer true "rate limits ID"
+// @Success 200 {object} rateLimits.RateLimit
+// @Failure 404 {string} string rateLimits.ErrRateLimitNotFound
+// @Router /rateLimits/{id} [get]
+// Read reads rate limits data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRateLimits", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	limit := rateLimits.RateLimit{Id: int64(id)}
+	log.GetInfo().Msg("start read rate limit with specified id")
+	if err := rateLimits.Read(&limit); err != nil {
+		log.GetError().Str("when", "read rate limit").
+			Err(err).Msg("failed to read rate limits")
+		if err == rateLimits.ErrRateLimitNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read rate limit").
+					Str("when", "rate limits not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read rate limit").
+				Str("when", "failed to read rate limits").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read rate limit")
+	bytes, err := json.Marshal(&limit)
+	if err != nil {
+		log.GetError().Str("when", "marshal read rate limit").
+			Err(err).Msg("unable to marshal rate limit")
+	}
+
+	log.GetInfo().Msg("send response read rate limit")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read rate limit").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /rateLimits/{id} Update rate limits
+// @Summary Update rate limits based on given id
+// @Tags RateLimits
+// @Description update rate limits
+// @Accept json
+// @Produce json
+// @Param id path integer true "rate limits ID"
+// @Param input body models.SwagRateLimits true "rate limits info"
+// @Success 200 {object} rateLimits.RateLimit
+// @Failure 400 {string} string "invalid rate limit"
+// @Failure 404 {string} string rateLimits.ErrRateLimitNotFound
+// @Router /rateLimits/{id} [put]
+// Update updates rate limits data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRateLimits", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	limit := rateLimits.RateLimit{Id: int64(id)}
+	log.GetInfo().Msg("read current rate limit, omitted fields keep their values")
+	if err := rateLimits.Read(&limit); err != nil {
+		log.GetError().Str("when", "read current rate limit").
+			Err(err).Msg("unable to read rate limit")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &limit); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate rate limit")
+	if err := siteManager.ValidateRateLimit(&limit); err != nil {
+		log.GetWarn().Str("when", "validate rate limit").
+			Err(err).Msg("invalid rate limit")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid rate limit").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update rate limit")
+	if err := rateLimits.Update(&limit); err != nil {
+		log.GetError().Str("when", "update rate limit").
+			Err(err).Msg("failed to update rate limit")
+		if err == rateLimits.ErrRateLimitNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update rate limit").
+					Str("when", "rate limits not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update rate limit").
+				Str("when", "failed to update rate limit").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update rate limit")
+	bytes, err := json.Marshal(&limit)
+	if err != nil {
+		log.GetError().Str("when", "marshal update rate limit").
+			Err(err).Msg("unable to marshal rate limit")
+	}
+
+	log.GetInfo().Msg("send response with update rate limit")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update rate limit").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /rateLimits/{id} Delete rate limits
+// @Summary Delete rate limits based on given id
+// @Tags RateLimits
+// @Description delete rate limits
+// @Accept json
+// @Produce json
+// @Param id path integer true "rate limits ID"
+// @Success 200 {object} rateLimits.RateLimit
+// @Failure 404 {string} string rateLimits.ErrRateLimitNotFound
+// @Router /rateLimits/{id} [delete]
+// Delete deletes rate limits data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRateLimits", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	limit := rateLimits.RateLimit{Id: int64(id)}
+	log.GetInfo().Msg("delete rate limit with specified id")
+	if err := rateLimits.Delete(&limit); err != nil {
+		log.GetError().Str("when", "delete rate limit").
+			Err(err).Msg("failed to delete rate limit")
+		if err == rateLimits.ErrRateLimitNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete rate limit").
+					Str("when", "rate limits not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete rate limit").
+				Str("when", "failed to delete rate limit").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal rate limit")
+	bytes, err := json.Marshal(&limit)
+	if err != nil {
+		log.GetError().Str("when", "marshal rate limit").
+			Err(err).Msg("unable to marshal rate limit")
+	}
+
+	log.GetInfo().Msg("send response deleted rate limit")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted rate limit").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
@@ -0,0 +1,3 @@
+// package handlers\redirectRules implements CRUD
+// for handlersRedirectRules
+package redirectRules
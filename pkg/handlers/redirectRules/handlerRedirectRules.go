@@ -0,0 +1,360 @@
+package redirectRules
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/redirectRules"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+const resourceName = "redirectRules"
+
+// Create godoc
+// @Swagger:operation POST /redirectRules Create redirect rules
+// @Summary Create new redirect rules
+// @Tags RedirectRules
+// @Description Create redirect rules
+// @Accept json
+// @Produce json
+// @Param input body models.SwagRedirectRules true "redirect rule info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid redirect rule"
+// @Failure 404 {string} string redirectRules.ErrRedirectRuleNotFound
+// @Router /redirectRules [post]
+// Create creates redirect rules data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRedirectRules", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	rule := redirectRules.RedirectRule{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &rule); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	rule.Site = &site
+	log.GetInfo().Msg("validate redirect rule")
+	if _, err := siteManager.NewRedirect(&rule); err != nil {
+		log.GetWarn().Str("when", "validate redirect rule").
+			Err(err).Msg("invalid redirect rule")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid redirect rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create redirect rule")
+	if err := redirectRules.Create(&rule); err != nil {
+		log.GetError().Str("when", "create redirect rule").
+			Err(err).Msg("failed to create redirect rule")
+		if err == redirectRules.ErrRedirectRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "redirect rules not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create redirect rule").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created redirect rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal created redirect rule").
+			Err(err).Msg("unable marshal created redirect rule")
+	}
+
+	log.GetInfo().Msg("send response created redirect rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created redirect rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /redirectRules/{id} Get redirect rules
+// @Summary Get redirect rules based on given id
+// @Tags RedirectRules
+// @Description get redirect rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "redirect rules ID"
+// @Success 200 {object} redirectRules.RedirectRule
+// @Failure 404 {string} string redirectRules.ErrRedirectRuleNotFound
+// @Router /redirectRules/{id} [get]
+// Read reads redirect rules data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRedirectRules", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	rule := redirectRules.RedirectRule{Id: int64(id)}
+	log.GetInfo().Msg("start read redirect rule with specified id")
+	if err := redirectRules.Read(&rule); err != nil {
+		log.GetError().Str("when", "read redirect rule").
+			Err(err).Msg("failed to read redirect rules")
+		if err == redirectRules.ErrRedirectRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read redirect rule").
+					Str("when", "redirect rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read redirect rule").
+				Str("when", "failed to read redirect rules").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read redirect rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal read redirect rule").
+			Err(err).Msg("unable to marshal redirect rule")
+	}
+
+	log.GetInfo().Msg("send response read redirect rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read redirect rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /redirectRules/{id} Update redirect rules
+// @Summary Update redirect rules based on given id
+// @Tags RedirectRules
+// @Description update redirect rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "redirect rules ID"
+// @Param input body models.SwagRedirectRules true "redirect rules info"
+// @Success 200 {object} redirectRules.RedirectRule
+// @Failure 400 {string} string "invalid redirect rule"
+// @Failure 404 {string} string redirectRules.ErrRedirectRuleNotFound
+// @Router /redirectRules/{id} [put]
+// Update updates redirect rules data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRedirectRules", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	rule := redirectRules.RedirectRule{Id: int64(id)}
+	log.GetInfo().Msg("read current redirect rule, omitted fields keep their values")
+	if err := redirectRules.Read(&rule); err != nil {
+		log.GetError().Str("when", "read current redirect rule").
+			Err(err).Msg("unable to read redirect rule")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &rule); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate redirect rule")
+	if _, err := siteManager.NewRedirect(&rule); err != nil {
+		log.GetWarn().Str("when", "validate redirect rule").
+			Err(err).Msg("invalid redirect rule")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid redirect rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update redirect rule")
+	if err := redirectRules.Update(&rule); err != nil {
+		log.GetError().Str("when", "update redirect rule").
+			Err(err).Msg("failed to update redirect rule")
+		if err == redirectRules.ErrRedirectRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update redirect rule").
+					Str("when", "redirect rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update redirect rule").
+				Str("when", "failed to update redirect rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update redirect rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal update redirect rule").
+			Err(err).Msg("unable to marshal redirect rule")
+	}
+
+	log.GetInfo().Msg("send response with update redirect rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update redirect rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /redirectRules/{id} Delete redirect rules
+// @Summary Delete redirect rules based on given id
+// @Tags RedirectRules
+// @Description delete redirect rules
+// @Accept json
+// @Produce json
+// @Param id path integer true "redirect rules ID"
+// @Success 200 {object} redirectRules.RedirectRule
+// @Failure 404 {string} string redirectRules.ErrRedirectRuleNotFound
+// @Router /redirectRules/{id} [delete]
+// Delete deletes redirect rules data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRedirectRules", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	rule := redirectRules.RedirectRule{Id: int64(id)}
+	log.GetInfo().Msg("delete redirect rule with specified id")
+	if err := redirectRules.Delete(&rule); err != nil {
+		log.GetError().Str("when", "delete redirect rule").
+			Err(err).Msg("failed to delete redirect rule")
+		if err == redirectRules.ErrRedirectRuleNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete redirect rule").
+					Str("when", "redirect rules not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete redirect rule").
+				Str("when", "failed to delete redirect rule").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal redirect rule")
+	bytes, err := json.Marshal(&rule)
+	if err != nil {
+		log.GetError().Str("when", "marshal redirect rule").
+			Err(err).Msg("unable to marshal redirect rule")
+	}
+
+	log.GetInfo().Msg("send response deleted redirect rule")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted redirect rule").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
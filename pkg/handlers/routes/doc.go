@@ -0,0 +1,3 @@
+// package handlers\routes implements CRUD
+// for handlersRoutes
+package routes
@@ -0,0 +1,360 @@
+package routes
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/routes"
+	"reverseProxy/pkg/repositories/sites"
+	"strconv"
+)
+
+const resourceName = "routes"
+
+// Create godoc
+// @Swagger:operation POST /routes Create routes
+// @Summary Create new routes
+// @Tags Routes
+// @Description Create routes
+// @Accept json
+// @Produce json
+// @Param input body models.SwagRoutes true "route info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid route"
+// @Failure 404 {string} string routes.ErrRouteNotFound
+// @Router /routes [post]
+// Create creates routes data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRoute", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	route := routes.Route{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &route); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	route.Site = &site
+	log.GetInfo().Msg("validate route")
+	if _, err := backendManager.NewRoute(&route); err != nil {
+		log.GetWarn().Str("when", "validate route").
+			Err(err).Msg("invalid route")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid route").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create route")
+	if err := routes.Create(&route); err != nil {
+		log.GetError().Str("when", "create route").
+			Err(err).Msg("failed to create route")
+		if err == routes.ErrRouteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "routes not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create route").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created route")
+	bytes, err := json.Marshal(&route)
+	if err != nil {
+		log.GetError().Str("when", "marshal created route").
+			Err(err).Msg("unable marshal created route")
+	}
+
+	log.GetInfo().Msg("send response created route")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created route").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /routes/{id} Get routes
+// @Summary Get routes based on given id
+// @Tags Routes
+// @Description get routes
+// @Accept json
+// @Produce json
+// @Param id path integer true "routes ID"
+// @Success 200 {object} routes.Route
+// @Failure 404 {string} string routes.ErrRouteNotFound
+// @Router /routes/{id} [get]
+// Read reads routes data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRoutes", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	route := routes.Route{Id: int64(id)}
+	log.GetInfo().Msg("start read route with specified id")
+	if err := routes.Read(&route); err != nil {
+		log.GetError().Str("when", "read route").
+			Err(err).Msg("failed to read routes")
+		if err == routes.ErrRouteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read route").
+					Str("when", "routes not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read route").
+				Str("when", "failed to read routes").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read route")
+	bytes, err := json.Marshal(&route)
+	if err != nil {
+		log.GetError().Str("when", "marshal read route").
+			Err(err).Msg("unable to marshal route")
+	}
+
+	log.GetInfo().Msg("send response read route")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read route").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /routes/{id} Update routes
+// @Summary Update routes based on given id
+// @Tags Routes
+// @Description update routes
+// @Accept json
+// @Produce json
+// @Param id path integer true "routes ID"
+// @Param input body models.SwagRoutes true "routes info"
+// @Success 200 {object} routes.Route
+// @Failure 400 {string} string "invalid route"
+// @Failure 404 {string} string routes.ErrRouteNotFound
+// @Router /routes/{id} [put]
+// Update updates routes data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRoute", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	route := routes.Route{Id: int64(id)}
+	log.GetInfo().Msg("read current route, omitted fields keep their values")
+	if err := routes.Read(&route); err != nil {
+		log.GetError().Str("when", "read current route").
+			Err(err).Msg("unable to read route")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &route); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate route")
+	if _, err := backendManager.NewRoute(&route); err != nil {
+		log.GetWarn().Str("when", "validate route").
+			Err(err).Msg("invalid route")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid route").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update route")
+	if err := routes.Update(&route); err != nil {
+		log.GetError().Str("when", "update route").
+			Err(err).Msg("failed to update route")
+		if err == routes.ErrRouteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update route").
+					Str("when", "routes not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update route").
+				Str("when", "failed to update route").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update route")
+	bytes, err := json.Marshal(&route)
+	if err != nil {
+		log.GetError().Str("when", "marshal update route").
+			Err(err).Msg("unable to marshal route")
+	}
+
+	log.GetInfo().Msg("send response with update route")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update route").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /routes/{id} Delete routes
+// @Summary Delete routes based on given id
+// @Tags Routes
+// @Description delete routes
+// @Accept json
+// @Produce json
+// @Param id path integer true "routes ID"
+// @Success 200 {object} routes.Route
+// @Failure 404 {string} string routes.ErrRouteNotFound
+// @Router /routes/{id} [delete]
+// Delete deletes routes data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerRoutes", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	route := routes.Route{Id: int64(id)}
+	log.GetInfo().Msg("delete route with specified id")
+	if err := routes.Delete(&route); err != nil {
+		log.GetError().Str("when", "delete route").
+			Err(err).Msg("failed to delete route")
+		if err == routes.ErrRouteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete route").
+					Str("when", "routes not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete route").
+				Str("when", "failed to delete route").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal route")
+	bytes, err := json.Marshal(&route)
+	if err != nil {
+		log.GetError().Str("when", "marshal route").
+			Err(err).Msg("unable to marshal route")
+	}
+
+	log.GetInfo().Msg("send response deleted route")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted route").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
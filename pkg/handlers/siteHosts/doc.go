@@ -0,0 +1,3 @@
+// package handlers\siteHosts implements CRUD
+// for handlersSiteHosts
+package siteHosts
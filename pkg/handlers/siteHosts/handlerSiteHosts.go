@@ -0,0 +1,360 @@
+package siteHosts
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"reverseProxy/pkg/formatters"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/siteHosts"
+	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
+	"strconv"
+)
+
+const resourceName = "siteHosts"
+
+// Create godoc
+// @Swagger:operation POST /siteHosts Create site hosts
+// @Summary Create new site hosts
+// @Tags SiteHosts
+// @Description Create site hosts
+// @Accept json
+// @Produce json
+// @Param input body models.SwagSiteHosts true "site host info"
+// @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid site host"
+// @Failure 404 {string} string siteHosts.ErrSiteHostNotFound
+// @Router /siteHosts [post]
+// Create creates site hosts data
+func Create(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerSiteHosts", "create")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Create")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	host := siteHosts.SiteHost{}
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &host); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal request body")
+	}
+
+	requestRawParams := make(map[string]json.RawMessage)
+	log.GetInfo().Msg("unmarshal request raw params to get site_id")
+	if err := json.Unmarshal(buf, &requestRawParams); err != nil {
+		log.GetError().Str("when", "unmarshal request raw params").
+			Err(err).Msg("unable to unmarshal request raw params")
+	}
+
+	log.GetInfo().Msg("convert site_id to integer")
+	siteId, err := strconv.Atoi(string(requestRawParams["site_id"]))
+	if err != nil {
+		log.GetError().Str("when", "convert site_id to integer").
+			Err(err).Msg("unable to convert site_id")
+	}
+
+	site := sites.Site{Id: int64(siteId)}
+	log.GetInfo().Msg("get site with specified id")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "get site").Err(err).Msg("unable to get site")
+		if err == sites.ErrSiteNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "unable to get site").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	host.Site = &site
+	log.GetInfo().Msg("validate site host")
+	if err := siteManager.ValidateHostPattern(host.Pattern); err != nil {
+		log.GetWarn().Str("when", "validate site host").
+			Err(err).Msg("invalid site host")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "invalid site host").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("create site host")
+	if err := siteHosts.Create(&host); err != nil {
+		log.GetError().Str("when", "create site host").
+			Err(err).Msg("failed to create site host")
+		if err == siteHosts.ErrSiteHostNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+				log.GetError().Str("when", "site hosts not found").
+					Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpCreate); err != nil {
+			log.GetError().Str("when", "failed to create site host").
+				Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal created site host")
+	bytes, err := json.Marshal(&host)
+	if err != nil {
+		log.GetError().Str("when", "marshal created site host").
+			Err(err).Msg("unable marshal created site host")
+	}
+
+	log.GetInfo().Msg("send response created site host")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpCreate); err != nil {
+		log.GetError().Str("when", "send response created site host").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Create")
+}
+
+// Read godoc
+// @Swagger:operation GET /siteHosts/{id} Get site hosts
+// @Summary Get site hosts based on given id
+// @Tags SiteHosts
+// @Description get site hosts
+// @Accept json
+// @Produce json
+// @Param id path integer true "site hosts ID"
+// @Success 200 {object} siteHosts.SiteHost
+// @Failure 404 {string} string siteHosts.ErrSiteHostNotFound
+// @Router /siteHosts/{id} [get]
+// Read reads site hosts data
+func Read(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerSiteHosts", "read")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Read")
+
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed get and convert id")
+	}
+	host := siteHosts.SiteHost{Id: int64(id)}
+	log.GetInfo().Msg("start read site host with specified id")
+	if err := siteHosts.Read(&host); err != nil {
+		log.GetError().Str("when", "read site host").
+			Err(err).Msg("failed to read site hosts")
+		if err == siteHosts.ErrSiteHostNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+				log.GetError().Str("when", "read site host").
+					Str("when", "site hosts not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpGet); err != nil {
+			log.GetError().Str("when", "read site host").
+				Str("when", "failed to read site hosts").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal read site host")
+	bytes, err := json.Marshal(&host)
+	if err != nil {
+		log.GetError().Str("when", "marshal read site host").
+			Err(err).Msg("unable to marshal site host")
+	}
+
+	log.GetInfo().Msg("send response read site host")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpGet); err != nil {
+		log.GetError().Str("when", "send response read site host").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Read")
+}
+
+// Update godoc
+// @Swagger:operation PUT /siteHosts/{id} Update site hosts
+// @Summary Update site hosts based on given id
+// @Tags SiteHosts
+// @Description update site hosts
+// @Accept json
+// @Produce json
+// @Param id path integer true "site hosts ID"
+// @Param input body models.SwagSiteHosts true "site hosts info"
+// @Success 200 {object} siteHosts.SiteHost
+// @Failure 400 {string} string "invalid site host"
+// @Failure 404 {string} string siteHosts.ErrSiteHostNotFound
+// @Router /siteHosts/{id} [put]
+// Update updates site hosts data
+func Update(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerSiteHosts", "update")
+	log.GetInfo().Str("when", "starting processing request").Msg("start handler Update")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+	host := siteHosts.SiteHost{Id: int64(id)}
+	log.GetInfo().Msg("read current site host, omitted fields keep their values")
+	if err := siteHosts.Read(&host); err != nil {
+		log.GetError().Str("when", "read current site host").
+			Err(err).Msg("unable to read site host")
+	}
+	log.GetInfo().Msg("read request body")
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.GetError().Str("when", "read request body").
+			Err(err).Msg("unable to read body")
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.GetError().Str("when", "close body").
+				Err(err).Msg("unable to close body")
+		}
+	}()
+
+	log.GetInfo().Msg("unmarshal request body")
+	if err := json.Unmarshal(buf, &host); err != nil {
+		log.GetError().Str("when", "unmarshal request body").
+			Err(err).Msg("unable to unmarshal body ")
+	}
+
+	log.GetInfo().Msg("validate site host")
+	if err := siteManager.ValidateHostPattern(host.Pattern); err != nil {
+		log.GetWarn().Str("when", "validate site host").
+			Err(err).Msg("invalid site host")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "invalid site host").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("update site host")
+	if err := siteHosts.Update(&host); err != nil {
+		log.GetError().Str("when", "update site host").
+			Err(err).Msg("failed to update site host")
+		if err == siteHosts.ErrSiteHostNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+				log.GetError().Str("when", "update site host").
+					Str("when", "site hosts not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpUpdate); err != nil {
+			log.GetError().Str("when", "update site host").
+				Str("when", "failed to update site host").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal update site host")
+	bytes, err := json.Marshal(&host)
+	if err != nil {
+		log.GetError().Str("when", "marshal update site host").
+			Err(err).Msg("unable to marshal site host")
+	}
+
+	log.GetInfo().Msg("send response with update site host")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpUpdate); err != nil {
+		log.GetError().Str("when", "send response with update site host").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Update")
+}
+
+// Delete godoc
+// @Swagger:operation DELETE /siteHosts/{id} Delete site hosts
+// @Summary Delete site hosts based on given id
+// @Tags SiteHosts
+// @Description delete site hosts
+// @Accept json
+// @Produce json
+// @Param id path integer true "site hosts ID"
+// @Success 200 {object} siteHosts.SiteHost
+// @Failure 404 {string} string siteHosts.ErrSiteHostNotFound
+// @Router /siteHosts/{id} [delete]
+// Delete deletes site hosts data
+func Delete(w http.ResponseWriter, r *http.Request) {
+	log := logging.NewLogs("handlerSiteHosts", "delete")
+	log.GetInfo().Str("when", "start processing request").Msg("start handler Delete")
+	w.Header().Set("Content-Type", "text/json; charset=utf-8")
+
+	log.GetInfo().Msg("get and convert id")
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		log.GetError().Str("when", "get and convert id").
+			Err(err).Msg("failed to get and convert id")
+	}
+
+	host := siteHosts.SiteHost{Id: int64(id)}
+	log.GetInfo().Msg("delete site host with specified id")
+	if err := siteHosts.Delete(&host); err != nil {
+		log.GetError().Str("when", "delete site host").
+			Err(err).Msg("failed to delete site host")
+		if err == siteHosts.ErrSiteHostNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+				log.GetError().Str("when", "delete site host").
+					Str("when", "site hosts not found").Str("when", "send response").
+					Err(err).Msg("unable to send response")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := formatters.WriteJsonOp(w, "{}", resourceName, formatters.OpDelete); err != nil {
+			log.GetError().Str("when", "delete site host").
+				Str("when", "failed to delete site host").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
+	log.GetInfo().Msg("marshal site host")
+	bytes, err := json.Marshal(&host)
+	if err != nil {
+		log.GetError().Str("when", "marshal site host").
+			Err(err).Msg("unable to marshal site host")
+	}
+
+	log.GetInfo().Msg("send response deleted site host")
+	if _, err := formatters.WriteJsonOp(w, string(bytes), resourceName, formatters.OpDelete); err != nil {
+		log.GetError().Str("when", "send response deleted site host").
+			Err(err).Msg("unable to send response")
+	}
+	log.GetInfo().Msg("exiting handler Delete")
+}
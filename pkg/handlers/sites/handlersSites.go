@@ -9,6 +9,7 @@ import (
 	"reverseProxy/pkg/formatters"
 	"reverseProxy/pkg/logging"
 	"reverseProxy/pkg/repositories/sites"
+	"reverseProxy/pkg/siteManager"
 	"strconv"
 )
 
@@ -23,6 +24,7 @@ const resourceName = "site"
 // @Produce json
 // @Param input body sites.Site true "site info"
 // @Success 200 {integer} integer 1
+// @Failure 400 {string} string "invalid site settings"
 // @Failure 404 {string} string sites.ErrSiteNotFound
 // @Router /sites [post]
 // Create creates new site
@@ -54,6 +56,19 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 
+	log.GetInfo().Msg("validate site settings")
+	if err := siteManager.ValidateSite(&site); err != nil {
+		log.GetWarn().Str("when", "validate site settings").
+			Err(err).Msg("invalid site settings")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := fmt.Fprint(w, "{}"); err != nil {
+			log.GetError().Str("when", "create site").
+				Str("when", "invalid site settings").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
 	log.GetInfo().Msg("create site")
 	if err := sites.Create(&site); err != nil {
 		log.GetError().Str("when", "create site").
@@ -174,6 +189,7 @@ func Read(w http.ResponseWriter, r *http.Request) {
 // @Param id path integer true "site ID"
 // @Param input body sites.Site true "site info"
 // @Success 200 {object} sites.Site
+// @Failure 400 {string} string "invalid site settings"
 // @Failure 404 {string} string sites.ErrSiteNotFound
 // @Router /sites/{id} [put]
 // Update updates sites
@@ -206,6 +222,12 @@ func Update(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	site := sites.Site{Id: int64(id)}
+	log.GetInfo().Msg("read current site, omitted fields keep their values")
+	if err := sites.GetSite(&site); err != nil {
+		log.GetError().Str("when", "read current site").
+			Err(err).Msg("unable to read site")
+	}
+
 	log.GetInfo().Msg("unmarshal request body")
 	if err := json.Unmarshal(buf, &site); err != nil {
 		log.GetError().Str("when", "unmarshal request body").
@@ -213,6 +235,19 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		err.Error()
 	}
 
+	log.GetInfo().Msg("validate site settings")
+	if err := siteManager.ValidateSite(&site); err != nil {
+		log.GetWarn().Str("when", "validate site settings").
+			Err(err).Msg("invalid site settings")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := fmt.Fprint(w, "{}"); err != nil {
+			log.GetError().Str("when", "update site").
+				Str("when", "invalid site settings").Str("when", "send response").
+				Err(err).Msg("unable to send response")
+		}
+		return
+	}
+
 	log.GetInfo().Msg("update site")
 	if err := sites.UpdateSite(&site); err != nil {
 		log.GetError().Str("when", "update site").
@@ -0,0 +1,7 @@
+// mirrorManager stores a structure
+// with the counters of the shadow requests.
+//
+// Responsible for limiting the shadow requests
+// in flight, counting their results and the
+// comparisons of their responses.
+package mirrorManager
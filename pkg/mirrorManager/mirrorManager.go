@@ -0,0 +1,197 @@
+package mirrorManager
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var MirrorMgr *MirrorManager
+
+type MirrorManager struct {
+	counters map[string]*Counters
+	// diffs are the comparisons of the routes of the hosts
+	diffs map[string]map[int64]*RouteDiffs
+	// slots limits the shadow requests in flight,
+	// the requests over the limit are dropped
+	slots chan struct{}
+	mux   sync.Mutex
+}
+
+// Counters are the numbers of the shadow requests of the site
+type Counters struct {
+	// Mirrored requests got the response of the shadow backend
+	Mirrored int64 `json:"mirrored"`
+	// Dropped requests were not sent: the body was over
+	// the limit, too many requests were in flight or
+	// there was no alive shadow backend
+	Dropped int64 `json:"dropped"`
+	// Failed requests got no response of the shadow backend
+	Failed int64 `json:"failed"`
+}
+
+// RouteDiffs are the numbers of the responses of the route
+// compared with the responses of the shadow backends
+type RouteDiffs struct {
+	// Route is the id of the route, 0 if no route matched
+	Route   int64  `json:"route"`
+	Pattern string `json:"pattern"`
+	// Compared responses got the response of the shadow backend
+	Compared   int64 `json:"compared"`
+	Mismatched int64 `json:"mismatched"`
+	// Skipped responses got the response of the shadow backend,
+	// but their bodies were over the limit or could not be
+	// decoded, and no other difference was found
+	Skipped int64 `json:"skipped"`
+	// MismatchRate is Mismatched of Compared
+	MismatchRate float64 `json:"mismatch_rate"`
+}
+
+// Result is the result of the shadow request
+type Result int
+
+const (
+	Mirrored Result = iota
+	Dropped
+	Failed
+)
+
+// NewMirrorManager returns new struct MirrorManager
+// with the limit of the shadow requests in flight
+func NewMirrorManager(maxRequests int64) *MirrorManager {
+	if maxRequests < 0 {
+		maxRequests = 0
+	}
+	return &MirrorManager{
+		counters: make(map[string]*Counters),
+		diffs:    make(map[string]map[int64]*RouteDiffs),
+		slots:    make(chan struct{}, maxRequests),
+	}
+}
+
+// Acquire takes the slot of the shadow request without
+// waiting, it returns false if all slots are taken
+func (m *MirrorManager) Acquire() bool {
+	if m == nil {
+		return false
+	}
+	select {
+	case m.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns the slot of the finished shadow request
+func (m *MirrorManager) Release() {
+	if m == nil {
+		return
+	}
+	<-m.slots
+}
+
+// Count counts the result of the shadow request of the host
+func (m *MirrorManager) Count(host string, result Result) {
+	if m == nil {
+		return
+	}
+	host = strings.ToLower(host)
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	counters, ok := m.counters[host]
+	if !ok {
+		counters = &Counters{}
+		m.counters[host] = counters
+	}
+	switch result {
+	case Mirrored:
+		counters.Mirrored++
+	case Dropped:
+		counters.Dropped++
+	case Failed:
+		counters.Failed++
+	}
+}
+
+// Counters returns the counters of the shadow
+// requests of the host since the start
+func (m *MirrorManager) Counters(host string) Counters {
+	if m == nil {
+		return Counters{}
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if counters, ok := m.counters[strings.ToLower(host)]; ok {
+		return *counters
+	}
+	return Counters{}
+}
+
+// CountDiff counts the comparison of the responses to
+// the request of the route of the host, the pattern
+// is the prefix or the regex of the route
+func (m *MirrorManager) CountDiff(host string, route int64, pattern string, mismatched bool) {
+	if m == nil {
+		return
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	diffs := m.routeDiffs(host, route, pattern)
+	diffs.Compared++
+	if mismatched {
+		diffs.Mismatched++
+	}
+}
+
+// CountSkipped counts the responses to the request of
+// the route of the host which could not be compared
+func (m *MirrorManager) CountSkipped(host string, route int64, pattern string) {
+	if m == nil {
+		return
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.routeDiffs(host, route, pattern).Skipped++
+}
+
+// routeDiffs returns the comparisons of the route of
+// the host, it is called with the mutex locked
+func (m *MirrorManager) routeDiffs(host string, route int64, pattern string) *RouteDiffs {
+	host = strings.ToLower(host)
+	routes, ok := m.diffs[host]
+	if !ok {
+		routes = make(map[int64]*RouteDiffs)
+		m.diffs[host] = routes
+	}
+	diffs, ok := routes[route]
+	if !ok {
+		diffs = &RouteDiffs{Route: route}
+		routes[route] = diffs
+	}
+	// the pattern of the changed route is updated
+	diffs.Pattern = pattern
+	return diffs
+}
+
+// Diffs returns the comparisons of the routes of
+// the host since the start in the order of the ids
+func (m *MirrorManager) Diffs(host string) []RouteDiffs {
+	list := []RouteDiffs{}
+	if m == nil {
+		return list
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, diffs := range m.diffs[strings.ToLower(host)] {
+		route := *diffs
+		if route.Compared > 0 {
+			route.MismatchRate = float64(route.Mismatched) / float64(route.Compared)
+		}
+		list = append(list, route)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Route < list[j].Route
+	})
+	return list
+}
@@ -0,0 +1,69 @@
+package mirrorManager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMirrorManager_Acquire(t *testing.T) {
+	m := NewMirrorManager(2)
+	if !m.Acquire() || !m.Acquire() {
+		t.Fatalf("Acquire() = false, want 2 free slots")
+	}
+	if m.Acquire() {
+		t.Fatalf("Acquire() over the limit = true, want false")
+	}
+	m.Release()
+	if !m.Acquire() {
+		t.Errorf("Acquire() after Release() = false, want true")
+	}
+	if (*MirrorManager)(nil).Acquire() {
+		t.Errorf("nil mirror manager has free slots")
+	}
+}
+
+func TestMirrorManager_Count(t *testing.T) {
+	m := NewMirrorManager(1)
+	for _, result := range []Result{Mirrored, Mirrored, Dropped, Failed, Mirrored} {
+		m.Count("Example.com", result)
+	}
+	m.Count("vk.com", Dropped)
+
+	want := Counters{Mirrored: 3, Dropped: 1, Failed: 1}
+	if got := m.Counters("example.com"); got != want {
+		t.Errorf("Counters() = %+v, want %+v", got, want)
+	}
+	if got := m.Counters("unknown.com"); got != (Counters{}) {
+		t.Errorf("Counters() of unknown host = %+v, want zero", got)
+	}
+	if got := (*MirrorManager)(nil).Counters("example.com"); got != (Counters{}) {
+		t.Errorf("nil mirror manager has counters %+v", got)
+	}
+}
+
+func TestMirrorManager_CountDiff(t *testing.T) {
+	m := NewMirrorManager(1)
+	m.CountDiff("example.com", 2, "/api", true)
+	m.CountDiff("example.com", 2, "/api", false)
+	m.CountDiff("example.com", 2, "/api/v2", false)
+	m.CountDiff("example.com", 2, "/api/v2", true)
+	m.CountDiff("Example.com", 0, "", false)
+	m.CountDiff("vk.com", 1, "/", true)
+	m.CountSkipped("example.com", 2, "/api/v2")
+	m.CountSkipped("example.com", 5, "/files")
+
+	want := []RouteDiffs{
+		{Route: 0, Compared: 1},
+		{Route: 2, Pattern: "/api/v2", Compared: 4, Mismatched: 2, Skipped: 1, MismatchRate: 0.5},
+		{Route: 5, Pattern: "/files", Skipped: 1},
+	}
+	if got := m.Diffs("example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diffs() = %+v, want %+v", got, want)
+	}
+	if got := m.Diffs("unknown.com"); len(got) != 0 {
+		t.Errorf("Diffs() of unknown host = %+v, want empty", got)
+	}
+	if got := (*MirrorManager)(nil).Diffs("example.com"); got == nil || len(got) != 0 {
+		t.Errorf("nil mirror manager has diffs %+v", got)
+	}
+}
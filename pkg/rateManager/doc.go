@@ -0,0 +1,6 @@
+// rateManager stores a structure
+// with the token buckets of rate limits.
+//
+// Responsible for taking the tokens of the
+// requests and removing the refilled buckets.
+package rateManager
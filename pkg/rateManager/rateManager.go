@@ -0,0 +1,133 @@
+package rateManager
+
+import (
+	"context"
+	"math"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/rateLimits"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var RateMgr *RateManager
+
+type RateManager struct {
+	buckets map[string]*bucket
+	tick    *time.Ticker
+	ctx     context.Context
+	mux     sync.Mutex
+	log     *logging.Logger
+}
+
+// bucket stores the tokens of one key of the
+// rate limit with the capacity and the rate
+// it is refilled with
+type bucket struct {
+	tokens   float64
+	updated  time.Time
+	capacity float64
+	// rate is in tokens per second
+	rate float64
+}
+
+// Result is the state of the bucket
+// after the request took its token
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// Reset is the time until the bucket is full
+	Reset time.Duration
+	// RetryAfter is the time until the next token
+	// of the rejected request, zero if allowed
+	RetryAfter time.Duration
+}
+
+// NewRateManager returns new struct RateManager
+func NewRateManager(ctx context.Context) *RateManager {
+	return &RateManager{
+		buckets: make(map[string]*bucket),
+		tick:    time.NewTicker(time.Minute),
+		ctx:     ctx,
+	}
+}
+
+// Take takes the token of the request with the key from
+// the bucket of the rate limit, the request is rejected
+// if the bucket is empty
+func (m *RateManager) Take(limit *rateLimits.RateLimit, key string, now time.Time) Result {
+	if m == nil {
+		return Result{Allowed: true}
+	}
+	capacity := float64(limit.Burst)
+	if limit.Burst <= 0 {
+		capacity = float64(limit.Requests)
+	}
+	rate := float64(limit.Requests) / (float64(limit.Period) / 1000)
+	id := strconv.FormatInt(limit.Id, 10) + " " + key
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	b, ok := m.buckets[id]
+	// the bucket of the changed limit starts full
+	if !ok || b.capacity != capacity || b.rate != rate {
+		b = &bucket{tokens: capacity, updated: now, capacity: capacity, rate: rate}
+		m.buckets[id] = b
+	}
+	b.refill(now)
+
+	result := Result{Limit: int64(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int64(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	return result
+}
+
+// refill adds the tokens for the time since
+// the last update, up to the capacity
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+}
+
+// seconds converts the seconds to the duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// sweep removes the full buckets, they
+// are the same as the missing ones
+func (m *RateManager) sweep(now time.Time) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.log = logging.NewLogs("rateManager", "sweep")
+	for id, b := range m.buckets {
+		b.refill(now)
+		if b.tokens >= b.capacity {
+			delete(m.buckets, id)
+		}
+	}
+	m.log.GetInfo().Int("buckets", len(m.buckets)).Msg("refilled buckets removed")
+}
+
+// Serve with the ticks running sweep
+// during the operation of the application
+func (m *RateManager) Serve() error {
+	defer m.tick.Stop()
+	for {
+		select {
+		case now := <-m.tick.C:
+			m.sweep(now)
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
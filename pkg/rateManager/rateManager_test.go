@@ -0,0 +1,59 @@
+package rateManager
+
+import (
+	"reverseProxy/pkg/repositories/rateLimits"
+	"testing"
+	"time"
+)
+
+func TestRateManager_Take(t *testing.T) {
+	m := NewRateManager(nil)
+	defer m.tick.Stop()
+	limit := &rateLimits.RateLimit{Id: 1, Requests: 2, Period: 1000, Burst: 3}
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		key           string
+		after         time.Duration
+		wantAllowed   bool
+		wantRemaining int64
+		wantRetry     time.Duration
+	}{
+		{name: "full bucket", key: "a", wantAllowed: true, wantRemaining: 2},
+		{name: "burst", key: "a", wantAllowed: true, wantRemaining: 1},
+		{name: "last token", key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "empty bucket", key: "a", wantRetry: 500 * time.Millisecond},
+		{name: "other key", key: "b", wantAllowed: true, wantRemaining: 2},
+		{name: "refilled token", key: "a", after: 500 * time.Millisecond, wantAllowed: true},
+		{name: "partly refilled", key: "a", after: 250 * time.Millisecond, wantRetry: 250 * time.Millisecond},
+		{name: "refill is limited by burst", key: "a", after: time.Hour, wantAllowed: true, wantRemaining: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+			got := m.Take(limit, tt.key, now)
+			if got.Allowed != tt.wantAllowed || got.Remaining != tt.wantRemaining || got.RetryAfter != tt.wantRetry {
+				t.Errorf("Take() = %+v, want allowed %v, remaining %d, retry after %v",
+					got, tt.wantAllowed, tt.wantRemaining, tt.wantRetry)
+			}
+			if got.Limit != 3 {
+				t.Errorf("Take() limit = %d, want 3", got.Limit)
+			}
+		})
+	}
+
+	// the bucket of the changed limit starts full
+	limit.Burst = 0
+	if got := m.Take(limit, "a", now); !got.Allowed || got.Limit != 2 || got.Remaining != 1 {
+		t.Errorf("Take() of changed limit = %+v", got)
+	}
+
+	m.sweep(now.Add(time.Hour))
+	if len(m.buckets) != 0 {
+		t.Errorf("sweep() left %d refilled buckets", len(m.buckets))
+	}
+	if got := (*RateManager)(nil).Take(limit, "a", now); !got.Allowed {
+		t.Errorf("Take() of nil manager rejected the request")
+	}
+}
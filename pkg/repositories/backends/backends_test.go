@@ -39,11 +39,11 @@ func (f fakeDbManager) Exec(query string, args ...interface{}) error {
 	switch query {
 	case sqlUpdate:
 		mockResult := sqlmock.NewResult(5, 0)
-		if args[1] == int64(1) {
+		if args[9] == int64(1) {
 			mockResult = sqlmock.NewResult(5, 1)
 		}
 		mock.ExpectExec("^UPDATE backends SET .* WHERE .*;$").WillReturnResult(mockResult)
-		result, err := dbMock.ExecContext(queryCtx, sqlUpdate, args[1])
+		result, err := dbMock.ExecContext(queryCtx, sqlUpdate, args[9])
 		if err != nil {
 			return err
 		}
@@ -93,17 +93,21 @@ func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, fu
 	switch query {
 	case sqlBackCreate:
 		mockRow := mock.NewRows([]string{"id"})
-		if args[0] == "127.0.0.1:80" && args[1] == int64(1) {
+		if args[0] == "127.0.0.1:80" && args[1] == SchemeHTTP && args[9] == int64(1) {
 			mockRow.AddRow(int64(1))
 		}
 		mock.ExpectQuery("^INSERT INTO backends (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
-		row := dbMock.QueryRow(sqlBackCreate, args[0], args[1])
+		row := dbMock.QueryRow(sqlBackCreate, args...)
 		return row, func() {}, nil
 
 	case sqlGet:
-		mockRow := mock.NewRows([]string{"id", "address", "site_id", "site_name", "site_host"})
+		mockRow := mock.NewRows([]string{"id", "address", "scheme", "ca_cert", "server_name", "client_cert",
+			"client_key", "insecure_skip_verify", "pool", "weight", "site_id", "site_name", "site_host"})
 		if args[0] == int64(1) {
-			mockRow.AddRow(int64(1), "127.0.0.1:80", int64(1), "vk", "vk.com")
+			mockRow.AddRow(int64(1), "127.0.0.1:80", "https", "", "vk.com", "", "", false, "", int64(1), int64(1), "vk", "vk.com")
+		}
+		if args[0] == int64(2) {
+			mockRow.AddRow(int64(2), "127.0.0.1:81", "http", "", "", "", "", false, "", nil, int64(1), "vk", "vk.com")
 		}
 
 		mock.ExpectQuery("^SELECT (.+) FROM backends b JOIN sites s ON b.site_id = s.id WHERE .*;$").
@@ -116,7 +120,51 @@ func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, fu
 }
 
 func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
-	panic("implement me")
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlList:
+		mockRows := mock.NewRows([]string{"id", "address", "scheme", "ca_cert", "server_name", "client_cert",
+			"client_key", "insecure_skip_verify", "pool", "weight", "site_id", "site_name", "site_host",
+			"connect_timeout", "response_header_timeout", "idle_timeout", "request_timeout"}).
+			AddRow(int64(1), "127.0.0.1:80", "http", "", "", "", "", false, "", int64(10), int64(1), "vk", "vk.com",
+				int64(0), int64(0), int64(0), int64(0)).
+			AddRow(int64(2), "127.0.0.1:81", "http", "", "", "", "", false, "", int64(0), int64(1), "vk", "vk.com",
+				int64(0), int64(0), int64(0), int64(0)).
+			AddRow(int64(3), "127.0.0.1:82", "http", "", "", "", "", false, "", nil, int64(1), "vk", "vk.com",
+				int64(0), int64(0), int64(0), int64(0))
+		mock.ExpectQuery("^SELECT (.+) FROM backends b JOIN sites s on s.id = b.site_id;$").WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() { dbMock.Close() }, nil
+	default:
+		dbMock.Close()
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	list, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	// the backend stored without the weight gets
+	// the requests, the stored 0 stays drained
+	want := map[int64]int64{1: 10, 2: 0, 3: DefaultWeight}
+	if len(list) != len(want) {
+		t.Fatalf("List() got %d backends, want %d", len(list), len(want))
+	}
+	for _, backend := range list {
+		if backend.Weight != want[backend.Id] {
+			t.Errorf("List() backend %d weight = %d, want %d", backend.Id, backend.Weight, want[backend.Id])
+		}
+	}
 }
 
 func TestCreate(t *testing.T) {
@@ -240,14 +288,22 @@ func TestRead(t *testing.T) {
 		Site:    site,
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name       string
+		args       args
+		wantWeight int64
+		wantErr    bool
 	}{
 		{
-			name:    "read existence backend",
-			args:    args{b: backend1},
-			wantErr: false,
+			name:       "read existence backend",
+			args:       args{b: backend1},
+			wantWeight: 1,
+			wantErr:    false,
+		},
+		{
+			name:       "read backend stored without weight",
+			args:       args{b: &Backend{Id: 2}},
+			wantWeight: DefaultWeight,
+			wantErr:    false,
 		},
 		{
 			name:    "read backend with non-existence id",
@@ -260,6 +316,9 @@ func TestRead(t *testing.T) {
 			if err := Read(tt.args.b); (err != nil) != tt.wantErr {
 				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !tt.wantErr && tt.args.b.Weight != tt.wantWeight {
+				t.Errorf("Read() weight = %d, want %d", tt.args.b.Weight, tt.wantWeight)
+			}
 		})
 	}
 }
@@ -294,12 +353,23 @@ func TestUpdate(t *testing.T) {
 			args:    args{b: backend2},
 			wantErr: true,
 		},
+		{
+			name: "update server name keeps scheme",
+			args: args{b: &Backend{
+				Id:  1,
+				TLS: TLSSettings{ServerName: "internal.vk.com"},
+			}},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if err := Update(tt.args.b); (err != nil) != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !tt.wantErr && tt.args.b.Scheme != SchemeHTTPS {
+				t.Errorf("Update() scheme = %s, want %s", tt.args.b.Scheme, SchemeHTTPS)
+			}
 		})
 	}
 }
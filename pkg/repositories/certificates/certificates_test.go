@@ -0,0 +1,304 @@
+package certificates
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlCertificateUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[2] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE certificates SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlCertificateUpdate, args[2])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlCertificateDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM certificates WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlCertificateDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlCertificateCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == "cert" && args[1] == "key" && args[2] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO certificates (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlCertificateCreate, args[0], args[1], args[2])
+		return row, func() {}, nil
+
+	case sqlCertificateGet:
+		mockRow := mock.NewRows([]string{"id", "cert", "key", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), "cert", "key", int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM certificates c JOIN sites s ON s.id = c.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlCertificateGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlCertificateList:
+		mockRows := mock.NewRows([]string{"id", "cert", "key", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), "cert", "key", int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), "cert2", "key2", int64(2), "example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM certificates c JOIN sites s ON s.id = c.site_id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlCertificateList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		c *Certificate
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new certificate",
+			args: args{c: &Certificate{
+				Cert: "cert",
+				Key:  "key",
+				Site: &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new certificate with non-existence site_id",
+			args: args{c: &Certificate{
+				Cert: "cert",
+				Key:  "key",
+				Site: &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.c); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		c *Certificate
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Certificate
+		wantErr bool
+	}{
+		{
+			name: "read existence certificate",
+			args: args{c: &Certificate{Id: 1}},
+			want: &Certificate{
+				Id:   1,
+				Cert: "cert",
+				Key:  "key",
+				Site: &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read certificate with non-existence id",
+			args:    args{c: &Certificate{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.c); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				condition := tt.args.c.Cert != tt.want.Cert || tt.args.c.Key != tt.want.Key
+				condition = condition || *tt.args.c.Site != *tt.want.Site
+				if condition {
+					t.Errorf("Read() got: %v, want: %v", tt.args.c, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		c *Certificate
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Certificate
+		wantErr bool
+	}{
+		{
+			name: "update key of existence certificate",
+			args: args{c: &Certificate{Id: 1, Key: "newKey"}},
+			want: &Certificate{
+				Id:   1,
+				Cert: "cert",
+				Key:  "newKey",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "update certificate with non-existence id",
+			args:    args{c: &Certificate{Id: 2, Key: "newKey"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.c); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				if tt.args.c.Cert != tt.want.Cert || tt.args.c.Key != tt.want.Key {
+					t.Errorf("Update() got: %v, want: %v", tt.args.c, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		c *Certificate
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete certificate with existence id",
+			args:    args{c: &Certificate{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete certificate with non-existence id",
+			args:    args{c: &Certificate{Id: 3}},
+			wantErr: ErrCertificateNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.c); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d certificates, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Cert != "cert2" {
+		t.Errorf("List() got: %v, want certificate of example.com", got[1])
+	}
+}
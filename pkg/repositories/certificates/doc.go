@@ -0,0 +1,6 @@
+// package repositories\certificates stores
+// a structure that contains rows data
+// of certificate's table, and functions for
+// create, read, update and delete
+// data of certificate's table
+package certificates
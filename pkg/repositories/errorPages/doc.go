@@ -0,0 +1,6 @@
+// package repositories\errorPages stores
+// a structure that contains rows data
+// of error page's table, and functions for
+// create, read, update and delete
+// data of error page's table
+package errorPages
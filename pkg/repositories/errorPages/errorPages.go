@@ -0,0 +1,126 @@
+package errorPages
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlErrorPageCreate = "INSERT INTO error_pages (status, content_type, body, site_id) VALUES ($1, $2, $3, $4) RETURNING id;"
+	sqlErrorPageGet    = "SELECT e.id, e.status, e.content_type, e.body, s.id, s.name, s.host FROM error_pages e JOIN sites s ON s.id = e.site_id WHERE e.id = $1;"
+	sqlErrorPageUpdate = "UPDATE error_pages SET status = $1, content_type = $2, body = $3 WHERE id = $4;"
+	sqlErrorPageDelete = "DELETE FROM error_pages WHERE id = $1;"
+	sqlErrorPageList   = "SELECT e.id, e.status, e.content_type, e.body, s.id, s.name, s.host FROM error_pages e JOIN sites s ON s.id = e.site_id ORDER BY e.id;"
+)
+
+// ErrorPage is the template of the error response
+// of the site with the status, the site may have
+// the templates of several content types
+type ErrorPage struct {
+	Id          int64       `json:"id" example:"1" swaggerignore:"true"`
+	Status      int64       `json:"status" example:"502"`
+	ContentType string      `json:"content_type" example:"text/html; charset=utf-8"`
+	Body        string      `json:"body" example:"<h1>{{.Status}} {{.StatusText}}</h1><p>{{.RequestID}}</p>"`
+	Site        *sites.Site `json:"site"`
+}
+
+var ErrErrorPageNotFound = fmt.Errorf("error page not found")
+
+// Create creates error page data
+func Create(e *ErrorPage) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlErrorPageCreate, e.Status, e.ContentType, e.Body, e.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&e.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads error page data
+func Read(e *ErrorPage) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlErrorPageGet, e.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	e.Site = &sites.Site{}
+	if err := row.Scan(&e.Id, &e.Status, &e.ContentType, &e.Body,
+		&e.Site.Id, &e.Site.Name, &e.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrErrorPageNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates error page data
+func Update(e *ErrorPage) error {
+	oldPage := *e
+	if err := Read(&oldPage); err != nil {
+		return err
+	}
+	if e.Status == 0 {
+		e.Status = oldPage.Status
+	}
+	if e.ContentType == "" {
+		e.ContentType = oldPage.ContentType
+	}
+	if e.Body == "" {
+		e.Body = oldPage.Body
+	}
+	e.Site = oldPage.Site
+
+	if err := db.ConnManager.Exec(sqlErrorPageUpdate, e.Status, e.ContentType, e.Body, e.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrErrorPageNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes error page data
+func Delete(e *ErrorPage) error {
+	if err := db.ConnManager.Exec(sqlErrorPageDelete, e.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrErrorPageNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all error pages from database
+// in the order of their creation
+func List() ([]*ErrorPage, error) {
+	pages := []*ErrorPage{}
+	rows, cancel, err := db.ConnManager.Query(sqlErrorPageList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return pages, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		page := ErrorPage{Site: &sites.Site{}}
+		if err := rows.Scan(&page.Id, &page.Status, &page.ContentType, &page.Body,
+			&page.Site.Id, &page.Site.Name, &page.Site.Host); err != nil {
+			return nil, err
+		}
+		pages = append(pages, &page)
+	}
+	return pages, rows.Err()
+}
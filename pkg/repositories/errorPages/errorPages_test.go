@@ -0,0 +1,311 @@
+package errorPages
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlErrorPageUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[3] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE error_pages SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlErrorPageUpdate, args[3])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlErrorPageDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM error_pages WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlErrorPageDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlErrorPageCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == int64(502) && args[1] == "text/html" && args[3] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO error_pages (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlErrorPageCreate, args...)
+		return row, func() {}, nil
+
+	case sqlErrorPageGet:
+		mockRow := mock.NewRows([]string{"id", "status", "content_type", "body", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), int64(502), "text/html", "<h1>{{.Status}}</h1>", int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM error_pages e JOIN sites s ON s.id = e.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlErrorPageGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlErrorPageList:
+		mockRows := mock.NewRows([]string{"id", "status", "content_type", "body", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), int64(502), "text/html", "<h1>{{.Status}}</h1>", int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), int64(429), "application/json", "{\"status\": {{.Status}}}", int64(2),
+			"example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM error_pages e JOIN sites s ON s.id = e.site_id ORDER BY e.id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlErrorPageList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		e *ErrorPage
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new error page",
+			args: args{e: &ErrorPage{
+				Status:      502,
+				ContentType: "text/html",
+				Body:        "<h1>{{.Status}}</h1>",
+				Site:        &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new error page with non-existence site_id",
+			args: args{e: &ErrorPage{
+				Status:      502,
+				ContentType: "text/html",
+				Body:        "<h1>{{.Status}}</h1>",
+				Site:        &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.e); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		e *ErrorPage
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *ErrorPage
+		wantErr bool
+	}{
+		{
+			name: "read existence error page",
+			args: args{e: &ErrorPage{Id: 1}},
+			want: &ErrorPage{
+				Id:          1,
+				Status:      502,
+				ContentType: "text/html",
+				Body:        "<h1>{{.Status}}</h1>",
+				Site:        &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read error page with non-existence id",
+			args:    args{e: &ErrorPage{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.e); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.e
+				got.Site, tt.want.Site = nil, nil
+				if got != *tt.want {
+					t.Errorf("Read() got: %v, want: %v", tt.args.e, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		e *ErrorPage
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *ErrorPage
+		wantErr bool
+	}{
+		{
+			name: "update body of existence error page",
+			args: args{e: &ErrorPage{Id: 1, Body: "<p>{{.RequestID}}</p>"}},
+			want: &ErrorPage{
+				Id:          1,
+				Status:      502,
+				ContentType: "text/html",
+				Body:        "<p>{{.RequestID}}</p>",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "update error page with non-existence id",
+			args:    args{e: &ErrorPage{Id: 2, Body: "<p>{{.RequestID}}</p>"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.e); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.e
+				got.Site = nil
+				if got != *tt.want {
+					t.Errorf("Update() got: %v, want: %v", tt.args.e, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		e *ErrorPage
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete error page with existence id",
+			args:    args{e: &ErrorPage{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete error page with non-existence id",
+			args:    args{e: &ErrorPage{Id: 3}},
+			wantErr: ErrErrorPageNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.e); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d error pages, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Status != 429 || got[1].ContentType != "application/json" {
+		t.Errorf("List() got: %v, want error page of example.com", got[1])
+	}
+}
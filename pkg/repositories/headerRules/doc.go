@@ -0,0 +1,6 @@
+// package repositories\headerRules stores
+// a structure that contains rows data
+// of header rule's table, and functions for
+// create, read, update and delete
+// data of header rule's table
+package headerRules
@@ -0,0 +1,138 @@
+package headerRules
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlHeaderRuleCreate = "INSERT INTO header_rules (direction, action, name, value, site_id) VALUES ($1, $2, $3, $4, $5) RETURNING id;"
+	sqlHeaderRuleGet    = "SELECT h.id, h.direction, h.action, h.name, h.value, s.id, s.name, s.host FROM header_rules h JOIN sites s ON s.id = h.site_id WHERE h.id = $1;"
+	sqlHeaderRuleUpdate = "UPDATE header_rules SET direction = $1, action = $2, name = $3, value = $4 WHERE id = $5;"
+	sqlHeaderRuleDelete = "DELETE FROM header_rules WHERE id = $1;"
+	sqlHeaderRuleList   = "SELECT h.id, h.direction, h.action, h.name, h.value, s.id, s.name, s.host FROM header_rules h JOIN sites s ON s.id = h.site_id ORDER BY h.id;"
+)
+
+const (
+	DirectionRequest  = "request"
+	DirectionResponse = "response"
+)
+
+const (
+	ActionAdd    = "add"
+	ActionSet    = "set"
+	ActionRemove = "remove"
+)
+
+// HeaderRule adds, sets or removes the header of
+// the requests sent to the backends or of the
+// responses sent to the users of the site
+type HeaderRule struct {
+	Id        int64       `json:"id" example:"1" swaggerignore:"true"`
+	Direction string      `json:"direction" example:"request"`
+	Action    string      `json:"action" example:"set"`
+	Name      string      `json:"name" example:"X-Env"`
+	Value     string      `json:"value" example:"prod"`
+	Site      *sites.Site `json:"site"`
+}
+
+var ErrHeaderRuleNotFound = fmt.Errorf("header rule not found")
+
+// Create creates header rule data
+func Create(h *HeaderRule) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlHeaderRuleCreate, h.Direction, h.Action, h.Name, h.Value, h.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&h.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads header rule data
+func Read(h *HeaderRule) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlHeaderRuleGet, h.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	h.Site = &sites.Site{}
+	if err := row.Scan(&h.Id, &h.Direction, &h.Action, &h.Name, &h.Value,
+		&h.Site.Id, &h.Site.Name, &h.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrHeaderRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates header rule data
+func Update(h *HeaderRule) error {
+	oldRule := *h
+	if err := Read(&oldRule); err != nil {
+		return err
+	}
+	if h.Direction == "" {
+		h.Direction = oldRule.Direction
+	}
+	if h.Action == "" {
+		h.Action = oldRule.Action
+	}
+	if h.Name == "" {
+		h.Name = oldRule.Name
+	}
+	h.Site = oldRule.Site
+
+	if err := db.ConnManager.Exec(sqlHeaderRuleUpdate, h.Direction, h.Action, h.Name, h.Value, h.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrHeaderRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes header rule data
+func Delete(h *HeaderRule) error {
+	if err := db.ConnManager.Exec(sqlHeaderRuleDelete, h.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrHeaderRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all header rules from database
+// in the order of their creation
+func List() ([]*HeaderRule, error) {
+	rules := []*HeaderRule{}
+	rows, cancel, err := db.ConnManager.Query(sqlHeaderRuleList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rules, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		rule := HeaderRule{Site: &sites.Site{}}
+		if err := rows.Scan(&rule.Id, &rule.Direction, &rule.Action, &rule.Name, &rule.Value,
+			&rule.Site.Id, &rule.Site.Name, &rule.Site.Host); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, rows.Err()
+}
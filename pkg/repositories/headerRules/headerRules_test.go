@@ -0,0 +1,314 @@
+package headerRules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlHeaderRuleUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[4] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE header_rules SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlHeaderRuleUpdate, args[4])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlHeaderRuleDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM header_rules WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlHeaderRuleDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlHeaderRuleCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == DirectionRequest && args[2] == "X-Env" && args[4] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO header_rules (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlHeaderRuleCreate, args...)
+		return row, func() {}, nil
+
+	case sqlHeaderRuleGet:
+		mockRow := mock.NewRows([]string{"id", "direction", "action", "name", "value", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), "request", "set", "X-Env", "prod", int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM header_rules h JOIN sites s ON s.id = h.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlHeaderRuleGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlHeaderRuleList:
+		mockRows := mock.NewRows([]string{"id", "direction", "action", "name", "value", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), "request", "set", "X-Env", "prod", int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), "response", "remove", "Server", "", int64(2), "example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM header_rules h JOIN sites s ON s.id = h.site_id ORDER BY h.id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlHeaderRuleList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		h *HeaderRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new header rule",
+			args: args{h: &HeaderRule{
+				Direction: DirectionRequest,
+				Action:    ActionSet,
+				Name:      "X-Env",
+				Value:     "prod",
+				Site:      &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new header rule with non-existence site_id",
+			args: args{h: &HeaderRule{
+				Direction: DirectionRequest,
+				Action:    ActionSet,
+				Name:      "X-Env",
+				Value:     "prod",
+				Site:      &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.h); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		h *HeaderRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *HeaderRule
+		wantErr bool
+	}{
+		{
+			name: "read existence header rule",
+			args: args{h: &HeaderRule{Id: 1}},
+			want: &HeaderRule{
+				Id:        1,
+				Direction: DirectionRequest,
+				Action:    ActionSet,
+				Name:      "X-Env",
+				Value:     "prod",
+				Site:      &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read header rule with non-existence id",
+			args:    args{h: &HeaderRule{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.h); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.h
+				got.Site, tt.want.Site = nil, nil
+				if got != *tt.want {
+					t.Errorf("Read() got: %v, want: %v", tt.args.h, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		h *HeaderRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *HeaderRule
+		wantErr bool
+	}{
+		{
+			name: "update value of existence header rule",
+			args: args{h: &HeaderRule{Id: 1, Value: "staging"}},
+			want: &HeaderRule{
+				Id:        1,
+				Direction: DirectionRequest,
+				Action:    ActionSet,
+				Name:      "X-Env",
+				Value:     "staging",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "update header rule with non-existence id",
+			args:    args{h: &HeaderRule{Id: 2, Value: "staging"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.h); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.h
+				got.Site = nil
+				if got != *tt.want {
+					t.Errorf("Update() got: %v, want: %v", tt.args.h, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		h *HeaderRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete header rule with existence id",
+			args:    args{h: &HeaderRule{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete header rule with non-existence id",
+			args:    args{h: &HeaderRule{Id: 3}},
+			wantErr: ErrHeaderRuleNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.h); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d header rules, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Action != ActionRemove || got[1].Name != "Server" {
+		t.Errorf("List() got: %v, want header rule of example.com", got[1])
+	}
+}
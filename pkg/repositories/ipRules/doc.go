@@ -0,0 +1,6 @@
+// package repositories\ipRules stores
+// a structure that contains rows data
+// of ip rule's table, and functions for
+// create, read, update and delete
+// data of ip rule's table
+package ipRules
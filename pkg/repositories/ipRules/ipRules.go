@@ -0,0 +1,125 @@
+package ipRules
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlIPRuleCreate = "INSERT INTO ip_rules (action, cidr, site_id) VALUES ($1, $2, $3) RETURNING id;"
+	sqlIPRuleGet    = "SELECT i.id, i.action, i.cidr, s.id, s.name, s.host FROM ip_rules i JOIN sites s ON s.id = i.site_id WHERE i.id = $1;"
+	sqlIPRuleUpdate = "UPDATE ip_rules SET action = $1, cidr = $2 WHERE id = $3;"
+	sqlIPRuleDelete = "DELETE FROM ip_rules WHERE id = $1;"
+	sqlIPRuleList   = "SELECT i.id, i.action, i.cidr, s.id, s.name, s.host FROM ip_rules i JOIN sites s ON s.id = i.site_id ORDER BY i.id;"
+)
+
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// IPRule allows or denies the requests to
+// the site from the addresses of the CIDR
+type IPRule struct {
+	Id     int64       `json:"id" example:"1" swaggerignore:"true"`
+	Action string      `json:"action" example:"allow"`
+	CIDR   string      `json:"cidr" example:"10.0.0.0/8"`
+	Site   *sites.Site `json:"site"`
+}
+
+var ErrIPRuleNotFound = fmt.Errorf("ip rule not found")
+
+// Create creates ip rule data
+func Create(i *IPRule) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlIPRuleCreate, i.Action, i.CIDR, i.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&i.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads ip rule data
+func Read(i *IPRule) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlIPRuleGet, i.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	i.Site = &sites.Site{}
+	if err := row.Scan(&i.Id, &i.Action, &i.CIDR, &i.Site.Id, &i.Site.Name, &i.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrIPRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates ip rule data
+func Update(i *IPRule) error {
+	oldRule := *i
+	if err := Read(&oldRule); err != nil {
+		return err
+	}
+	if i.Action == "" {
+		i.Action = oldRule.Action
+	}
+	if i.CIDR == "" {
+		i.CIDR = oldRule.CIDR
+	}
+	i.Site = oldRule.Site
+
+	if err := db.ConnManager.Exec(sqlIPRuleUpdate, i.Action, i.CIDR, i.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrIPRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes ip rule data
+func Delete(i *IPRule) error {
+	if err := db.ConnManager.Exec(sqlIPRuleDelete, i.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrIPRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all ip rules from database
+// in the order of their creation
+func List() ([]*IPRule, error) {
+	rules := []*IPRule{}
+	rows, cancel, err := db.ConnManager.Query(sqlIPRuleList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rules, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		rule := IPRule{Site: &sites.Site{}}
+		if err := rows.Scan(&rule.Id, &rule.Action, &rule.CIDR,
+			&rule.Site.Id, &rule.Site.Name, &rule.Site.Host); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, rows.Err()
+}
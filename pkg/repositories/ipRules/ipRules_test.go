@@ -0,0 +1,306 @@
+package ipRules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlIPRuleUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[2] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE ip_rules SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlIPRuleUpdate, args[2])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlIPRuleDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM ip_rules WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlIPRuleDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlIPRuleCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == ActionAllow && args[1] == "10.0.0.0/8" && args[2] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO ip_rules (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlIPRuleCreate, args...)
+		return row, func() {}, nil
+
+	case sqlIPRuleGet:
+		mockRow := mock.NewRows([]string{"id", "action", "cidr", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), "allow", "10.0.0.0/8", int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM ip_rules i JOIN sites s ON s.id = i.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlIPRuleGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlIPRuleList:
+		mockRows := mock.NewRows([]string{"id", "action", "cidr", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), "allow", "10.0.0.0/8", int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), "deny", "2001:db8::/32", int64(2), "example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM ip_rules i JOIN sites s ON s.id = i.site_id ORDER BY i.id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlIPRuleList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		i *IPRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new ip rule",
+			args: args{i: &IPRule{
+				Action: ActionAllow,
+				CIDR:   "10.0.0.0/8",
+				Site:   &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new ip rule with non-existence site_id",
+			args: args{i: &IPRule{
+				Action: ActionAllow,
+				CIDR:   "10.0.0.0/8",
+				Site:   &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.i); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		i *IPRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *IPRule
+		wantErr bool
+	}{
+		{
+			name: "read existence ip rule",
+			args: args{i: &IPRule{Id: 1}},
+			want: &IPRule{
+				Id:     1,
+				Action: ActionAllow,
+				CIDR:   "10.0.0.0/8",
+				Site:   &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read ip rule with non-existence id",
+			args:    args{i: &IPRule{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.i); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.i
+				got.Site, tt.want.Site = nil, nil
+				if got != *tt.want {
+					t.Errorf("Read() got: %v, want: %v", tt.args.i, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		i *IPRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *IPRule
+		wantErr bool
+	}{
+		{
+			name: "update action of existence ip rule",
+			args: args{i: &IPRule{Id: 1, Action: ActionDeny}},
+			want: &IPRule{
+				Id:     1,
+				Action: ActionDeny,
+				CIDR:   "10.0.0.0/8",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "update ip rule with non-existence id",
+			args:    args{i: &IPRule{Id: 2, Action: ActionDeny}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.i); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.i
+				got.Site = nil
+				if got != *tt.want {
+					t.Errorf("Update() got: %v, want: %v", tt.args.i, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		i *IPRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete ip rule with existence id",
+			args:    args{i: &IPRule{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete ip rule with non-existence id",
+			args:    args{i: &IPRule{Id: 3}},
+			wantErr: ErrIPRuleNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.i); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d ip rules, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Action != ActionDeny || got[1].CIDR != "2001:db8::/32" {
+		t.Errorf("List() got: %v, want ip rule of example.com", got[1])
+	}
+}
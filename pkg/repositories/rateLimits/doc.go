@@ -0,0 +1,6 @@
+// package repositories\rateLimits stores
+// a structure that contains rows data
+// of rate limit's table, and functions for
+// create, read, update and delete
+// data of rate limit's table
+package rateLimits
@@ -0,0 +1,143 @@
+package rateLimits
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlRateLimitCreate = "INSERT INTO rate_limits (key, header, requests, period, burst, site_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;"
+	sqlRateLimitGet    = "SELECT r.id, r.key, r.header, r.requests, r.period, r.burst, s.id, s.name, s.host FROM rate_limits r JOIN sites s ON s.id = r.site_id WHERE r.id = $1;"
+	sqlRateLimitUpdate = "UPDATE rate_limits SET key = $1, header = $2, requests = $3, period = $4, burst = $5 WHERE id = $6;"
+	sqlRateLimitDelete = "DELETE FROM rate_limits WHERE id = $1;"
+	sqlRateLimitList   = "SELECT r.id, r.key, r.header, r.requests, r.period, r.burst, s.id, s.name, s.host FROM rate_limits r JOIN sites s ON s.id = r.site_id ORDER BY r.id;"
+)
+
+const (
+	KeyIP     = "ip"
+	KeyLogin  = "login"
+	KeyHeader = "header"
+	KeySite   = "site"
+)
+
+// RateLimit limits the requests to the site, the requests
+// with the same key share the bucket of Burst tokens, which
+// is refilled with Requests tokens per Period
+type RateLimit struct {
+	Id int64 `json:"id" example:"1" swaggerignore:"true"`
+	// Key is the client IP, the login of the
+	// user, the value of the header or the site
+	Key string `json:"key" example:"ip"`
+	// Header is the name of the header of the key "header"
+	Header   string `json:"header" example:"X-Api-Key"`
+	Requests int64  `json:"requests" example:"10"`
+	// Period is in milliseconds
+	Period int64 `json:"period" example:"1000"`
+	// Burst is the size of the bucket,
+	// 0 means the number of Requests
+	Burst int64       `json:"burst" example:"20"`
+	Site  *sites.Site `json:"site"`
+}
+
+var ErrRateLimitNotFound = fmt.Errorf("rate limit not found")
+
+// Create creates rate limit data
+func Create(l *RateLimit) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlRateLimitCreate, l.Key, l.Header, l.Requests, l.Period,
+		l.Burst, l.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&l.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads rate limit data
+func Read(l *RateLimit) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlRateLimitGet, l.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	l.Site = &sites.Site{}
+	if err := row.Scan(&l.Id, &l.Key, &l.Header, &l.Requests, &l.Period, &l.Burst,
+		&l.Site.Id, &l.Site.Name, &l.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRateLimitNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates rate limit data
+func Update(l *RateLimit) error {
+	oldLimit := *l
+	if err := Read(&oldLimit); err != nil {
+		return err
+	}
+	if l.Key == "" {
+		l.Key = oldLimit.Key
+	}
+	if l.Requests == 0 {
+		l.Requests = oldLimit.Requests
+	}
+	if l.Period == 0 {
+		l.Period = oldLimit.Period
+	}
+	l.Site = oldLimit.Site
+
+	if err := db.ConnManager.Exec(sqlRateLimitUpdate, l.Key, l.Header, l.Requests, l.Period, l.Burst,
+		l.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrRateLimitNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes rate limit data
+func Delete(l *RateLimit) error {
+	if err := db.ConnManager.Exec(sqlRateLimitDelete, l.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrRateLimitNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all rate limits from database
+// in the order of their creation
+func List() ([]*RateLimit, error) {
+	limits := []*RateLimit{}
+	rows, cancel, err := db.ConnManager.Query(sqlRateLimitList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return limits, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		limit := RateLimit{Site: &sites.Site{}}
+		if err := rows.Scan(&limit.Id, &limit.Key, &limit.Header, &limit.Requests, &limit.Period,
+			&limit.Burst, &limit.Site.Id, &limit.Site.Name, &limit.Site.Host); err != nil {
+			return nil, err
+		}
+		limits = append(limits, &limit)
+	}
+	return limits, rows.Err()
+}
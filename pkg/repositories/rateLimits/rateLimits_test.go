@@ -0,0 +1,313 @@
+package rateLimits
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlRateLimitUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[5] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE rate_limits SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlRateLimitUpdate, args[5])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlRateLimitDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM rate_limits WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlRateLimitDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlRateLimitCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == KeyIP && args[2] == int64(10) && args[5] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO rate_limits (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlRateLimitCreate, args...)
+		return row, func() {}, nil
+
+	case sqlRateLimitGet:
+		mockRow := mock.NewRows([]string{"id", "key", "header", "requests", "period", "burst", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), "ip", "", int64(10), int64(1000), int64(20), int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM rate_limits r JOIN sites s ON s.id = r.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlRateLimitGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlRateLimitList:
+		mockRows := mock.NewRows([]string{"id", "key", "header", "requests", "period", "burst", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), "ip", "", int64(10), int64(1000), int64(20), int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), "header", "X-Api-Key", int64(100), int64(60000), int64(0), int64(2),
+			"example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM rate_limits r JOIN sites s ON s.id = r.site_id ORDER BY r.id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlRateLimitList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		l *RateLimit
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new rate limit",
+			args: args{l: &RateLimit{
+				Key:      KeyIP,
+				Requests: 10,
+				Period:   1000,
+				Site:     &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new rate limit with non-existence site_id",
+			args: args{l: &RateLimit{
+				Key:      KeyIP,
+				Requests: 10,
+				Period:   1000,
+				Site:     &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.l); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		l *RateLimit
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *RateLimit
+		wantErr bool
+	}{
+		{
+			name: "read existence rate limit",
+			args: args{l: &RateLimit{Id: 1}},
+			want: &RateLimit{
+				Id:       1,
+				Key:      KeyIP,
+				Requests: 10,
+				Period:   1000,
+				Burst:    20,
+				Site:     &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read rate limit with non-existence id",
+			args:    args{l: &RateLimit{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.l); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.l
+				got.Site, tt.want.Site = nil, nil
+				if got != *tt.want {
+					t.Errorf("Read() got: %v, want: %v", tt.args.l, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		l *RateLimit
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *RateLimit
+		wantErr bool
+	}{
+		{
+			name: "update burst of existence rate limit",
+			args: args{l: &RateLimit{Id: 1, Burst: 50}},
+			want: &RateLimit{
+				Id:       1,
+				Key:      KeyIP,
+				Requests: 10,
+				Period:   1000,
+				Burst:    50,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "update rate limit with non-existence id",
+			args:    args{l: &RateLimit{Id: 2, Burst: 50}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.l); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.l
+				got.Site = nil
+				if got != *tt.want {
+					t.Errorf("Update() got: %v, want: %v", tt.args.l, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		l *RateLimit
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete rate limit with existence id",
+			args:    args{l: &RateLimit{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete rate limit with non-existence id",
+			args:    args{l: &RateLimit{Id: 3}},
+			wantErr: ErrRateLimitNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.l); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d rate limits, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Key != KeyHeader || got[1].Header != "X-Api-Key" {
+		t.Errorf("List() got: %v, want rate limit of example.com", got[1])
+	}
+}
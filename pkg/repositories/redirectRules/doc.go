@@ -0,0 +1,6 @@
+// package repositories\redirectRules stores
+// a structure that contains rows data
+// of redirect rule's table, and functions for
+// create, read, update and delete
+// data of redirect rule's table
+package redirectRules
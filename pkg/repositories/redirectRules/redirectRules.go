@@ -0,0 +1,148 @@
+package redirectRules
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlRedirectRuleCreate = "INSERT INTO redirect_rules (kind, source, target, status, site_id) VALUES ($1, $2, $3, $4, $5) RETURNING id;"
+	sqlRedirectRuleGet    = "SELECT r.id, r.kind, r.source, r.target, r.status, s.id, s.name, s.host FROM redirect_rules r JOIN sites s ON s.id = r.site_id WHERE r.id = $1;"
+	sqlRedirectRuleUpdate = "UPDATE redirect_rules SET kind = $1, source = $2, target = $3, status = $4 WHERE id = $5;"
+	sqlRedirectRuleDelete = "DELETE FROM redirect_rules WHERE id = $1;"
+	sqlRedirectRuleList   = "SELECT r.id, r.kind, r.source, r.target, r.status, s.id, s.name, s.host FROM redirect_rules r JOIN sites s ON s.id = r.site_id ORDER BY r.id;"
+)
+
+const (
+	// KindHTTPS redirects the http requests
+	// of the site to https
+	KindHTTPS = "https"
+	// KindHost redirects the requests of
+	// the Source host to the site
+	KindHost = "host"
+	// KindPath redirects the paths matching the Source
+	// regex to the Target, where $1 is the first
+	// capture group
+	KindPath = "path"
+)
+
+// RedirectRule redirects the requests of the site
+// with the Status before the backends are selected
+type RedirectRule struct {
+	Id   int64  `json:"id" example:"1" swaggerignore:"true"`
+	Kind string `json:"kind" example:"path"`
+	// Source is the host of the rule "host"
+	// and the regex of the rule "path"
+	Source string `json:"source" example:"^/old/(.*)$"`
+	// Target is the path or the URL of the rule "path"
+	Target string `json:"target" example:"/new/$1"`
+	// Status is 301, 302, 307 or 308,
+	// 0 means 301
+	Status int64       `json:"status" example:"301"`
+	Site   *sites.Site `json:"site"`
+}
+
+var ErrRedirectRuleNotFound = fmt.Errorf("redirect rule not found")
+
+// Create creates redirect rule data
+func Create(r *RedirectRule) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlRedirectRuleCreate, r.Kind, r.Source, r.Target, r.Status,
+		r.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&r.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads redirect rule data
+func Read(r *RedirectRule) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlRedirectRuleGet, r.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	r.Site = &sites.Site{}
+	if err := row.Scan(&r.Id, &r.Kind, &r.Source, &r.Target, &r.Status,
+		&r.Site.Id, &r.Site.Name, &r.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRedirectRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates redirect rule data
+func Update(r *RedirectRule) error {
+	oldRule := *r
+	if err := Read(&oldRule); err != nil {
+		return err
+	}
+	if r.Kind == "" {
+		r.Kind = oldRule.Kind
+	}
+	if r.Source == "" {
+		r.Source = oldRule.Source
+	}
+	if r.Target == "" {
+		r.Target = oldRule.Target
+	}
+	if r.Status == 0 {
+		r.Status = oldRule.Status
+	}
+	r.Site = oldRule.Site
+
+	if err := db.ConnManager.Exec(sqlRedirectRuleUpdate, r.Kind, r.Source, r.Target, r.Status, r.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrRedirectRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes redirect rule data
+func Delete(r *RedirectRule) error {
+	if err := db.ConnManager.Exec(sqlRedirectRuleDelete, r.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrRedirectRuleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all redirect rules from database
+// in the order of their creation
+func List() ([]*RedirectRule, error) {
+	rules := []*RedirectRule{}
+	rows, cancel, err := db.ConnManager.Query(sqlRedirectRuleList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rules, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		rule := RedirectRule{Site: &sites.Site{}}
+		if err := rows.Scan(&rule.Id, &rule.Kind, &rule.Source, &rule.Target, &rule.Status,
+			&rule.Site.Id, &rule.Site.Name, &rule.Site.Host); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, rows.Err()
+}
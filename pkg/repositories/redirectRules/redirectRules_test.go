@@ -0,0 +1,315 @@
+package redirectRules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlRedirectRuleUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[4] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE redirect_rules SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlRedirectRuleUpdate, args[4])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlRedirectRuleDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM redirect_rules WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlRedirectRuleDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlRedirectRuleCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == KindPath && args[1] == "^/old/(.*)$" && args[4] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO redirect_rules (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlRedirectRuleCreate, args...)
+		return row, func() {}, nil
+
+	case sqlRedirectRuleGet:
+		mockRow := mock.NewRows([]string{"id", "kind", "source", "target", "status", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), "path", "^/old/(.*)$", "/new/$1", int64(301), int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM redirect_rules r JOIN sites s ON s.id = r.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlRedirectRuleGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlRedirectRuleList:
+		mockRows := mock.NewRows([]string{"id", "kind", "source", "target", "status", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), "path", "^/old/(.*)$", "/new/$1", int64(301), int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), "host", "www.example.com", "", int64(308), int64(2),
+			"example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM redirect_rules r JOIN sites s ON s.id = r.site_id ORDER BY r.id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlRedirectRuleList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		r *RedirectRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new redirect rule",
+			args: args{r: &RedirectRule{
+				Kind:   KindPath,
+				Source: "^/old/(.*)$",
+				Target: "/new/$1",
+				Status: 301,
+				Site:   &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new redirect rule with non-existence site_id",
+			args: args{r: &RedirectRule{
+				Kind:   KindPath,
+				Source: "^/old/(.*)$",
+				Target: "/new/$1",
+				Status: 301,
+				Site:   &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.r); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		r *RedirectRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *RedirectRule
+		wantErr bool
+	}{
+		{
+			name: "read existence redirect rule",
+			args: args{r: &RedirectRule{Id: 1}},
+			want: &RedirectRule{
+				Id:     1,
+				Kind:   KindPath,
+				Source: "^/old/(.*)$",
+				Target: "/new/$1",
+				Status: 301,
+				Site:   &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read redirect rule with non-existence id",
+			args:    args{r: &RedirectRule{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.r); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.r
+				got.Site, tt.want.Site = nil, nil
+				if got != *tt.want {
+					t.Errorf("Read() got: %v, want: %v", tt.args.r, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		r *RedirectRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *RedirectRule
+		wantErr bool
+	}{
+		{
+			name: "update status of existence redirect rule",
+			args: args{r: &RedirectRule{Id: 1, Status: 308}},
+			want: &RedirectRule{
+				Id:     1,
+				Kind:   KindPath,
+				Source: "^/old/(.*)$",
+				Target: "/new/$1",
+				Status: 308,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "update redirect rule with non-existence id",
+			args:    args{r: &RedirectRule{Id: 2, Status: 308}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.r); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				got := *tt.args.r
+				got.Site = nil
+				if got != *tt.want {
+					t.Errorf("Update() got: %v, want: %v", tt.args.r, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		r *RedirectRule
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete redirect rule with existence id",
+			args:    args{r: &RedirectRule{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete redirect rule with non-existence id",
+			args:    args{r: &RedirectRule{Id: 3}},
+			wantErr: ErrRedirectRuleNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.r); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d redirect rules, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Kind != KindHost || got[1].Source != "www.example.com" {
+		t.Errorf("List() got: %v, want redirect rule of example.com", got[1])
+	}
+}
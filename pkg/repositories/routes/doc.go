@@ -0,0 +1,6 @@
+// package repositories\routes stores
+// a structure that contains rows data
+// of route's table, and functions for
+// create, read, update and delete
+// data of route's table
+package routes
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlRouteCreate = "INSERT INTO routes (prefix, regex, priority, pool, strip_prefix, add_prefix, rewrite_regex, rewrite_replacement, site_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;"
+	sqlRouteGet    = "SELECT r.id, r.prefix, r.regex, r.priority, r.pool, r.strip_prefix, r.add_prefix, r.rewrite_regex, r.rewrite_replacement, s.id, s.name, s.host FROM routes r JOIN sites s ON s.id = r.site_id WHERE r.id = $1;"
+	sqlRouteUpdate = "UPDATE routes SET prefix = $1, regex = $2, priority = $3, pool = $4, strip_prefix = $5, add_prefix = $6, rewrite_regex = $7, rewrite_replacement = $8 WHERE id = $9;"
+	sqlRouteDelete = "DELETE FROM routes WHERE id = $1;"
+	sqlRouteList   = "SELECT r.id, r.prefix, r.regex, r.priority, r.pool, r.strip_prefix, r.add_prefix, r.rewrite_regex, r.rewrite_replacement, s.id, s.name, s.host FROM routes r JOIN sites s ON s.id = r.site_id;"
+)
+
+// Route sends the requests of the site, which path
+// matches the prefix or the regex, to the backends
+// of the pool
+type Route struct {
+	Id       int64       `json:"id" example:"1" swaggerignore:"true"`
+	Prefix   string      `json:"prefix" example:"/api"`
+	Regex    string      `json:"regex" example:"^/v[0-9]+/api/"`
+	Priority int64       `json:"priority" example:"0"`
+	Pool     string      `json:"pool" example:"api"`
+	Rewrite  Rewrite     `json:"rewrite"`
+	Site     *sites.Site `json:"site"`
+}
+
+// Rewrite stores the rules of rewriting the path of
+// the request, the same rules are set on the sites
+type Rewrite = sites.Rewrite
+
+var ErrRouteNotFound = fmt.Errorf("route not found")
+
+// Create creates route data
+func Create(r *Route) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlRouteCreate, r.Prefix, r.Regex, r.Priority, r.Pool,
+		r.Rewrite.StripPrefix, r.Rewrite.AddPrefix, r.Rewrite.Regex, r.Rewrite.Replacement, r.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&r.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads route data
+func Read(r *Route) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlRouteGet, r.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	r.Site = &sites.Site{}
+	if err := row.Scan(&r.Id, &r.Prefix, &r.Regex, &r.Priority, &r.Pool, &r.Rewrite.StripPrefix,
+		&r.Rewrite.AddPrefix, &r.Rewrite.Regex, &r.Rewrite.Replacement, &r.Site.Id, &r.Site.Name, &r.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRouteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates route data
+func Update(r *Route) error {
+	oldRoute := *r
+	if err := Read(&oldRoute); err != nil {
+		return err
+	}
+	r.Site = oldRoute.Site
+
+	if err := db.ConnManager.Exec(sqlRouteUpdate, r.Prefix, r.Regex, r.Priority, r.Pool,
+		r.Rewrite.StripPrefix, r.Rewrite.AddPrefix, r.Rewrite.Regex, r.Rewrite.Replacement, r.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrRouteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes route data
+func Delete(r *Route) error {
+	if err := db.ConnManager.Exec(sqlRouteDelete, r.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrRouteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all routes from database
+func List() ([]*Route, error) {
+	routes := []*Route{}
+	rows, cancel, err := db.ConnManager.Query(sqlRouteList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return routes, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		route := Route{Site: &sites.Site{}}
+		if err := rows.Scan(&route.Id, &route.Prefix, &route.Regex, &route.Priority, &route.Pool,
+			&route.Rewrite.StripPrefix, &route.Rewrite.AddPrefix, &route.Rewrite.Regex, &route.Rewrite.Replacement,
+			&route.Site.Id, &route.Site.Name, &route.Site.Host); err != nil {
+			return nil, err
+		}
+		routes = append(routes, &route)
+	}
+	return routes, rows.Err()
+}
@@ -0,0 +1,301 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+	"testing"
+	"time"
+)
+
+type fakeDbManager struct{}
+
+func (f fakeDbManager) Connect(cfg db.DbConfig) error {
+	return nil
+}
+
+func (f fakeDbManager) Close() error {
+	return nil
+}
+
+func (f fakeDbManager) Exec(query string, args ...interface{}) error {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	ctx := context.TODO()
+	queryCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	switch query {
+	case sqlRouteUpdate:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[8] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^UPDATE routes SET .* WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlRouteUpdate, args[8])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+
+	case sqlRouteDelete:
+		mockResult := sqlmock.NewResult(5, 0)
+		if args[0] == int64(1) {
+			mockResult = sqlmock.NewResult(5, 1)
+		}
+		mock.ExpectExec("^DELETE FROM routes WHERE .*;$").WillReturnResult(mockResult)
+		result, err := dbMock.ExecContext(queryCtx, sqlRouteDelete, args[0])
+		if err != nil {
+			return err
+		}
+		row, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if row == 0 {
+			return db.ErrNothingDone
+		}
+	default:
+		return fmt.Errorf("unrecognized sql query")
+	}
+	return nil
+}
+
+func (f fakeDbManager) QueryRow(query string, args ...interface{}) (*sql.Row, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := dbMock.Close(); err != nil {
+			return
+		}
+	}()
+
+	switch query {
+	case sqlRouteCreate:
+		mockRow := mock.NewRows([]string{"id"})
+		if args[0] == "/api" && args[3] == "api" && args[8] == int64(1) {
+			mockRow.AddRow(int64(1))
+		}
+		mock.ExpectQuery("^INSERT INTO routes (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlRouteCreate, args...)
+		return row, func() {}, nil
+
+	case sqlRouteGet:
+		mockRow := mock.NewRows([]string{"id", "prefix", "regex", "priority", "pool",
+			"strip_prefix", "add_prefix", "rewrite_regex", "rewrite_replacement", "site_id", "site_name", "site_host"})
+		if args[0] == int64(1) {
+			mockRow.AddRow(int64(1), "/api", "", int64(0), "api", "/api", "", "", "", int64(1), "vk", "vk.com")
+		}
+		mock.ExpectQuery("^SELECT (.+) FROM routes r JOIN sites s ON s.id = r.site_id WHERE .*;$").
+			WillReturnRows(mockRow)
+		row := dbMock.QueryRow(sqlRouteGet, args[0])
+		return row, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func (f fakeDbManager) Query(query string, args ...interface{}) (*sql.Rows, func(), error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch query {
+	case sqlRouteList:
+		mockRows := mock.NewRows([]string{"id", "prefix", "regex", "priority", "pool",
+			"strip_prefix", "add_prefix", "rewrite_regex", "rewrite_replacement", "site_id", "site_name", "site_host"})
+		mockRows.AddRow(int64(1), "/api", "", int64(0), "api", "/api", "", "", "", int64(1), "vk", "vk.com")
+		mockRows.AddRow(int64(2), "", "^/v[0-9]+/", int64(10), "versioned", "", "", "^/v([0-9]+)/(.*)$", "/api/v$1/$2", int64(2), "example", "example.com")
+		mock.ExpectQuery("^SELECT (.+) FROM routes r JOIN sites s ON s.id = r.site_id;$").
+			WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlRouteList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unrecognized sql query")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type args struct {
+		r *Route
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "create new route",
+			args: args{r: &Route{
+				Prefix: "/api",
+				Pool:   "api",
+				Site:   &sites.Site{Id: 1},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "create new route with non-existence site_id",
+			args: args{r: &Route{
+				Prefix: "/api",
+				Pool:   "api",
+				Site:   &sites.Site{Id: 2},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Create(tt.args.r); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	type args struct {
+		r *Route
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Route
+		wantErr bool
+	}{
+		{
+			name: "read existence route",
+			args: args{r: &Route{Id: 1}},
+			want: &Route{
+				Id:      1,
+				Prefix:  "/api",
+				Pool:    "api",
+				Rewrite: Rewrite{StripPrefix: "/api"},
+				Site:    &sites.Site{Id: 1, Name: "vk", Host: "vk.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read route with non-existence id",
+			args:    args{r: &Route{Id: 3}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Read(tt.args.r); (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				condition := tt.args.r.Prefix != tt.want.Prefix || tt.args.r.Pool != tt.want.Pool
+				condition = condition || tt.args.r.Rewrite != tt.want.Rewrite
+				condition = condition || *tt.args.r.Site != *tt.want.Site
+				if condition {
+					t.Errorf("Read() got: %v, want: %v", tt.args.r, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type args struct {
+		r *Route
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "update priority of existence route",
+			args:    args{r: &Route{Id: 1, Prefix: "/api", Priority: 5, Pool: "api"}},
+			wantErr: false,
+		},
+		{
+			name:    "update route with non-existence id",
+			args:    args{r: &Route{Id: 2, Prefix: "/api"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Update(tt.args.r); (err != nil) != tt.wantErr {
+				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.args.r.Site.Host != "vk.com" {
+				t.Errorf("Update() site = %v, want vk.com", tt.args.r.Site)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		r *Route
+	}
+	db.ConnManager = fakeDbManager{}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "delete route with existence id",
+			args:    args{r: &Route{Id: 1}},
+			wantErr: nil,
+		},
+		{
+			name:    "delete route with non-existence id",
+			args:    args{r: &Route{Id: 3}},
+			wantErr: ErrRouteNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Delete(tt.args.r); err != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeDbManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() got %d routes, want 2", len(got))
+	}
+	if got[1].Site.Host != "example.com" || got[1].Regex != "^/v[0-9]+/" || got[1].Priority != 10 ||
+		got[1].Rewrite.Replacement != "/api/v$1/$2" {
+		t.Errorf("List() got: %v, want route of example.com", got[1])
+	}
+}
@@ -0,0 +1,6 @@
+// package repositories\siteHosts stores
+// a structure that contains rows data
+// of site host's table, and functions for
+// create, read, update and delete
+// data of site host's table
+package siteHosts
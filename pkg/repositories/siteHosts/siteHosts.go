@@ -0,0 +1,117 @@
+package siteHosts
+
+import (
+	"database/sql"
+	"fmt"
+	"reverseProxy/pkg/db"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+const (
+	sqlSiteHostCreate = "INSERT INTO site_hosts (pattern, site_id) VALUES ($1, $2) RETURNING id;"
+	sqlSiteHostGet    = "SELECT h.id, h.pattern, s.id, s.name, s.host FROM site_hosts h JOIN sites s ON s.id = h.site_id WHERE h.id = $1;"
+	sqlSiteHostUpdate = "UPDATE site_hosts SET pattern = $1 WHERE id = $2;"
+	sqlSiteHostDelete = "DELETE FROM site_hosts WHERE id = $1;"
+	sqlSiteHostList   = "SELECT h.id, h.pattern, s.id, s.name, s.host FROM site_hosts h JOIN sites s ON s.id = h.site_id ORDER BY h.id;"
+)
+
+// SiteHost is the alias of the host of the site
+type SiteHost struct {
+	Id int64 `json:"id" example:"1" swaggerignore:"true"`
+	// Pattern is the host "www.example.com", the wildcard
+	// "*.example.com" or the regex "~^api[0-9]+\.example\.com$"
+	Pattern string      `json:"pattern" example:"*.example.com"`
+	Site    *sites.Site `json:"site"`
+}
+
+var ErrSiteHostNotFound = fmt.Errorf("site host not found")
+
+// Create creates site host data
+func Create(h *SiteHost) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlSiteHostCreate, h.Pattern, h.Site.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := row.Scan(&h.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Read reads site host data
+func Read(h *SiteHost) error {
+	row, cancel, err := db.ConnManager.QueryRow(sqlSiteHostGet, h.Id)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	h.Site = &sites.Site{}
+	if err := row.Scan(&h.Id, &h.Pattern, &h.Site.Id, &h.Site.Name, &h.Site.Host); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrSiteHostNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Update updates site host data
+func Update(h *SiteHost) error {
+	oldHost := *h
+	if err := Read(&oldHost); err != nil {
+		return err
+	}
+	if h.Pattern == "" {
+		h.Pattern = oldHost.Pattern
+	}
+	h.Site = oldHost.Site
+
+	if err := db.ConnManager.Exec(sqlSiteHostUpdate, h.Pattern, h.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrSiteHostNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes site host data
+func Delete(h *SiteHost) error {
+	if err := db.ConnManager.Exec(sqlSiteHostDelete, h.Id); err != nil {
+		if err == db.ErrNothingDone {
+			return ErrSiteHostNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns all site hosts from database
+// in the order of their creation
+func List() ([]*SiteHost, error) {
+	hosts := []*SiteHost{}
+	rows, cancel, err := db.ConnManager.Query(sqlSiteHostList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return hosts, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		host := SiteHost{Site: &sites.Site{}}
+		if err := rows.Scan(&host.Id, &host.Pattern, &host.Site.Id, &host.Site.Name,
+			&host.Site.Host); err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, &host)
+	}
+	return hosts, rows.Err()
+}
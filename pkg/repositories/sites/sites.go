@@ -8,21 +8,170 @@ import (
 )
 
 const (
-	sqlSiteCreate         = "INSERT INTO sites (name, host) VALUES ($1, $2) RETURNING id;"
-	sqlSiteGet            = "SELECT * FROM sites WHERE id=$1;"
-	sqlSiteUpdate         = "UPDATE sites SET name=$1, host=$2 WHERE id=$3;"
+	sqlSiteCreate         = "INSERT INTO sites (name, host, trusted_proxies, forwarded, retry_attempts, retry_backoff, connect_timeout, response_header_timeout, idle_timeout, request_timeout, cache_enabled, cache_serve_stale, compression_enabled, compression_types, compression_min_size, maintenance_enabled, maintenance_retry_after, maintenance_bypass_ips, maintenance_bypass_logins, catch_all_enabled, catch_all_action, catch_all_status, catch_all_body, catch_all_target, catch_all_pool, mirror_pool, mirror_percent, mirror_max_body_size, mirror_compare, mirror_compare_headers, mirror_ignore_paths, rewrite_strip_prefix, rewrite_regex, rewrite_replacement, rewrite_add_prefix) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35) RETURNING id;"
+	sqlSiteGet            = "SELECT id, name, host, trusted_proxies, forwarded, retry_attempts, retry_backoff, connect_timeout, response_header_timeout, idle_timeout, request_timeout, cache_enabled, cache_serve_stale, compression_enabled, compression_types, compression_min_size, maintenance_enabled, maintenance_retry_after, maintenance_bypass_ips, maintenance_bypass_logins, catch_all_enabled, catch_all_action, catch_all_status, catch_all_body, catch_all_target, catch_all_pool, mirror_pool, mirror_percent, mirror_max_body_size, mirror_compare, mirror_compare_headers, mirror_ignore_paths, rewrite_strip_prefix, rewrite_regex, rewrite_replacement, rewrite_add_prefix FROM sites WHERE id=$1;"
+	sqlSiteUpdate         = "UPDATE sites SET name=$1, host=$2, trusted_proxies=$3, forwarded=$4, retry_attempts=$5, retry_backoff=$6, connect_timeout=$7, response_header_timeout=$8, idle_timeout=$9, request_timeout=$10, cache_enabled=$11, cache_serve_stale=$12, compression_enabled=$13, compression_types=$14, compression_min_size=$15, maintenance_enabled=$16, maintenance_retry_after=$17, maintenance_bypass_ips=$18, maintenance_bypass_logins=$19, catch_all_enabled=$20, catch_all_action=$21, catch_all_status=$22, catch_all_body=$23, catch_all_target=$24, catch_all_pool=$25, mirror_pool=$26, mirror_percent=$27, mirror_max_body_size=$28, mirror_compare=$29, mirror_compare_headers=$30, mirror_ignore_paths=$31, rewrite_strip_prefix=$32, rewrite_regex=$33, rewrite_replacement=$34, rewrite_add_prefix=$35 WHERE id=$36;"
 	sqlSiteDelete         = "DELETE FROM sites WHERE id=$1;"
+	sqlSiteList           = "SELECT id, name, host, trusted_proxies, forwarded, retry_attempts, retry_backoff, connect_timeout, response_header_timeout, idle_timeout, request_timeout, cache_enabled, cache_serve_stale, compression_enabled, compression_types, compression_min_size, maintenance_enabled, maintenance_retry_after, maintenance_bypass_ips, maintenance_bypass_logins, catch_all_enabled, catch_all_action, catch_all_status, catch_all_body, catch_all_target, catch_all_pool, mirror_pool, mirror_percent, mirror_max_body_size, mirror_compare, mirror_compare_headers, mirror_ignore_paths, rewrite_strip_prefix, rewrite_regex, rewrite_replacement, rewrite_add_prefix FROM sites;"
 	sqlNeedsAuthorization = "SELECT c.login, c.password, s.name FROM credentials c JOIN sites s ON s.id = c.site_id WHERE s.host = $1;"
 )
 
+const (
+	// CatchAllSite serves the unmatched
+	// hosts as the default site
+	CatchAllSite = "site"
+	// CatchAllResponse sends the Body
+	// to the unmatched hosts
+	CatchAllResponse = "response"
+	// CatchAllRedirect redirects the
+	// unmatched hosts to the Target
+	CatchAllRedirect = "redirect"
+	// CatchAllPool proxies the unmatched hosts
+	// to the backends of the Pool of the site
+	CatchAllPool = "pool"
+)
+
 var (
 	ErrSiteNotFound = fmt.Errorf("site not found")
 )
 
 type Site struct {
-	Id   int64  `json:"id" example:"1" swaggerignore:"true"`
-	Name string `json:"name" example:"site"`
-	Host string `json:"host" example:"site.com"`
+	Id             int64               `json:"id" example:"1" swaggerignore:"true"`
+	Name           string              `json:"name" example:"site"`
+	Host           string              `json:"host" example:"site.com"`
+	TrustedProxies string              `json:"trusted_proxies" example:"10.0.0.0/8,192.168.1.1"`
+	Forwarded      bool                `json:"forwarded" example:"false"`
+	Retry          RetrySettings       `json:"retry"`
+	Timeouts       Timeouts            `json:"timeouts"`
+	Cache          CacheSettings       `json:"cache"`
+	Compression    CompressionSettings `json:"compression"`
+	Maintenance    MaintenanceSettings `json:"maintenance"`
+	CatchAll       CatchAllSettings    `json:"catch_all"`
+	Mirror         MirrorSettings      `json:"mirror"`
+	Rewrite        Rewrite             `json:"rewrite"`
+}
+
+// RetrySettings stores the settings of sending
+// the failed request to another backend
+type RetrySettings struct {
+	// Attempts is the number of attempts including the
+	// first one, 0 means the default number of attempts
+	Attempts int64 `json:"attempts" example:"3"`
+	// Backoff is the delay before the second attempt in
+	// milliseconds, it is doubled for each next attempt
+	Backoff int64 `json:"backoff" example:"100"`
+}
+
+// Timeouts stores the timeouts of the requests to the
+// backends of the site in milliseconds, 0 means the
+// default timeout of the transport
+type Timeouts struct {
+	// Connect limits establishing the connection
+	Connect int64 `json:"connect" example:"5000"`
+	// ResponseHeader limits waiting for the response
+	// header after the request is sent
+	ResponseHeader int64 `json:"response_header" example:"10000"`
+	// Idle limits keeping the unused connection
+	Idle int64 `json:"idle" example:"90000"`
+	// Request limits the whole request including
+	// reading the response body
+	Request int64 `json:"request" example:"30000"`
+}
+
+// CacheSettings stores the settings of
+// caching the responses of the backends
+type CacheSettings struct {
+	// Enabled sends the fresh stored responses
+	Enabled bool `json:"enabled" example:"true"`
+	// ServeStale sends the last stored responses
+	// when the backends are dead or fail
+	ServeStale bool `json:"serve_stale" example:"true"`
+}
+
+// CompressionSettings stores the settings of compressing
+// the responses of the backends of the site
+type CompressionSettings struct {
+	// Enabled compresses the responses with gzip
+	// or brotli accepted by the user
+	Enabled bool `json:"enabled" example:"true"`
+	// Types is the comma separated list of the compressed
+	// MIME types, empty means the default types
+	Types string `json:"types" example:"text/html,application/json"`
+	// MinSize is the minimum size of the compressed
+	// body in bytes, 0 means the default size
+	MinSize int64 `json:"min_size" example:"1024"`
+}
+
+// MaintenanceSettings stores the settings of the maintenance
+// of the site, the requests get 503 without contacting the
+// backends unless the client bypasses the maintenance
+type MaintenanceSettings struct {
+	Enabled bool `json:"enabled" example:"false"`
+	// RetryAfter is sent in Retry-After in seconds,
+	// 0 means the header is not sent
+	RetryAfter int64 `json:"retry_after" example:"600"`
+	// BypassIPs is the comma separated list of the CIDRs
+	// and addresses of the clients reaching the backends
+	BypassIPs string `json:"bypass_ips" example:"10.0.0.0/8,192.168.1.1"`
+	// BypassLogins is the comma separated list of the logins
+	// of the credentials of the site reaching the backends
+	BypassLogins string `json:"bypass_logins" example:"admin,deploy"`
+}
+
+// CatchAllSettings stores the settings of the default site,
+// which gets the requests of the hosts matching no site
+type CatchAllSettings struct {
+	// Enabled makes the site the default site,
+	// only one site may be the default site
+	Enabled bool `json:"enabled" example:"false"`
+	// Action is "site" (serve as the site, the default),
+	// "response" (send the Body), "redirect" (redirect to
+	// the Target) or "pool" (proxy to the backends of the Pool)
+	Action string `json:"action" example:"response"`
+	// Status is the status of the response (200 if 0)
+	// or of the redirect (302 if 0)
+	Status int64  `json:"status" example:"200"`
+	Body   string `json:"body" example:"ok"`
+	// Target is the URL of the redirect, without the
+	// path the path of the request is kept
+	Target string `json:"target" example:"https://example.com"`
+	// Pool is the pool of the backends of the site
+	Pool string `json:"pool" example:"default"`
+}
+
+// MirrorSettings stores the settings of copying the requests
+// of the site to the shadow backends, whose responses are
+// discarded
+type MirrorSettings struct {
+	// Pool is the pool of the shadow backends
+	// of the site, empty disables the mirroring
+	Pool string `json:"pool" example:"shadow"`
+	// Percent is the percentage of the copied requests
+	Percent int64 `json:"percent" example:"10"`
+	// MaxBodySize is the maximum size of the body of the
+	// copied request in bytes, 0 means the default size
+	MaxBodySize int64 `json:"max_body_size" example:"1048576"`
+	// Compare compares the responses of the shadow backends
+	// with the responses of the primary backends
+	Compare bool `json:"compare" example:"true"`
+	// CompareHeaders is the comma separated list
+	// of the compared headers of the responses
+	CompareHeaders string `json:"compare_headers" example:"Content-Type,Location"`
+	// IgnorePaths is the comma separated list of the paths
+	// of the JSON bodies skipped in the comparison, "*"
+	// matches any key of an object or item of an array
+	IgnorePaths string `json:"ignore_paths" example:"updated_at,items.*.id"`
+}
+
+// Rewrite stores the rules of rewriting the path
+// of the request before it is sent to the backend,
+// they are applied in the order of the fields. The
+// rules of the route replace the rules of its site
+type Rewrite struct {
+	StripPrefix string `json:"strip_prefix" example:"/billing"`
+	Regex       string `json:"regex" example:"^/v1/(.*)$"`
+	Replacement string `json:"replacement" example:"/api/$1"`
+	AddPrefix   string `json:"add_prefix" example:"/internal"`
 }
 
 // Authorization checks the received host
@@ -52,7 +201,15 @@ func Authorization(hostName string) (bool, error) {
 
 // Create creates site data
 func Create(site *Site) error {
-	row, cancel, err := db.ConnManager.QueryRow(sqlSiteCreate, site.Name, site.Host)
+	row, cancel, err := db.ConnManager.QueryRow(sqlSiteCreate, site.Name, site.Host, site.TrustedProxies, site.Forwarded,
+		site.Retry.Attempts, site.Retry.Backoff, site.Timeouts.Connect, site.Timeouts.ResponseHeader,
+		site.Timeouts.Idle, site.Timeouts.Request, site.Cache.Enabled, site.Cache.ServeStale,
+		site.Compression.Enabled, site.Compression.Types, site.Compression.MinSize,
+		site.Maintenance.Enabled, site.Maintenance.RetryAfter, site.Maintenance.BypassIPs,
+		site.Maintenance.BypassLogins, site.CatchAll.Enabled, site.CatchAll.Action, site.CatchAll.Status,
+		site.CatchAll.Body, site.CatchAll.Target, site.CatchAll.Pool, site.Mirror.Pool, site.Mirror.Percent,
+		site.Mirror.MaxBodySize, site.Mirror.Compare, site.Mirror.CompareHeaders, site.Mirror.IgnorePaths,
+		site.Rewrite.StripPrefix, site.Rewrite.Regex, site.Rewrite.Replacement, site.Rewrite.AddPrefix)
 	if err != nil {
 		if err == db.ErrNothingDone {
 			return ErrSiteNotFound
@@ -74,7 +231,16 @@ func GetSite(site *Site) error {
 		return err
 	}
 	defer cancel()
-	if err := row.Scan(&site.Id, &site.Name, &site.Host); err != nil {
+	if err := row.Scan(&site.Id, &site.Name, &site.Host, &site.TrustedProxies, &site.Forwarded,
+		&site.Retry.Attempts, &site.Retry.Backoff, &site.Timeouts.Connect, &site.Timeouts.ResponseHeader,
+		&site.Timeouts.Idle, &site.Timeouts.Request, &site.Cache.Enabled, &site.Cache.ServeStale,
+		&site.Compression.Enabled, &site.Compression.Types, &site.Compression.MinSize,
+		&site.Maintenance.Enabled, &site.Maintenance.RetryAfter, &site.Maintenance.BypassIPs,
+		&site.Maintenance.BypassLogins, &site.CatchAll.Enabled, &site.CatchAll.Action, &site.CatchAll.Status,
+		&site.CatchAll.Body, &site.CatchAll.Target, &site.CatchAll.Pool, &site.Mirror.Pool, &site.Mirror.Percent,
+		&site.Mirror.MaxBodySize, &site.Mirror.Compare, &site.Mirror.CompareHeaders,
+		&site.Mirror.IgnorePaths, &site.Rewrite.StripPrefix, &site.Rewrite.Regex, &site.Rewrite.Replacement,
+		&site.Rewrite.AddPrefix); err != nil {
 		if err == sql.ErrNoRows {
 			return ErrSiteNotFound
 		}
@@ -110,7 +276,17 @@ func UpdateSite(site *Site) error {
 		site.Host = oldSite.Host
 	}
 
-	if err := db.ConnManager.Exec(sqlSiteUpdate, site.Name, site.Host, site.Id); err != nil {
+	if err := db.ConnManager.Exec(sqlSiteUpdate, site.Name, site.Host, site.TrustedProxies,
+		site.Forwarded, site.Retry.Attempts, site.Retry.Backoff, site.Timeouts.Connect,
+		site.Timeouts.ResponseHeader, site.Timeouts.Idle, site.Timeouts.Request,
+		site.Cache.Enabled, site.Cache.ServeStale, site.Compression.Enabled,
+		site.Compression.Types, site.Compression.MinSize, site.Maintenance.Enabled,
+		site.Maintenance.RetryAfter, site.Maintenance.BypassIPs, site.Maintenance.BypassLogins,
+		site.CatchAll.Enabled, site.CatchAll.Action, site.CatchAll.Status, site.CatchAll.Body,
+		site.CatchAll.Target, site.CatchAll.Pool, site.Mirror.Pool, site.Mirror.Percent,
+		site.Mirror.MaxBodySize, site.Mirror.Compare, site.Mirror.CompareHeaders, site.Mirror.IgnorePaths,
+		site.Rewrite.StripPrefix, site.Rewrite.Regex, site.Rewrite.Replacement, site.Rewrite.AddPrefix,
+		site.Id); err != nil {
 		if err == db.ErrNothingDone {
 			return ErrSiteNotFound
 		}
@@ -118,3 +294,39 @@ func UpdateSite(site *Site) error {
 	}
 	return nil
 }
+
+// List returns all sites from database
+func List() ([]*Site, error) {
+	sites := []*Site{}
+	rows, cancel, err := db.ConnManager.Query(sqlSiteList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sites, nil
+		}
+		return nil, err
+	}
+	defer cancel()
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	for rows.Next() {
+		site := Site{}
+		if err := rows.Scan(&site.Id, &site.Name, &site.Host, &site.TrustedProxies, &site.Forwarded,
+			&site.Retry.Attempts, &site.Retry.Backoff, &site.Timeouts.Connect, &site.Timeouts.ResponseHeader,
+			&site.Timeouts.Idle, &site.Timeouts.Request, &site.Cache.Enabled, &site.Cache.ServeStale,
+			&site.Compression.Enabled, &site.Compression.Types, &site.Compression.MinSize,
+			&site.Maintenance.Enabled, &site.Maintenance.RetryAfter, &site.Maintenance.BypassIPs,
+			&site.Maintenance.BypassLogins, &site.CatchAll.Enabled, &site.CatchAll.Action,
+			&site.CatchAll.Status, &site.CatchAll.Body, &site.CatchAll.Target, &site.CatchAll.Pool,
+			&site.Mirror.Pool, &site.Mirror.Percent, &site.Mirror.MaxBodySize, &site.Mirror.Compare,
+			&site.Mirror.CompareHeaders, &site.Mirror.IgnorePaths, &site.Rewrite.StripPrefix,
+			&site.Rewrite.Regex, &site.Rewrite.Replacement, &site.Rewrite.AddPrefix); err != nil {
+			return nil, err
+		}
+		sites = append(sites, &site)
+	}
+	return sites, rows.Err()
+}
@@ -55,11 +55,12 @@ func (f fakeConnManager) Exec(query string, args ...interface{}) error {
 		}
 	case sqlSiteUpdate:
 		mockResult := sqlmock.NewResult(5, 0)
-		if args[2] == int64(1) {
+		id := args[len(args)-1]
+		if id == int64(1) {
 			mockResult = sqlmock.NewResult(5, 1)
 		}
 		mock.ExpectExec("^UPDATE sites SET (.+) WHERE .*;$").WillReturnResult(mockResult)
-		result, err := dbMock.ExecContext(queryCtx, sqlSiteUpdate, args[2])
+		result, err := dbMock.ExecContext(queryCtx, sqlSiteUpdate, id)
 		if err != nil {
 			return err
 		}
@@ -94,13 +95,25 @@ func (f fakeConnManager) QueryRow(query string, args ...interface{}) (*sql.Row,
 			mockRows.AddRow(int64(1))
 		}
 		mock.ExpectQuery("^INSERT INTO (.+) VALUES .* RETURNING id;$").WillReturnRows(mockRows)
-		row := dbMock.QueryRow(sqlSiteCreate, args[0], args[1])
+		row := dbMock.QueryRow(sqlSiteCreate, args...)
 		return row, func() {}, nil
 
 	case sqlSiteGet:
-		mockRows := sqlmock.NewRows([]string{"id", "name", "host"})
+		mockRows := sqlmock.NewRows([]string{"id", "name", "host", "trusted_proxies", "forwarded",
+			"retry_attempts", "retry_backoff", "connect_timeout", "response_header_timeout", "idle_timeout",
+			"request_timeout", "cache_enabled", "cache_serve_stale", "compression_enabled",
+			"compression_types", "compression_min_size", "maintenance_enabled", "maintenance_retry_after",
+			"maintenance_bypass_ips", "maintenance_bypass_logins", "catch_all_enabled", "catch_all_action",
+			"catch_all_status", "catch_all_body", "catch_all_target", "catch_all_pool",
+			"mirror_pool", "mirror_percent", "mirror_max_body_size", "mirror_compare", "mirror_compare_headers",
+			"mirror_ignore_paths", "rewrite_strip_prefix", "rewrite_regex", "rewrite_replacement",
+			"rewrite_add_prefix"})
 		if args[0] == int64(1) {
-			mockRows.AddRow(int64(1), "vk", "vk.com")
+			mockRows.AddRow(int64(1), "vk", "vk.com", "10.0.0.0/8", true, int64(3), int64(100),
+				int64(5000), int64(0), int64(0), int64(30000), true, true, true, "text/html", int64(1024),
+				true, int64(600), "10.0.0.0/8", "admin", false, "", int64(0), "", "", "",
+				"shadow", int64(10), int64(0), true, "Content-Type", "updated_at",
+				"/billing", "", "", "")
 		}
 
 		mock.ExpectQuery("^SELECT .+ FROM sites .*;$").WillReturnRows(mockRows)
@@ -133,6 +146,31 @@ func (f fakeConnManager) Query(query string, args ...interface{}) (*sql.Rows, fu
 			return nil, nil, err
 		}
 		return rows, func() {}, nil
+	case sqlSiteList:
+		mockRows := sqlmock.NewRows([]string{"id", "name", "host", "trusted_proxies", "forwarded",
+			"retry_attempts", "retry_backoff", "connect_timeout", "response_header_timeout", "idle_timeout",
+			"request_timeout", "cache_enabled", "cache_serve_stale", "compression_enabled",
+			"compression_types", "compression_min_size", "maintenance_enabled", "maintenance_retry_after",
+			"maintenance_bypass_ips", "maintenance_bypass_logins", "catch_all_enabled", "catch_all_action",
+			"catch_all_status", "catch_all_body", "catch_all_target", "catch_all_pool",
+			"mirror_pool", "mirror_percent", "mirror_max_body_size", "mirror_compare", "mirror_compare_headers",
+			"mirror_ignore_paths", "rewrite_strip_prefix", "rewrite_regex", "rewrite_replacement",
+			"rewrite_add_prefix"})
+		mockRows.AddRow(int64(1), "vk", "vk.com", "10.0.0.0/8", true, int64(3), int64(100),
+			int64(5000), int64(0), int64(0), int64(30000), true, true, true, "text/html", int64(1024),
+			true, int64(600), "10.0.0.0/8", "admin", false, "", int64(0), "", "", "",
+			"shadow", int64(10), int64(0), true, "Content-Type", "updated_at",
+			"/billing", "", "", "")
+		mockRows.AddRow(int64(2), "example", "example.com", "", false, int64(0), int64(0),
+			int64(0), int64(0), int64(0), int64(0), false, false, false, "", int64(0),
+			false, int64(0), "", "", true, "response", int64(200), "ok", "", "",
+			"", int64(0), int64(0), false, "", "", "", "", "", "")
+		mock.ExpectQuery("^SELECT (.+) FROM sites;$").WillReturnRows(mockRows)
+		rows, err := dbMock.Query(sqlSiteList)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
 	default:
 		return nil, nil, fmt.Errorf("unrecognized sql query")
 	}
@@ -407,3 +445,32 @@ func TestUpdateSite(t *testing.T) {
 		})
 	}
 }
+
+func TestList(t *testing.T) {
+	db.ConnManager = fakeConnManager{}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []*Site{
+		{Id: 1, Name: "vk", Host: "vk.com", TrustedProxies: "10.0.0.0/8", Forwarded: true,
+			Retry: RetrySettings{Attempts: 3, Backoff: 100}, Timeouts: Timeouts{Connect: 5000, Request: 30000},
+			Cache:       CacheSettings{Enabled: true, ServeStale: true},
+			Compression: CompressionSettings{Enabled: true, Types: "text/html", MinSize: 1024},
+			Maintenance: MaintenanceSettings{Enabled: true, RetryAfter: 600, BypassIPs: "10.0.0.0/8",
+				BypassLogins: "admin"},
+			Mirror: MirrorSettings{Pool: "shadow", Percent: 10, Compare: true, CompareHeaders: "Content-Type",
+				IgnorePaths: "updated_at"},
+			Rewrite: Rewrite{StripPrefix: "/billing"}},
+		{Id: 2, Name: "example", Host: "example.com",
+			CatchAll: CatchAllSettings{Enabled: true, Action: "response", Status: 200, Body: "ok"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("List() got %d sites, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("List() got: %v, want: %v", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package siteManager
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reverseProxy/pkg/repositories/sites"
+)
+
+// checkCatchAll checks the action of the default site
+func checkCatchAll(settings sites.CatchAllSettings) error {
+	if !settings.Enabled {
+		return nil
+	}
+	switch settings.Action {
+	case "", sites.CatchAllSite:
+	case sites.CatchAllResponse:
+		if settings.Status != 0 && (settings.Status < 200 || settings.Status > 599) {
+			return fmt.Errorf("unsupported catch-all status %d", settings.Status)
+		}
+	case sites.CatchAllRedirect:
+		if settings.Status != 0 && !redirectStatuses[settings.Status] {
+			return fmt.Errorf("unsupported catch-all status %d", settings.Status)
+		}
+		target, err := url.Parse(settings.Target)
+		if err != nil {
+			return err
+		}
+		if !target.IsAbs() || target.Host == "" {
+			return fmt.Errorf("invalid catch-all target %q", settings.Target)
+		}
+	case sites.CatchAllPool:
+		if settings.Pool == "" {
+			return fmt.Errorf("empty catch-all pool")
+		}
+	default:
+		return fmt.Errorf("unsupported catch-all action %q", settings.Action)
+	}
+	return nil
+}
+
+// GetCatchAllAction returns the action of the unmatched
+// hosts of the default site, "site" if not set
+func (s *Site) GetCatchAllAction() string {
+	if s == nil || s.Site == nil || s.CatchAll.Action == "" {
+		return sites.CatchAllSite
+	}
+	return s.CatchAll.Action
+}
+
+// GetCatchAllStatus returns the status of the response
+// (200 if not set) or of the redirect (302 if not set)
+// of the unmatched hosts
+func (s *Site) GetCatchAllStatus() int {
+	switch {
+	case s == nil || s.Site == nil:
+		return 0
+	case s.CatchAll.Status != 0:
+		return int(s.CatchAll.Status)
+	case s.CatchAll.Action == sites.CatchAllRedirect:
+		return http.StatusFound
+	default:
+		return http.StatusOK
+	}
+}
+
+// GetDefaultSite returns the site of the hosts
+// matching no site, nil if there is none
+func (s *SiteManager) GetDefaultSite() *Site {
+	if s == nil {
+		return nil
+	}
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.defaultSite
+}
@@ -0,0 +1,6 @@
+// siteManager stores a structure
+// with the settings of sites.
+//
+// Responsible for finding the site
+// of the requested host.
+package siteManager
@@ -0,0 +1,139 @@
+package siteManager
+
+import (
+	"encoding/json"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"mime"
+	"net/http"
+	"reverseProxy/pkg/repositories/errorPages"
+	"strings"
+	textTemplate "text/template"
+	"text/template/parse"
+)
+
+// errorPageStatuses are the statuses
+// the sites may set the error pages of
+var errorPageStatuses = map[int]bool{
+	http.StatusUnauthorized:       true,
+	http.StatusForbidden:          true,
+	http.StatusNotFound:           true,
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// errorPageFuncs are the functions of the templates,
+// json quotes the value for the JSON templates
+var errorPageFuncs = map[string]interface{}{
+	"json": func(v interface{}) (string, error) {
+		bytes, err := json.Marshal(v)
+		return string(bytes), err
+	},
+}
+
+// ErrorPage is the error page
+// with its parsed template
+type ErrorPage struct {
+	*errorPages.ErrorPage
+	// MediaType is the content type without the parameters
+	MediaType string
+	template  interface {
+		Execute(w io.Writer, data interface{}) error
+	}
+}
+
+// NewErrorPage parses the template of the error page, the HTML
+// pages escape the variables, the JSON pages must output them
+// through json, the other types are rejected since the values
+// from the user could not be escaped for them
+func NewErrorPage(page *errorPages.ErrorPage) (*ErrorPage, error) {
+	if !errorPageStatuses[int(page.Status)] {
+		return nil, fmt.Errorf("unsupported status %d", page.Status)
+	}
+	mediaType, _, err := mime.ParseMediaType(page.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(mediaType, "*") {
+		return nil, fmt.Errorf("invalid content type %q", page.ContentType)
+	}
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	if mediaType != "text/html" && !isJSON {
+		return nil, fmt.Errorf("unsupported content type %q", page.ContentType)
+	}
+	parsed := &ErrorPage{ErrorPage: page, MediaType: mediaType}
+	name := fmt.Sprintf("%d %s", page.Status, mediaType)
+	if mediaType == "text/html" {
+		parsed.template, err = htmlTemplate.New(name).Funcs(errorPageFuncs).Parse(page.Body)
+		if err != nil {
+			return nil, err
+		}
+		return parsed, nil
+	}
+	template, err := textTemplate.New(name).Funcs(errorPageFuncs).Parse(page.Body)
+	if err != nil {
+		return nil, err
+	}
+	// the host and the path come from the user, so
+	// the values are quoted to keep the JSON valid
+	for _, t := range template.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		if err := checkJSONOutput(t.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	parsed.template = template
+	return parsed, nil
+}
+
+// checkJSONOutput checks that the actions of the
+// JSON template output the values only through json
+func checkJSONOutput(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkJSONOutput(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return nil
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "json" {
+			return nil
+		}
+		return fmt.Errorf("action %s of the JSON page must output the value through json", n)
+	case *parse.IfNode:
+		return checkJSONBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkJSONBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkJSONBranch(&n.BranchNode)
+	}
+	return nil
+}
+
+// checkJSONBranch checks the actions of
+// the branches of if, range and with
+func checkJSONBranch(branch *parse.BranchNode) error {
+	if err := checkJSONOutput(branch.List); err != nil {
+		return err
+	}
+	return checkJSONOutput(branch.ElseList)
+}
+
+// Execute writes the error page
+// with the variables to w
+func (p *ErrorPage) Execute(w io.Writer, data interface{}) error {
+	return p.template.Execute(w, data)
+}
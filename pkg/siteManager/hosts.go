@@ -0,0 +1,107 @@
+package siteManager
+
+import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NormalizeHost returns the host without the port and the
+// trailing dot in lower case, the international names are
+// converted to punycode
+func NormalizeHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	host = strings.TrimSuffix(host, ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+	return strings.ToLower(host)
+}
+
+// HostMatcher finds the site of the host: the exact hosts
+// are checked first, then the wildcards "*.example.com" with
+// the longest suffix first, then the regexes "~^api[0-9]+\.",
+// in the order they were added
+type HostMatcher struct {
+	exact     map[string]*Site
+	wildcards []*hostPattern
+	regexes   []*hostPattern
+}
+
+// hostPattern is the wildcard or the regex of the site
+type hostPattern struct {
+	// suffix is ".example.com" of the wildcard "*.example.com"
+	suffix string
+	regex  *regexp.Regexp
+	site   *Site
+}
+
+// NewHostMatcher returns new struct HostMatcher
+func NewHostMatcher() *HostMatcher {
+	return &HostMatcher{exact: make(map[string]*Site)}
+}
+
+// Add adds the host pattern of the site, the pattern
+// taken by another site is an error
+func (m *HostMatcher) Add(pattern string, site *Site) error {
+	pattern = strings.TrimSpace(pattern)
+	switch {
+	case strings.HasPrefix(pattern, "~"):
+		regex, err := regexp.Compile(pattern[1:])
+		if err != nil {
+			return err
+		}
+		m.regexes = append(m.regexes, &hostPattern{regex: regex, site: site})
+	case strings.HasPrefix(pattern, "*."):
+		suffix := "." + NormalizeHost(pattern[2:])
+		for _, wildcard := range m.wildcards {
+			if wildcard.suffix == suffix {
+				return fmt.Errorf("host %q is taken", pattern)
+			}
+		}
+		m.wildcards = append(m.wildcards, &hostPattern{suffix: suffix, site: site})
+		sort.SliceStable(m.wildcards, func(i, j int) bool {
+			return len(m.wildcards[i].suffix) > len(m.wildcards[j].suffix)
+		})
+	case pattern == "" || strings.Contains(pattern, "*"):
+		return fmt.Errorf("invalid host %q", pattern)
+	default:
+		host := NormalizeHost(pattern)
+		if _, ok := m.exact[host]; ok {
+			return fmt.Errorf("host %q is taken", pattern)
+		}
+		m.exact[host] = site
+	}
+	return nil
+}
+
+// ValidateHostPattern checks the host pattern
+// like it is added to the HostMatcher
+func ValidateHostPattern(pattern string) error {
+	return NewHostMatcher().Add(pattern, nil)
+}
+
+// Match returns the site of the host, nil if none
+func (m *HostMatcher) Match(host string) *Site {
+	host = NormalizeHost(host)
+	if site, ok := m.exact[host]; ok {
+		return site
+	}
+	for _, wildcard := range m.wildcards {
+		if strings.HasSuffix(host, wildcard.suffix) {
+			return wildcard.site
+		}
+	}
+	for _, regex := range m.regexes {
+		if regex.regex.MatchString(host) {
+			return regex.site
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package siteManager
+
+import (
+	"net"
+	"reverseProxy/pkg/repositories/ipRules"
+)
+
+// ipTrie is the binary trie of the CIDRs of the ip rules,
+// it finds the most specific rule of the address in
+// at most 128 steps for any number of rules
+type ipTrie struct {
+	v4 *ipNode
+	v6 *ipNode
+}
+
+// ipNode is the prefix of the CIDRs,
+// rule is set if the prefix is a CIDR
+type ipNode struct {
+	children [2]*ipNode
+	rule     *ipRules.IPRule
+}
+
+// root returns the root of the addresses of the
+// family of ip and the address in its length
+func (t *ipTrie) root(ip net.IP) (**ipNode, net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &t.v4, ip4
+	}
+	return &t.v6, ip.To16()
+}
+
+// insert adds the rule of the CIDR, the deny
+// rule wins over the allow rule of the same CIDR
+func (t *ipTrie) insert(ipNet *net.IPNet, rule *ipRules.IPRule) {
+	root, ip := t.root(ipNet.IP)
+	ones, bits := ipNet.Mask.Size()
+	// the IPv4 address written in the IPv6 form
+	ones -= bits - 8*len(ip)
+	if *root == nil {
+		*root = &ipNode{}
+	}
+	node := *root
+	for i := 0; i < ones; i++ {
+		b := bit(ip, i)
+		if node.children[b] == nil {
+			node.children[b] = &ipNode{}
+		}
+		node = node.children[b]
+	}
+	if node.rule == nil || rule.Action == ipRules.ActionDeny {
+		node.rule = rule
+	}
+}
+
+// match returns the rule of the most specific
+// CIDR containing the address, nil if none
+func (t *ipTrie) match(ip net.IP) *ipRules.IPRule {
+	root, ip := t.root(ip)
+	if ip == nil {
+		return nil
+	}
+	var matched *ipRules.IPRule
+	node := *root
+	for i := 0; node != nil; i++ {
+		if node.rule != nil {
+			matched = node.rule
+		}
+		if i == 8*len(ip) {
+			break
+		}
+		node = node.children[bit(ip, i)]
+	}
+	return matched
+}
+
+// bit returns the bit of the address
+// at the position from the left
+func bit(ip net.IP, i int) byte {
+	return ip[i/8] >> (7 - uint(i%8)) & 1
+}
@@ -0,0 +1,64 @@
+package siteManager
+
+import (
+	"fmt"
+	"net/http"
+	"reverseProxy/pkg/repositories/sites"
+	"strings"
+)
+
+// defaultMirrorMaxBodySize is the maximum size of the body of
+// the copied request if the site does not set the size
+const defaultMirrorMaxBodySize = 1 << 20
+
+// checkMirror checks the settings of the mirroring
+func checkMirror(settings sites.MirrorSettings) error {
+	if settings.Percent < 0 || settings.Percent > 100 {
+		return fmt.Errorf("invalid mirror percent %d", settings.Percent)
+	}
+	if settings.MaxBodySize < 0 {
+		return fmt.Errorf("invalid mirror body size %d", settings.MaxBodySize)
+	}
+	return nil
+}
+
+// parseHeaderNames parses the comma separated
+// list of the names of the headers
+func parseHeaderNames(list string) []string {
+	names := parseList(list)
+	for i, name := range names {
+		names[i] = http.CanonicalHeaderKey(name)
+	}
+	return names
+}
+
+// parseIgnorePaths parses the comma separated list of
+// the dotted paths of the JSON bodies
+func parseIgnorePaths(list string) [][]string {
+	paths := [][]string{}
+	for _, path := range parseList(list) {
+		paths = append(paths, strings.Split(path, "."))
+	}
+	return paths
+}
+
+// IsMirrorEnabled determines whether the requests
+// of the site are copied to the shadow backends
+func (s *Site) IsMirrorEnabled() bool {
+	return s != nil && s.Site != nil && s.Mirror.Pool != "" && s.Mirror.Percent > 0
+}
+
+// GetMirrorMaxBodySize returns the maximum size of
+// the body of the request copied to the shadow backends
+func (s *Site) GetMirrorMaxBodySize() int64 {
+	if s == nil || s.Site == nil || s.Mirror.MaxBodySize <= 0 {
+		return defaultMirrorMaxBodySize
+	}
+	return s.Mirror.MaxBodySize
+}
+
+// IsMirrorCompared determines whether the responses of the
+// shadow backends are compared with the primary responses
+func (s *Site) IsMirrorCompared() bool {
+	return s.IsMirrorEnabled() && s.Mirror.Compare
+}
@@ -0,0 +1,74 @@
+package siteManager
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"reverseProxy/pkg/repositories/redirectRules"
+	"strings"
+)
+
+// redirectStatuses are the statuses of the redirects
+var redirectStatuses = map[int64]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// Redirect is the redirect rule
+// with its parsed regex
+type Redirect struct {
+	*redirectRules.RedirectRule
+	regex *regexp.Regexp
+}
+
+// NewRedirect checks the kind and the status of
+// the redirect rule and parses the regex of the path
+func NewRedirect(rule *redirectRules.RedirectRule) (*Redirect, error) {
+	if rule.Status != 0 && !redirectStatuses[rule.Status] {
+		return nil, fmt.Errorf("unsupported status %d", rule.Status)
+	}
+	redirect := &Redirect{RedirectRule: rule}
+	switch rule.Kind {
+	case redirectRules.KindHTTPS:
+	case redirectRules.KindHost:
+		if rule.Source == "" || (rule.Site != nil && strings.EqualFold(rule.Source, rule.Site.Host)) {
+			return nil, fmt.Errorf("invalid source host %q", rule.Source)
+		}
+	case redirectRules.KindPath:
+		if rule.Target == "" {
+			return nil, fmt.Errorf("empty target")
+		}
+		regex, err := regexp.Compile(rule.Source)
+		if err != nil {
+			return nil, err
+		}
+		redirect.regex = regex
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", rule.Kind)
+	}
+	return redirect, nil
+}
+
+// GetStatus returns the status of the redirect,
+// 301 if the rule has no status
+func (r *Redirect) GetStatus() int {
+	if r.Status == 0 {
+		return http.StatusMovedPermanently
+	}
+	return int(r.Status)
+}
+
+// RedirectPath returns the target of the path matching the
+// regex of the rule, where $1 is the first capture group
+func (r *Redirect) RedirectPath(path string) (string, bool) {
+	if r.regex == nil {
+		return "", false
+	}
+	match := r.regex.FindStringSubmatchIndex(path)
+	if match == nil {
+		return "", false
+	}
+	return string(r.regex.ExpandString(nil, r.Target, path, match)), true
+}
@@ -0,0 +1,796 @@
+package siteManager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reverseProxy/pkg/backendManager"
+	"reverseProxy/pkg/logging"
+	"reverseProxy/pkg/repositories/errorPages"
+	"reverseProxy/pkg/repositories/headerRules"
+	"reverseProxy/pkg/repositories/ipRules"
+	"reverseProxy/pkg/repositories/rateLimits"
+	"reverseProxy/pkg/repositories/redirectRules"
+	"reverseProxy/pkg/repositories/siteHosts"
+	"reverseProxy/pkg/repositories/sites"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryAttempts      = 3
+	maxRetryBackoffShift      = 10
+	defaultCompressionMinSize = 1024
+)
+
+// defaultCompressionTypes are compressed
+// if the site does not list the types
+var defaultCompressionTypes = []string{
+	"text/html", "text/plain", "text/css", "text/xml", "text/javascript",
+	"application/javascript", "application/json", "application/xml", "image/svg+xml",
+}
+
+var (
+	SiteMgr         *SiteManager
+	ErrSiteNotFound = fmt.Errorf("site not found")
+)
+
+type SiteManager struct {
+	sites  map[string]*Site
+	tickDB *time.Ticker
+	ctx    context.Context
+	mux    sync.RWMutex
+	e      chan error
+	log    *logging.Logger
+	// hosts finds the sites of the hosts of the requests,
+	// hostRedirects are the redirect rules of the hosts
+	// redirected to the sites
+	hosts         *HostMatcher
+	hostRedirects map[string]*Redirect
+	// defaultSite gets the requests of the
+	// hosts matching no site, it may be nil
+	defaultSite *Site
+	// syncMux serializes SyncSites, so that the
+	// current sites are not swapped concurrently
+	syncMux sync.Mutex
+}
+
+// Site stores the site with its parsed settings
+type Site struct {
+	*sites.Site
+	TrustedProxies   []*net.IPNet
+	CompressionTypes []string
+	RequestHeaders   []*headerRules.HeaderRule
+	ResponseHeaders  []*headerRules.HeaderRule
+	RateLimits       []*rateLimits.RateLimit
+	// MaintenanceBypassIPs and MaintenanceBypassLogins
+	// reach the backends during the maintenance
+	MaintenanceBypassIPs    []*net.IPNet
+	MaintenanceBypassLogins []string
+	// ErrorPages are the pages of the statuses
+	// in the order of their creation
+	ErrorPages map[int][]*ErrorPage
+	// HTTPSRedirect is nil if the http requests are served,
+	// PathRedirects are in the order of their creation
+	HTTPSRedirect *Redirect
+	PathRedirects []*Redirect
+	// MirrorCompareHeaders are compared in the responses of the
+	// shadow backends, MirrorIgnorePaths are the split paths
+	// of the JSON bodies skipped in the comparison
+	MirrorCompareHeaders []string
+	MirrorIgnorePaths    [][]string
+	// Rewriter rewrites the paths of the requests
+	// of the routes without their own rules
+	Rewriter *backendManager.Rewriter
+	// ipRules are nil if the site has no ip
+	// rules, allowOnly is set by an allow rule,
+	// denyAll is set by an invalid rule
+	ipRules   *ipTrie
+	allowOnly bool
+	denyAll   bool
+}
+
+// NewSiteManager returns new struct SiteManager
+func NewSiteManager(ctx context.Context) *SiteManager {
+	return &SiteManager{
+		sites:  make(map[string]*Site),
+		tickDB: time.NewTicker(5 * time.Second),
+		ctx:    ctx,
+		e:      make(chan error),
+	}
+}
+
+// newSite parses the settings of the site
+func newSite(site *sites.Site) (*Site, error) {
+	trustedProxies, err := ParseCIDRs(site.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	bypassIPs, err := ParseCIDRs(site.Maintenance.BypassIPs)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCatchAll(site.CatchAll); err != nil {
+		return nil, err
+	}
+	if err := checkMirror(site.Mirror); err != nil {
+		return nil, err
+	}
+	rewriter, err := backendManager.NewRewriter(site.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+	return &Site{
+		Site:                    site,
+		TrustedProxies:          trustedProxies,
+		CompressionTypes:        parseMediaTypes(site.Compression.Types),
+		MaintenanceBypassIPs:    bypassIPs,
+		MaintenanceBypassLogins: parseList(site.Maintenance.BypassLogins),
+		MirrorCompareHeaders:    parseHeaderNames(site.Mirror.CompareHeaders),
+		MirrorIgnorePaths:       parseIgnorePaths(site.Mirror.IgnorePaths),
+		Rewriter:                rewriter,
+	}, nil
+}
+
+// ValidateSite checks the settings of the site,
+// the invalid site is rejected by the CRUD server
+func ValidateSite(site *sites.Site) error {
+	_, err := newSite(site)
+	return err
+}
+
+// parseMediaTypes parses the comma separated
+// list of MIME types, the parameters are ignored
+func parseMediaTypes(list string) []string {
+	types := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if i := strings.Index(item, ";"); i >= 0 {
+			item = item[:i]
+		}
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			types = append(types, item)
+		}
+	}
+	return types
+}
+
+// parseList parses the comma separated list,
+// the empty items are skipped
+func parseList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseCIDRs parses the comma separated list of
+// CIDRs, a single address is parsed as the
+// network of this address only
+func ParseCIDRs(list string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+				bits = 8 * net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// syncSites updates the current sites, the site with
+// invalid settings keeps its last valid settings, so
+// that its rules are not dropped
+func (s *SiteManager) syncSites(list []*sites.Site) error {
+	s.log = logging.NewLogs("siteManager", "syncSites")
+
+	s.log.GetInfo().Msg("updating the site data")
+	previous := make(map[int64]*Site, len(s.sites))
+	for _, site := range s.sites {
+		previous[site.Id] = site
+	}
+	current := make(map[string]*Site, len(list))
+	hosts := NewHostMatcher()
+	var defaultSite *Site
+	for _, site := range list {
+		parsed, err := newSite(site)
+		if err != nil {
+			last, ok := previous[site.Id]
+			if !ok {
+				s.log.GetWarn().Str("when", "parse site settings").Int64("site", site.Id).
+					Err(err).Msg("invalid site settings, skipped")
+				continue
+			}
+			s.log.GetWarn().Str("when", "parse site settings").Int64("site", site.Id).
+				Err(err).Msg("invalid site settings, last valid settings kept")
+			kept := *last.Site
+			kept.Host = site.Host
+			site = &kept
+			if parsed, err = newSite(site); err != nil {
+				continue
+			}
+		}
+		if err := hosts.Add(site.Host, parsed); err != nil {
+			s.log.GetWarn().Str("when", "add site host").Int64("site", site.Id).
+				Err(err).Msg("invalid site host, skipped")
+			continue
+		}
+		current[site.Host] = parsed
+		if parsed.CatchAll.Enabled {
+			// the site with the lowest id is the default site
+			other := parsed
+			if defaultSite == nil || parsed.Id < defaultSite.Id {
+				defaultSite, other = parsed, defaultSite
+			}
+			if other != nil {
+				s.log.GetWarn().Str("when", "set default site").Int64("site", other.Id).
+					Msg("default site is taken, skipped")
+			}
+		}
+	}
+	s.sites = current
+	s.hosts = hosts
+	s.defaultSite = defaultSite
+	return nil
+}
+
+// syncSiteHosts adds the aliases of the hosts
+// of the current sites, the host of the site
+// wins over the alias of another site
+func (s *SiteManager) syncSiteHosts(list []*siteHosts.SiteHost) {
+	s.log = logging.NewLogs("siteManager", "syncSiteHosts")
+
+	s.log.GetInfo().Msg("updating the site hosts")
+	for _, host := range list {
+		site, ok := s.sites[host.Site.Host]
+		if !ok {
+			continue
+		}
+		if err := s.hosts.Add(host.Pattern, site); err != nil {
+			s.log.GetWarn().Str("when", "add site host").Int64("host", host.Id).
+				Err(err).Msg("invalid site host, skipped")
+		}
+	}
+}
+
+// syncHeaderRules attaches the header rules
+// to the current sites
+func (s *SiteManager) syncHeaderRules(rules []*headerRules.HeaderRule) {
+	s.log = logging.NewLogs("siteManager", "syncHeaderRules")
+
+	s.log.GetInfo().Msg("updating the header rules")
+	for _, rule := range rules {
+		site, ok := s.sites[rule.Site.Host]
+		if !ok {
+			continue
+		}
+		if err := ValidateHeaderRule(rule); err != nil {
+			s.log.GetWarn().Str("when", "validate header rule").Int64("rule", rule.Id).
+				Err(err).Msg("invalid header rule, skipped")
+			continue
+		}
+		rule.Name = http.CanonicalHeaderKey(rule.Name)
+		if rule.Direction == headerRules.DirectionRequest {
+			site.RequestHeaders = append(site.RequestHeaders, rule)
+		} else {
+			site.ResponseHeaders = append(site.ResponseHeaders, rule)
+		}
+	}
+}
+
+// ValidateHeaderRule checks the direction,
+// the action and the name of the header rule
+func ValidateHeaderRule(rule *headerRules.HeaderRule) error {
+	switch rule.Direction {
+	case headerRules.DirectionRequest, headerRules.DirectionResponse:
+	default:
+		return fmt.Errorf("unsupported direction %q", rule.Direction)
+	}
+	switch rule.Action {
+	case headerRules.ActionAdd, headerRules.ActionSet, headerRules.ActionRemove:
+	default:
+		return fmt.Errorf("unsupported action %q", rule.Action)
+	}
+	if rule.Name == "" {
+		return fmt.Errorf("empty header name")
+	}
+	return nil
+}
+
+// syncIPRules builds the tries of
+// the ip rules of the current sites
+func (s *SiteManager) syncIPRules(rules []*ipRules.IPRule) {
+	s.log = logging.NewLogs("siteManager", "syncIPRules")
+
+	s.log.GetInfo().Msg("updating the ip rules")
+	for _, rule := range rules {
+		site, ok := s.sites[rule.Site.Host]
+		if !ok {
+			continue
+		}
+		ipNet, err := parseIPRule(rule)
+		if err != nil {
+			// the skipped rule could open the site,
+			// so the site is closed until it is fixed
+			s.log.GetError().Str("when", "validate ip rule").Int64("rule", rule.Id).
+				Err(err).Msg("invalid ip rule, all requests to the site denied")
+			site.denyAll = true
+			continue
+		}
+		if site.ipRules == nil {
+			site.ipRules = &ipTrie{}
+		}
+		site.ipRules.insert(ipNet, rule)
+		site.allowOnly = site.allowOnly || rule.Action == ipRules.ActionAllow
+	}
+}
+
+// ValidateIPRule checks the action and the CIDR of the
+// ip rule, the invalid rule is rejected by the CRUD server
+func ValidateIPRule(rule *ipRules.IPRule) error {
+	_, err := parseIPRule(rule)
+	return err
+}
+
+// parseIPRule checks the action and parses
+// the CIDR or the address of the ip rule
+func parseIPRule(rule *ipRules.IPRule) (*net.IPNet, error) {
+	switch rule.Action {
+	case ipRules.ActionAllow, ipRules.ActionDeny:
+	default:
+		return nil, fmt.Errorf("unsupported action %q", rule.Action)
+	}
+	nets, err := ParseCIDRs(rule.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	if len(nets) != 1 {
+		return nil, fmt.Errorf("want one CIDR, got %q", rule.CIDR)
+	}
+	return nets[0], nil
+}
+
+// syncRateLimits attaches the rate
+// limits to the current sites
+func (s *SiteManager) syncRateLimits(limits []*rateLimits.RateLimit) {
+	s.log = logging.NewLogs("siteManager", "syncRateLimits")
+
+	s.log.GetInfo().Msg("updating the rate limits")
+	for _, limit := range limits {
+		site, ok := s.sites[limit.Site.Host]
+		if !ok {
+			continue
+		}
+		if err := ValidateRateLimit(limit); err != nil {
+			s.log.GetWarn().Str("when", "validate rate limit").Int64("limit", limit.Id).
+				Err(err).Msg("invalid rate limit, skipped")
+			continue
+		}
+		limit.Header = http.CanonicalHeaderKey(limit.Header)
+		site.RateLimits = append(site.RateLimits, limit)
+	}
+}
+
+// ValidateRateLimit checks the key
+// and the rate of the rate limit
+func ValidateRateLimit(limit *rateLimits.RateLimit) error {
+	switch limit.Key {
+	case rateLimits.KeyIP, rateLimits.KeyLogin, rateLimits.KeySite:
+	case rateLimits.KeyHeader:
+		if limit.Header == "" {
+			return fmt.Errorf("empty header name")
+		}
+	default:
+		return fmt.Errorf("unsupported key %q", limit.Key)
+	}
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		return fmt.Errorf("invalid rate %d per %d ms", limit.Requests, limit.Period)
+	}
+	if limit.Burst < 0 {
+		return fmt.Errorf("invalid burst %d", limit.Burst)
+	}
+	return nil
+}
+
+// syncErrorPages parses the templates of the
+// error pages of the current sites
+func (s *SiteManager) syncErrorPages(pages []*errorPages.ErrorPage) {
+	s.log = logging.NewLogs("siteManager", "syncErrorPages")
+
+	s.log.GetInfo().Msg("updating the error pages")
+	for _, page := range pages {
+		site, ok := s.sites[page.Site.Host]
+		if !ok {
+			continue
+		}
+		parsed, err := NewErrorPage(page)
+		if err != nil {
+			s.log.GetWarn().Str("when", "parse error page").Int64("page", page.Id).
+				Err(err).Msg("invalid error page, skipped")
+			continue
+		}
+		if site.ErrorPages == nil {
+			site.ErrorPages = make(map[int][]*ErrorPage)
+		}
+		status := int(page.Status)
+		site.ErrorPages[status] = append(site.ErrorPages[status], parsed)
+	}
+}
+
+// syncRedirectRules attaches the redirect rules to
+// the current sites and maps the redirected hosts
+func (s *SiteManager) syncRedirectRules(rules []*redirectRules.RedirectRule) {
+	s.log = logging.NewLogs("siteManager", "syncRedirectRules")
+
+	s.log.GetInfo().Msg("updating the redirect rules")
+	hostRedirects := make(map[string]*Redirect)
+	for _, rule := range rules {
+		site, ok := s.sites[rule.Site.Host]
+		if !ok {
+			continue
+		}
+		redirect, err := NewRedirect(rule)
+		if err != nil {
+			s.log.GetWarn().Str("when", "parse redirect rule").Int64("rule", rule.Id).
+				Err(err).Msg("invalid redirect rule, skipped")
+			continue
+		}
+		switch rule.Kind {
+		case redirectRules.KindHTTPS:
+			if site.HTTPSRedirect == nil {
+				site.HTTPSRedirect = redirect
+			}
+		case redirectRules.KindHost:
+			host := NormalizeHost(rule.Source)
+			if _, ok := hostRedirects[host]; !ok {
+				hostRedirects[host] = redirect
+			}
+		case redirectRules.KindPath:
+			site.PathRedirects = append(site.PathRedirects, redirect)
+		}
+	}
+	s.hostRedirects = hostRedirects
+}
+
+// SyncSites updates the current sites of database, the
+// sites are queried and parsed without the lock, which
+// is taken only to swap them, so GetSite is not blocked
+func (s *SiteManager) SyncSites() {
+	s.syncMux.Lock()
+	defer s.syncMux.Unlock()
+	list, err := sites.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+	hostList, err := siteHosts.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+	rules, err := headerRules.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+	ipList, err := ipRules.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+	limits, err := rateLimits.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+	pages, err := errorPages.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+	redirects, err := redirectRules.List()
+	if err != nil {
+		s.e <- err
+		return
+	}
+
+	s.mux.RLock()
+	next := &SiteManager{sites: s.sites}
+	s.mux.RUnlock()
+	if err := next.syncSites(list); err != nil {
+		s.e <- err
+		return
+	}
+	next.syncSiteHosts(hostList)
+	next.syncHeaderRules(rules)
+	next.syncIPRules(ipList)
+	next.syncRateLimits(limits)
+	next.syncErrorPages(pages)
+	next.syncRedirectRules(redirects)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.sites = next.sites
+	s.hosts = next.hosts
+	s.hostRedirects = next.hostRedirects
+	s.defaultSite = next.defaultSite
+}
+
+// GetSite returns the site of the given host, the host
+// is matched with the hosts and the aliases of the sites
+func (s *SiteManager) GetSite(host string) (*Site, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if s.hosts == nil {
+		return nil, ErrSiteNotFound
+	}
+	site := s.hosts.Match(host)
+	if site == nil {
+		return nil, ErrSiteNotFound
+	}
+	return site, nil
+}
+
+// GetHostRedirect returns the redirect rule of the host
+// and the site the host is redirected to, nil if none
+func (s *SiteManager) GetHostRedirect(host string) (*Redirect, *Site) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	redirect, ok := s.hostRedirects[NormalizeHost(host)]
+	if !ok {
+		return nil, nil
+	}
+	return redirect, s.sites[redirect.Site.Host]
+}
+
+// IsTrusted determines whether the address
+// belongs to the trusted proxies of the site
+func (s *Site) IsTrusted(ip net.IP) bool {
+	if s == nil || ip == nil {
+		return false
+	}
+	for _, ipNet := range s.TrustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIP determines whether the requests from the address
+// are allowed by the ip rules of the site, the rule of the
+// most specific CIDR decides, the address without the rule
+// is denied only if the site has allow rules. All addresses
+// are denied if the site has an invalid rule
+func (s *Site) CheckIP(ip net.IP) (*ipRules.IPRule, bool) {
+	if s == nil {
+		return nil, true
+	}
+	if s.denyAll {
+		return nil, false
+	}
+	if s.ipRules == nil {
+		return nil, true
+	}
+	if ip == nil {
+		return nil, !s.allowOnly
+	}
+	rule := s.ipRules.match(ip)
+	if rule == nil {
+		return nil, !s.allowOnly
+	}
+	return rule, rule.Action == ipRules.ActionAllow
+}
+
+// GetRequestHeaders returns the rules of the
+// headers of the requests to the backends
+func (s *Site) GetRequestHeaders() []*headerRules.HeaderRule {
+	if s == nil {
+		return nil
+	}
+	return s.RequestHeaders
+}
+
+// GetResponseHeaders returns the rules of the
+// headers of the responses to the users
+func (s *Site) GetResponseHeaders() []*headerRules.HeaderRule {
+	if s == nil {
+		return nil
+	}
+	return s.ResponseHeaders
+}
+
+// GetRateLimits returns the rate
+// limits of the requests to the site
+func (s *Site) GetRateLimits() []*rateLimits.RateLimit {
+	if s == nil {
+		return nil
+	}
+	return s.RateLimits
+}
+
+// GetErrorPages returns the error
+// pages of the status of the site
+func (s *Site) GetErrorPages(status int) []*ErrorPage {
+	if s == nil {
+		return nil
+	}
+	return s.ErrorPages[status]
+}
+
+// GetHTTPSRedirect returns the redirect rule of
+// the http requests, nil if they are served
+func (s *Site) GetHTTPSRedirect() *Redirect {
+	if s == nil {
+		return nil
+	}
+	return s.HTTPSRedirect
+}
+
+// GetPathRedirects returns the redirect
+// rules of the paths of the site
+func (s *Site) GetPathRedirects() []*Redirect {
+	if s == nil {
+		return nil
+	}
+	return s.PathRedirects
+}
+
+// IsCacheEnabled determines whether the responses
+// of the backends of the site are cached
+func (s *Site) IsCacheEnabled() bool {
+	return s != nil && s.Site != nil && s.Cache.Enabled
+}
+
+// IsStaleEnabled determines whether the stored responses
+// are sent when the backends of the site fail
+func (s *Site) IsStaleEnabled() bool {
+	return s != nil && s.Site != nil && s.Cache.ServeStale
+}
+
+// InMaintenance determines whether the requests to
+// the site get 503 without contacting the backends
+func (s *Site) InMaintenance() bool {
+	return s != nil && s.Site != nil && s.Maintenance.Enabled
+}
+
+// BypassesMaintenance determines whether the client with the
+// address or the login reaches the backends during the maintenance
+func (s *Site) BypassesMaintenance(ip net.IP, login string) bool {
+	if s == nil {
+		return false
+	}
+	if ip != nil {
+		for _, ipNet := range s.MaintenanceBypassIPs {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	if login != "" {
+		for _, bypass := range s.MaintenanceBypassLogins {
+			if bypass == login {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetMaintenanceRetryAfter returns the seconds
+// of Retry-After of the maintenance, 0 if not sent
+func (s *Site) GetMaintenanceRetryAfter() int64 {
+	if s == nil || s.Site == nil || s.Maintenance.RetryAfter < 0 {
+		return 0
+	}
+	return s.Maintenance.RetryAfter
+}
+
+// IsCompressionEnabled determines whether the responses
+// of the backends of the site are compressed
+func (s *Site) IsCompressionEnabled() bool {
+	return s != nil && s.Site != nil && s.Compression.Enabled
+}
+
+// Compresses determines whether the responses of the media
+// type are compressed, the type "text/*" matches all
+// text types
+func (s *Site) Compresses(mediaType string) bool {
+	types := defaultCompressionTypes
+	if s != nil && len(s.CompressionTypes) > 0 {
+		types = s.CompressionTypes
+	}
+	mediaType = strings.ToLower(mediaType)
+	for _, t := range types {
+		if t == mediaType || t == "*/*" ||
+			(strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCompressionMinSize returns the minimum size
+// of the compressed body in bytes
+func (s *Site) GetCompressionMinSize() int64 {
+	if s == nil || s.Site == nil || s.Compression.MinSize <= 0 {
+		return defaultCompressionMinSize
+	}
+	return s.Compression.MinSize
+}
+
+// GetRetryAttempts returns the number of attempts
+// to send the request, including the first one
+func (s *Site) GetRetryAttempts() int {
+	if s == nil || s.Site == nil || s.Retry.Attempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return int(s.Retry.Attempts)
+}
+
+// GetRetryBackoff returns the delay before the attempt,
+// the first attempt is sent without delay
+func (s *Site) GetRetryBackoff(attempt int) time.Duration {
+	if s == nil || s.Site == nil || s.Retry.Backoff <= 0 || attempt <= 1 {
+		return 0
+	}
+	shift := attempt - 2
+	if shift > maxRetryBackoffShift {
+		shift = maxRetryBackoffShift
+	}
+	return time.Duration(s.Retry.Backoff) * time.Millisecond << uint(shift)
+}
+
+// GetRewriter returns the rules of rewriting the
+// path of the site, nil if the site has no rules
+func (s *Site) GetRewriter() *backendManager.Rewriter {
+	if s == nil {
+		return nil
+	}
+	return s.Rewriter
+}
+
+// Serve with the ticks running SyncSites
+// during the operation of the application
+func (s *SiteManager) Serve() error {
+	defer s.tickDB.Stop()
+	defer close(s.e)
+	go s.SyncSites()
+	for {
+		select {
+		case <-s.tickDB.C:
+			go s.SyncSites()
+		case err := <-s.e:
+			return err
+		case <-s.ctx.Done():
+			return nil
+		}
+	}
+}
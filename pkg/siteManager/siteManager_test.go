@@ -0,0 +1,753 @@
+package siteManager
+
+import (
+	"fmt"
+	"net"
+	"reverseProxy/pkg/repositories/errorPages"
+	"reverseProxy/pkg/repositories/headerRules"
+	"reverseProxy/pkg/repositories/ipRules"
+	"reverseProxy/pkg/repositories/rateLimits"
+	"reverseProxy/pkg/repositories/redirectRules"
+	"reverseProxy/pkg/repositories/siteHosts"
+	"reverseProxy/pkg/repositories/sites"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty list",
+			list: "",
+			want: []string{},
+		},
+		{
+			name: "networks and addresses",
+			list: "10.0.0.0/8, 192.168.1.1,2001:db8::/32 ,::1",
+			want: []string{"10.0.0.0/8", "192.168.1.1/32", "2001:db8::/32", "::1/128"},
+		},
+		{
+			name:    "invalid network",
+			list:    "10.0.0.0/33",
+			wantErr: true,
+		},
+		{
+			name:    "invalid address",
+			list:    "localhost",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCIDRs(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCIDRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseCIDRs() got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].String() != tt.want[i] {
+					t.Errorf("ParseCIDRs() got %v, want %v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSiteManager_GetSite(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	list := []*sites.Site{
+		{Id: 1, Name: "vk", Host: "vk.com", TrustedProxies: "10.0.0.0/8"},
+		{Id: 2, Name: "broken", Host: "broken.com", TrustedProxies: "10.0.0.0/80"},
+	}
+	if err := s.syncSites(list); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		host    string
+		ip      string
+		trusted bool
+		wantErr error
+	}{
+		{
+			name:    "trusted proxy of site",
+			host:    "vk.com",
+			ip:      "10.1.2.3",
+			trusted: true,
+		},
+		{
+			name:    "untrusted address",
+			host:    "vk.com",
+			ip:      "11.1.2.3",
+			trusted: false,
+		},
+		{
+			name:    "site with invalid settings is skipped",
+			host:    "broken.com",
+			wantErr: ErrSiteNotFound,
+		},
+		{
+			name:    "unknown site",
+			host:    "odnoklassniki.ru",
+			wantErr: ErrSiteNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetSite(tt.host)
+			if err != tt.wantErr {
+				t.Fatalf("GetSite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.IsTrusted(net.ParseIP(tt.ip)) != tt.trusted {
+				t.Errorf("IsTrusted() got %v, want %v", !tt.trusted, tt.trusted)
+			}
+		})
+	}
+}
+
+func TestSiteManager_syncSites_lastValid(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	rules := []*ipRules.IPRule{{Id: 1, Action: ipRules.ActionAllow, CIDR: "10.0.0.0/8",
+		Site: &sites.Site{Host: "vk.com"}}}
+	if err := s.syncSites([]*sites.Site{{Id: 1, Name: "vk", Host: "vk.com", TrustedProxies: "10.0.0.0/8"}}); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncIPRules(rules)
+
+	broken := []*sites.Site{
+		{Id: 1, Name: "vk", Host: "vk.com", TrustedProxies: "10.0.0.0/80"},
+		{Id: 2, Name: "new", Host: "new.com", TrustedProxies: "10.0.0.0/80"},
+	}
+	if err := s.syncSites(broken); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncIPRules(rules)
+
+	site, err := s.GetSite("vk.com")
+	if err != nil {
+		t.Fatalf("GetSite() error = %v, want the last valid site", err)
+	}
+	if !site.IsTrusted(net.ParseIP("10.1.2.3")) {
+		t.Errorf("IsTrusted() = false, want the last valid trusted proxies")
+	}
+	if _, ok := site.CheckIP(net.ParseIP("192.168.1.1")); ok {
+		t.Errorf("CheckIP() = true, want the ip rules kept")
+	}
+	if _, err := s.GetSite("new.com"); err != ErrSiteNotFound {
+		t.Errorf("GetSite() error = %v, want %v for the site never valid", err, ErrSiteNotFound)
+	}
+}
+
+func TestValidateSite(t *testing.T) {
+	tests := []struct {
+		name    string
+		site    *sites.Site
+		wantErr bool
+	}{
+		{name: "valid", site: &sites.Site{Host: "vk.com", TrustedProxies: "10.0.0.0/8"}},
+		{name: "invalid trusted proxies", site: &sites.Site{Host: "vk.com", TrustedProxies: "10.0.0.0/80"},
+			wantErr: true},
+		{name: "invalid bypass address", site: &sites.Site{Host: "vk.com",
+			Maintenance: sites.MaintenanceSettings{BypassIPs: "1.2.3"}}, wantErr: true},
+		{name: "invalid mirror", site: &sites.Site{Host: "vk.com",
+			Mirror: sites.MirrorSettings{Pool: "shadow", Percent: 101}}, wantErr: true},
+		{name: "rewrite", site: &sites.Site{Host: "vk.com",
+			Rewrite: sites.Rewrite{Regex: "^/v[0-9]+/", Replacement: "/"}}},
+		{name: "invalid rewrite regex", site: &sites.Site{Host: "vk.com",
+			Rewrite: sites.Rewrite{Regex: "^/v[0-9+/"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSite(tt.site); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateIPRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    *ipRules.IPRule
+		wantErr bool
+	}{
+		{name: "cidr", rule: &ipRules.IPRule{Action: ipRules.ActionAllow, CIDR: "10.0.0.0/8"}},
+		{name: "address", rule: &ipRules.IPRule{Action: ipRules.ActionDeny, CIDR: "2001:db8::1"}},
+		{name: "invalid cidr", rule: &ipRules.IPRule{Action: ipRules.ActionAllow, CIDR: "10.0.0.0/33"}, wantErr: true},
+		{name: "two cidrs", rule: &ipRules.IPRule{Action: ipRules.ActionAllow, CIDR: "10.0.0.0/8,1.2.3.4"},
+			wantErr: true},
+		{name: "invalid action", rule: &ipRules.IPRule{Action: "block", CIDR: "10.0.0.0/8"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateIPRule(tt.rule); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIPRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSiteManager_syncHeaderRules(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	site := &sites.Site{Id: 1, Name: "vk", Host: "vk.com"}
+	if err := s.syncSites([]*sites.Site{site}); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncHeaderRules([]*headerRules.HeaderRule{
+		{Id: 1, Direction: headerRules.DirectionRequest, Action: headerRules.ActionSet, Name: "x-env", Site: site},
+		{Id: 2, Direction: headerRules.DirectionResponse, Action: headerRules.ActionRemove, Name: "Server", Site: site},
+		{Id: 3, Direction: "both", Action: headerRules.ActionSet, Name: "X-Env", Site: site},
+		{Id: 4, Direction: headerRules.DirectionRequest, Action: "replace", Name: "X-Env", Site: site},
+		{Id: 5, Direction: headerRules.DirectionRequest, Action: headerRules.ActionSet, Name: "X-Env",
+			Site: &sites.Site{Id: 2, Host: "example.com"}},
+	})
+
+	got, err := s.GetSite("vk.com")
+	if err != nil {
+		t.Fatalf("GetSite() error = %v", err)
+	}
+	requestHeaders := got.GetRequestHeaders()
+	if len(requestHeaders) != 1 || requestHeaders[0].Id != 1 || requestHeaders[0].Name != "X-Env" {
+		t.Errorf("GetRequestHeaders() got %v, want rule 1 with canonical name", requestHeaders)
+	}
+	responseHeaders := got.GetResponseHeaders()
+	if len(responseHeaders) != 1 || responseHeaders[0].Id != 2 {
+		t.Errorf("GetResponseHeaders() got %v, want rule 2", responseHeaders)
+	}
+	var nilSite *Site
+	if nilSite.GetRequestHeaders() != nil || nilSite.GetResponseHeaders() != nil {
+		t.Errorf("nil site has header rules")
+	}
+}
+
+func TestSite_CheckIP(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	office := &sites.Site{Id: 1, Name: "office", Host: "office.com"}
+	public := &sites.Site{Id: 2, Name: "public", Host: "public.com"}
+	broken := &sites.Site{Id: 3, Name: "broken", Host: "broken.com"}
+	if err := s.syncSites([]*sites.Site{office, public, broken}); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	rules := []*ipRules.IPRule{
+		{Id: 1, Action: ipRules.ActionAllow, CIDR: "10.0.0.0/8", Site: office},
+		{Id: 2, Action: ipRules.ActionDeny, CIDR: "10.1.0.0/16", Site: office},
+		{Id: 3, Action: ipRules.ActionAllow, CIDR: "10.1.2.3", Site: office},
+		{Id: 4, Action: ipRules.ActionAllow, CIDR: "2001:db8::/32", Site: office},
+		{Id: 5, Action: ipRules.ActionAllow, CIDR: "192.168.0.0/16", Site: office},
+		{Id: 6, Action: ipRules.ActionDeny, CIDR: "192.168.0.0/16", Site: office},
+		{Id: 7, Action: ipRules.ActionDeny, CIDR: "invalid", Site: broken},
+		{Id: 8, Action: "block", CIDR: "172.16.0.0/12", Site: broken},
+		{Id: 10, Action: ipRules.ActionAllow, CIDR: "8.8.8.0/24", Site: broken},
+		{Id: 9, Action: ipRules.ActionDeny, CIDR: "1.2.3.0/24", Site: public},
+	}
+	// the matcher must not slow down
+	// with thousands of ranges
+	for i := 0; i < 4096; i++ {
+		rules = append(rules, &ipRules.IPRule{Id: int64(100 + i), Action: ipRules.ActionAllow,
+			CIDR: fmt.Sprintf("100.%d.%d.0/24", i/256, i%256), Site: office})
+	}
+	s.syncIPRules(rules)
+
+	tests := []struct {
+		name     string
+		host     string
+		ip       string
+		wantRule int64
+		want     bool
+	}{
+		{name: "allowed range", host: "office.com", ip: "10.2.0.1", wantRule: 1, want: true},
+		{name: "denied subrange", host: "office.com", ip: "10.1.0.1", wantRule: 2},
+		{name: "allowed address in denied subrange", host: "office.com", ip: "10.1.2.3", wantRule: 3, want: true},
+		{name: "ipv6", host: "office.com", ip: "2001:db8::1", wantRule: 4, want: true},
+		{name: "ipv4 mapped ipv6", host: "office.com", ip: "::ffff:10.2.0.1", wantRule: 1, want: true},
+		{name: "deny wins on the same range", host: "office.com", ip: "192.168.1.1", wantRule: 6},
+		{name: "one of many ranges", host: "office.com", ip: "100.15.255.7", wantRule: 100 + 4095, want: true},
+		{name: "no rule with allow rules", host: "office.com", ip: "8.8.8.8"},
+		{name: "invalid rules deny all", host: "broken.com", ip: "172.16.0.1"},
+		{name: "invalid rules deny the allowed range", host: "broken.com", ip: "8.8.8.8"},
+		{name: "denied range", host: "public.com", ip: "1.2.3.4", wantRule: 9},
+		{name: "no rule without allow rules", host: "public.com", ip: "8.8.8.8", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site, err := s.GetSite(tt.host)
+			if err != nil {
+				t.Fatalf("GetSite() error = %v", err)
+			}
+			rule, got := site.CheckIP(net.ParseIP(tt.ip))
+			if got != tt.want {
+				t.Errorf("CheckIP() allowed = %v, want %v", got, tt.want)
+			}
+			if (rule == nil && tt.wantRule != 0) || (rule != nil && rule.Id != tt.wantRule) {
+				t.Errorf("CheckIP() rule = %v, want rule %d", rule, tt.wantRule)
+			}
+		})
+	}
+	if _, allowed := (*Site)(nil).CheckIP(net.ParseIP("8.8.8.8")); !allowed {
+		t.Errorf("CheckIP() of nil site denied the address")
+	}
+}
+
+func TestSiteManager_syncRateLimits(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	site := &sites.Site{Id: 1, Name: "vk", Host: "vk.com"}
+	if err := s.syncSites([]*sites.Site{site}); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncRateLimits([]*rateLimits.RateLimit{
+		{Id: 1, Key: rateLimits.KeyIP, Requests: 10, Period: 1000, Site: site},
+		{Id: 2, Key: rateLimits.KeyHeader, Header: "x-api-key", Requests: 10, Period: 1000, Site: site},
+		{Id: 3, Key: rateLimits.KeyHeader, Requests: 10, Period: 1000, Site: site},
+		{Id: 4, Key: "cookie", Requests: 10, Period: 1000, Site: site},
+		{Id: 5, Key: rateLimits.KeySite, Requests: 0, Period: 1000, Site: site},
+		{Id: 6, Key: rateLimits.KeyIP, Requests: 10, Period: 1000, Site: &sites.Site{Id: 2, Host: "example.com"}},
+	})
+
+	got, err := s.GetSite("vk.com")
+	if err != nil {
+		t.Fatalf("GetSite() error = %v", err)
+	}
+	limits := got.GetRateLimits()
+	if len(limits) != 2 || limits[0].Id != 1 || limits[1].Id != 2 || limits[1].Header != "X-Api-Key" {
+		t.Errorf("GetRateLimits() got %v, want limits 1 and 2 with canonical header", limits)
+	}
+	if (*Site)(nil).GetRateLimits() != nil {
+		t.Errorf("nil site has rate limits")
+	}
+}
+
+func TestSiteManager_syncErrorPages(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	site := &sites.Site{Id: 1, Name: "vk", Host: "vk.com"}
+	if err := s.syncSites([]*sites.Site{site}); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncErrorPages([]*errorPages.ErrorPage{
+		{Id: 1, Status: 502, ContentType: "text/html; charset=utf-8", Body: "<p>{{.Message}}</p>", Site: site},
+		{Id: 2, Status: 502, ContentType: "application/json", Body: `{"message": {{json .Message}}}`, Site: site},
+		{Id: 3, Status: 500, ContentType: "text/html", Body: "<p>{{.Message}}</p>", Site: site},
+		{Id: 4, Status: 502, ContentType: "text/*", Body: "{{.Message}}", Site: site},
+		{Id: 5, Status: 502, ContentType: "text/html", Body: "{{.Message", Site: site},
+		{Id: 6, Status: 502, ContentType: "text/html", Body: "{{.Message}}", Site: &sites.Site{Id: 2, Host: "example.com"}},
+		{Id: 7, Status: 502, ContentType: "text/plain", Body: "{{.Message}}", Site: site},
+	})
+
+	got, err := s.GetSite("vk.com")
+	if err != nil {
+		t.Fatalf("GetSite() error = %v", err)
+	}
+	pages := got.GetErrorPages(502)
+	if len(pages) != 2 || pages[0].MediaType != "text/html" || pages[1].MediaType != "application/json" {
+		t.Fatalf("GetErrorPages() got %v, want pages 1 and 2", pages)
+	}
+	vars := struct{ Message string }{Message: `<"quoted">`}
+	tests := []struct {
+		page *ErrorPage
+		want string
+	}{
+		{page: pages[0], want: "<p>&lt;&#34;quoted&#34;&gt;</p>"},
+		{page: pages[1], want: `{"message": "\u003c\"quoted\"\u003e"}`},
+	}
+	for _, tt := range tests {
+		b := strings.Builder{}
+		if err := tt.page.Execute(&b, vars); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("Execute() got %s, want %s", b.String(), tt.want)
+		}
+	}
+	if (*Site)(nil).GetErrorPages(502) != nil {
+		t.Errorf("nil site has error pages")
+	}
+}
+
+func TestNewErrorPage_json(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{name: "quoted values", contentType: "application/json",
+			body: `{"host": {{json .Host}}, "path": {{.Path | json}}}`},
+		{name: "quoted values in branches", contentType: "application/problem+json",
+			body: `{{if .Path}}{"path": {{json .Path}}}{{else}}{{with .Host}}{{json .}}{{end}}{{end}}`},
+		{name: "declaration outputs nothing", contentType: "application/json",
+			body: `{{$host := .Host}}{"host": {{json $host}}}`},
+		{name: "raw value", contentType: "application/json", body: `{"host": "{{.Host}}"}`, wantErr: true},
+		{name: "raw value in branch", contentType: "application/json",
+			body: `{{range .Path}}{{.}}{{end}}`, wantErr: true},
+		{name: "raw value in defined template", contentType: "application/json",
+			body: `{{define "path"}}{{.Path}}{{end}}{{template "path" .}}`, wantErr: true},
+		{name: "plain text", contentType: "text/plain", body: `{{.Host}}{{.Path}}`, wantErr: true},
+		{name: "xhtml", contentType: "application/xhtml+xml", body: `<p>{{.Path}}</p>`, wantErr: true},
+		{name: "svg", contentType: "image/svg+xml", body: `<svg>{{.Path}}</svg>`, wantErr: true},
+		{name: "xml", contentType: "application/xml", body: `<path>{{.Path}}</path>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &errorPages.ErrorPage{Status: 502, ContentType: tt.contentType, Body: tt.body}
+			if _, err := NewErrorPage(page); (err != nil) != tt.wantErr {
+				t.Errorf("NewErrorPage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSite_BypassesMaintenance(t *testing.T) {
+	site, err := newSite(&sites.Site{Maintenance: sites.MaintenanceSettings{
+		Enabled: true, BypassIPs: "10.0.0.0/8, 2001:db8::1", BypassLogins: "admin, deploy,"}})
+	if err != nil {
+		t.Fatalf("newSite() error = %v", err)
+	}
+	tests := []struct {
+		ip    string
+		login string
+		want  bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "2001:db8::1", want: true},
+		{ip: "2001:db8::2", want: false},
+		{ip: "192.168.1.1", login: "deploy", want: true},
+		{ip: "192.168.1.1", login: "user", want: false},
+		{login: "admin", want: true},
+		{want: false},
+	}
+	for _, tt := range tests {
+		if got := site.BypassesMaintenance(net.ParseIP(tt.ip), tt.login); got != tt.want {
+			t.Errorf("BypassesMaintenance(%q, %q) = %v, want %v", tt.ip, tt.login, got, tt.want)
+		}
+	}
+	if !site.InMaintenance() || (*Site)(nil).InMaintenance() {
+		t.Errorf("InMaintenance() mismatch")
+	}
+	if _, err := newSite(&sites.Site{Maintenance: sites.MaintenanceSettings{BypassIPs: "10.0.0.0/33"}}); err == nil {
+		t.Errorf("newSite() with invalid bypass ips, want error")
+	}
+}
+
+func TestSiteManager_syncRedirectRules(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	site := &sites.Site{Id: 1, Name: "example", Host: "example.com"}
+	if err := s.syncSites([]*sites.Site{site}); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncRedirectRules([]*redirectRules.RedirectRule{
+		{Id: 1, Kind: redirectRules.KindHTTPS, Status: 308, Site: site},
+		{Id: 2, Kind: redirectRules.KindHost, Source: "WWW.example.com", Site: site},
+		{Id: 3, Kind: redirectRules.KindPath, Source: "^/old/(.*)$", Target: "/new/$1", Site: site},
+		{Id: 4, Kind: redirectRules.KindPath, Source: "^/(", Target: "/", Site: site},
+		{Id: 5, Kind: redirectRules.KindPath, Source: "^/docs$", Target: "/help", Status: 200, Site: site},
+		{Id: 6, Kind: redirectRules.KindHost, Source: "example.com", Site: site},
+		{Id: 7, Kind: "cookie", Site: site},
+		{Id: 8, Kind: redirectRules.KindHTTPS, Site: &sites.Site{Id: 2, Host: "vk.com"}},
+	})
+
+	got, err := s.GetSite("example.com")
+	if err != nil {
+		t.Fatalf("GetSite() error = %v", err)
+	}
+	if got.GetHTTPSRedirect() == nil || got.GetHTTPSRedirect().GetStatus() != 308 {
+		t.Errorf("GetHTTPSRedirect() got %v, want rule 1", got.GetHTTPSRedirect())
+	}
+	redirects := got.GetPathRedirects()
+	if len(redirects) != 1 || redirects[0].Id != 3 || redirects[0].GetStatus() != 301 {
+		t.Fatalf("GetPathRedirects() got %v, want rule 3", redirects)
+	}
+	if target, ok := redirects[0].RedirectPath("/old/a/b"); !ok || target != "/new/a/b" {
+		t.Errorf("RedirectPath() got %q, %v, want /new/a/b", target, ok)
+	}
+	if _, ok := redirects[0].RedirectPath("/new/a"); ok {
+		t.Errorf("RedirectPath() of not matching path, want false")
+	}
+	rule, target := s.GetHostRedirect("www.Example.com")
+	if rule == nil || rule.Id != 2 || target != got {
+		t.Errorf("GetHostRedirect() got %v, %v, want rule 2 of example.com", rule, target)
+	}
+	if rule, _ := s.GetHostRedirect("example.com"); rule != nil {
+		t.Errorf("GetHostRedirect() of site host got %v, want nil", rule)
+	}
+	if rule, _ := (*SiteManager)(nil).GetHostRedirect("www.example.com"); rule != nil {
+		t.Errorf("nil site manager has host redirects")
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "Example.COM", want: "example.com"},
+		{host: "example.com:8080", want: "example.com"},
+		{host: "example.com.", want: "example.com"},
+		{host: "[::1]:443", want: "::1"},
+		{host: "Bücher.example", want: "xn--bcher-kva.example"},
+		{host: "xn--bcher-kva.example:80", want: "xn--bcher-kva.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := NormalizeHost(tt.host); got != tt.want {
+				t.Errorf("NormalizeHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHostPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{pattern: "www.example.com"},
+		{pattern: "*.example.com"},
+		{pattern: `~^api[0-9]+\.example\.com$`},
+		{pattern: "", wantErr: true},
+		{pattern: "www.*.com", wantErr: true},
+		{pattern: "~^api[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if err := ValidateHostPattern(tt.pattern); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHostPattern() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSiteManager_syncSiteHosts(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	list := []*sites.Site{
+		{Id: 1, Name: "main", Host: "example.com"},
+		{Id: 2, Name: "api", Host: "api.example.com"},
+		{Id: 3, Name: "shop", Host: "*.shop.example.com"},
+		{Id: 4, Name: "numbered", Host: "~^node[0-9]+\\.example\\.com$"},
+		{Id: 5, Name: "taken", Host: "EXAMPLE.com"},
+	}
+	if err := s.syncSites(list); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	s.syncSiteHosts([]*siteHosts.SiteHost{
+		{Id: 1, Pattern: "*.example.com", Site: list[0]},
+		{Id: 2, Pattern: "www.bücher.example", Site: list[0]},
+		{Id: 3, Pattern: "api.example.com", Site: list[0]},
+		{Id: 4, Pattern: "~^(", Site: list[1]},
+		{Id: 5, Pattern: "~^node1\\.", Site: list[1]},
+		{Id: 6, Pattern: "unknown.com", Site: &sites.Site{Id: 6, Host: "unknown.com"}},
+	})
+
+	tests := []struct {
+		host     string
+		wantSite int64
+	}{
+		{host: "Example.com:8080", wantSite: 1},
+		{host: "api.example.com", wantSite: 2},
+		{host: "www.example.com", wantSite: 1},
+		{host: "a.b.shop.example.com", wantSite: 3},
+		{host: "shop.example.com", wantSite: 1},
+		{host: "node1.example.com", wantSite: 1},
+		{host: "www.xn--bcher-kva.example", wantSite: 1},
+		{host: "node1.example.org", wantSite: 2},
+		{host: "unknown.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := s.GetSite(tt.host)
+			if tt.wantSite == 0 {
+				if err != ErrSiteNotFound {
+					t.Errorf("GetSite() error = %v, want %v", err, ErrSiteNotFound)
+				}
+				return
+			}
+			if err != nil || got.Id != tt.wantSite {
+				t.Errorf("GetSite() got %v, %v, want site %d", got, err, tt.wantSite)
+			}
+		})
+	}
+}
+
+func TestSiteManager_GetDefaultSite(t *testing.T) {
+	s := NewSiteManager(nil)
+	defer s.tickDB.Stop()
+	if got := s.GetDefaultSite(); got != nil {
+		t.Fatalf("GetDefaultSite() before sync = %v, want nil", got)
+	}
+	list := []*sites.Site{
+		{Id: 3, Name: "parking", Host: "parking.com", CatchAll: sites.CatchAllSettings{Enabled: true,
+			Action: sites.CatchAllResponse, Body: "parked"}},
+		{Id: 1, Name: "broken", Host: "broken.com", CatchAll: sites.CatchAllSettings{Enabled: true,
+			Action: sites.CatchAllRedirect, Target: "/relative"}},
+		{Id: 2, Name: "main", Host: "example.com", CatchAll: sites.CatchAllSettings{Enabled: true,
+			Action: sites.CatchAllRedirect, Target: "https://example.com"}},
+		{Id: 4, Name: "api", Host: "api.example.com"},
+	}
+	if err := s.syncSites(list); err != nil {
+		t.Fatalf("syncSites() error = %v", err)
+	}
+	got := s.GetDefaultSite()
+	if got == nil || got.Id != 2 {
+		t.Fatalf("GetDefaultSite() = %v, want site 2", got)
+	}
+	if got.GetCatchAllAction() != sites.CatchAllRedirect || got.GetCatchAllStatus() != 302 {
+		t.Errorf("catch-all got %q %d, want redirect 302", got.GetCatchAllAction(), got.GetCatchAllStatus())
+	}
+	if _, err := s.GetSite("unknown.com"); err != ErrSiteNotFound {
+		t.Errorf("GetSite() of unmatched host error = %v, want %v", err, ErrSiteNotFound)
+	}
+	if site, err := s.GetSite("parking.com"); err != nil || site.GetCatchAllStatus() != 200 {
+		t.Errorf("GetSite() of skipped default site got %v, %v", site, err)
+	}
+	if got := (*SiteManager)(nil).GetDefaultSite(); got != nil {
+		t.Errorf("nil site manager has default site")
+	}
+}
+
+func TestCheckCatchAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings sites.CatchAllSettings
+		wantErr  bool
+	}{
+		{name: "disabled", settings: sites.CatchAllSettings{Action: "unknown"}},
+		{name: "site", settings: sites.CatchAllSettings{Enabled: true}},
+		{name: "response", settings: sites.CatchAllSettings{Enabled: true, Action: "response", Status: 404}},
+		{name: "response status", settings: sites.CatchAllSettings{Enabled: true, Action: "response", Status: 42},
+			wantErr: true},
+		{name: "redirect", settings: sites.CatchAllSettings{Enabled: true, Action: "redirect",
+			Target: "https://example.com/", Status: 308}},
+		{name: "redirect status", settings: sites.CatchAllSettings{Enabled: true, Action: "redirect",
+			Target: "https://example.com/", Status: 200}, wantErr: true},
+		{name: "relative redirect", settings: sites.CatchAllSettings{Enabled: true, Action: "redirect",
+			Target: "/"}, wantErr: true},
+		{name: "pool", settings: sites.CatchAllSettings{Enabled: true, Action: "pool", Pool: "parking"}},
+		{name: "empty pool", settings: sites.CatchAllSettings{Enabled: true, Action: "pool"}, wantErr: true},
+		{name: "unknown action", settings: sites.CatchAllSettings{Enabled: true, Action: "drop"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCatchAll(tt.settings); (err != nil) != tt.wantErr {
+				t.Errorf("checkCatchAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckMirror(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     sites.MirrorSettings
+		wantErr      bool
+		wantEnabled  bool
+		wantCompared bool
+		wantMaxBody  int64
+	}{
+		{name: "disabled", wantMaxBody: defaultMirrorMaxBodySize},
+		{name: "no pool", settings: sites.MirrorSettings{Percent: 10}, wantMaxBody: defaultMirrorMaxBodySize},
+		{name: "enabled", settings: sites.MirrorSettings{Pool: "shadow", Percent: 10, MaxBodySize: 512},
+			wantEnabled: true, wantMaxBody: 512},
+		{name: "percent over 100", settings: sites.MirrorSettings{Pool: "shadow", Percent: 101}, wantErr: true},
+		{name: "negative body size", settings: sites.MirrorSettings{Pool: "shadow", Percent: 10, MaxBodySize: -1},
+			wantErr: true},
+		{name: "compared", settings: sites.MirrorSettings{Pool: "shadow", Percent: 10, Compare: true},
+			wantEnabled: true, wantCompared: true, wantMaxBody: defaultMirrorMaxBodySize},
+		{name: "compared without pool", settings: sites.MirrorSettings{Compare: true},
+			wantMaxBody: defaultMirrorMaxBodySize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkMirror(tt.settings); (err != nil) != tt.wantErr {
+				t.Fatalf("checkMirror() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			site := &Site{Site: &sites.Site{Mirror: tt.settings}}
+			if got := site.IsMirrorEnabled(); got != tt.wantEnabled {
+				t.Errorf("IsMirrorEnabled() = %v, want %v", got, tt.wantEnabled)
+			}
+			if got := site.IsMirrorCompared(); got != tt.wantCompared {
+				t.Errorf("IsMirrorCompared() = %v, want %v", got, tt.wantCompared)
+			}
+			if got := site.GetMirrorMaxBodySize(); got != tt.wantMaxBody {
+				t.Errorf("GetMirrorMaxBodySize() = %d, want %d", got, tt.wantMaxBody)
+			}
+		})
+	}
+}
+
+func TestSite_GetRetryBackoff(t *testing.T) {
+	site := &Site{Site: &sites.Site{Retry: sites.RetrySettings{Attempts: 5, Backoff: 100}}}
+	tests := []struct {
+		name    string
+		site    *Site
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt", site: site, attempt: 1, want: 0},
+		{name: "second attempt", site: site, attempt: 2, want: 100 * time.Millisecond},
+		{name: "third attempt is doubled", site: site, attempt: 3, want: 200 * time.Millisecond},
+		{name: "delay is limited", site: site, attempt: 100, want: 1024 * 100 * time.Millisecond},
+		{name: "nil site", site: nil, attempt: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.site.GetRetryBackoff(tt.attempt); got != tt.want {
+				t.Errorf("GetRetryBackoff() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := (*Site)(nil).GetRetryAttempts(); got != defaultRetryAttempts {
+		t.Errorf("GetRetryAttempts() got %d, want %d", got, defaultRetryAttempts)
+	}
+	if got := site.GetRetryAttempts(); got != 5 {
+		t.Errorf("GetRetryAttempts() got %d, want 5", got)
+	}
+}
+
+func TestSite_Compresses(t *testing.T) {
+	site, err := newSite(&sites.Site{Compression: sites.CompressionSettings{
+		Enabled: true, Types: "application/json, text/*; charset=utf-8"}})
+	if err != nil {
+		t.Fatalf("newSite() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		site      *Site
+		mediaType string
+		want      bool
+	}{
+		{name: "listed type", site: site, mediaType: "application/json", want: true},
+		{name: "wildcard type", site: site, mediaType: "text/csv", want: true},
+		{name: "type is case insensitive", site: site, mediaType: "Application/JSON", want: true},
+		{name: "not listed type", site: site, mediaType: "image/png"},
+		{name: "default type", site: nil, mediaType: "text/html", want: true},
+		{name: "not default type", site: nil, mediaType: "text/csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.site.Compresses(tt.mediaType); got != tt.want {
+				t.Errorf("Compresses() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := (*Site)(nil).GetCompressionMinSize(); got != defaultCompressionMinSize {
+		t.Errorf("GetCompressionMinSize() got %d, want %d", got, defaultCompressionMinSize)
+	}
+}